@@ -12,6 +12,20 @@ const (
 
 	CodeNeedLogin
 	CodeInvalidToken
+
+	CodePostNotExist
+	CodeNoPermission
+	CodeCommentNotExist
+	CodeVoteTimeExpired
+	CodeVoteRepeated
+	CodeTooManyRequests
+	CodeLoginLocked
+	CodeCommunityNotExist
+	CodeCommunityExist
+	CodeCommunityArchived
+	CodeFileTooLarge
+	CodeUnsupportedFileType
+	CodeRevisionNotExist
 )
 
 var codeMsgMap = map[ResCode]string{
@@ -23,6 +37,22 @@ var codeMsgMap = map[ResCode]string{
 	CodeServerBusy:      "服务繁忙",
 	CodeNeedLogin:       "需要登录",
 	CodeInvalidToken:    "无效的token",
+	CodePostNotExist:    "帖子不存在",
+	CodeNoPermission:    "没有权限",
+	CodeCommentNotExist: "评论不存在",
+	CodeVoteTimeExpired: "投票时间已过",
+	CodeVoteRepeated:    "不允许重复投票",
+	CodeTooManyRequests: "请求过于频繁",
+	CodeLoginLocked:     "登录失败次数过多，请稍后再试",
+
+	CodeCommunityNotExist: "社区不存在",
+	CodeCommunityExist:    "社区名称已存在",
+	CodeCommunityArchived: "社区已归档，不能发帖",
+
+	CodeFileTooLarge:        "文件过大",
+	CodeUnsupportedFileType: "不支持的文件类型，只能上传jpeg、png、gif图片",
+
+	CodeRevisionNotExist: "版本不存在",
 }
 
 func (c ResCode) Msg() string {
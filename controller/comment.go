@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"bell_best/logic"
+	"bell_best/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// CreateCommentHandler 发表评论或回复评论
+func CreateCommentHandler(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamCreateComment)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("create comment with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.CreateComment(userID, pid, p)
+	if err != nil {
+		zap.L().Error("logic.CreateComment failed", zap.Int64("post_id", pid), zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetCommentListHandler 获取帖子的评论列表
+// GET /api/v1/post/:id/comments?view=tree&cursor=xxx&size=20
+func GetCommentListHandler(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := &models.ParamCommentList{
+		View: models.CommentViewFlat,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("get comment list with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetCommentList(pid, p)
+	if err != nil {
+		zap.L().Error("logic.GetCommentList failed", zap.Int64("post_id", pid), zap.Error(err))
+		responseCommentError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// responseCommentError 把评论相关的错误转换为对应的响应码
+func responseCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrorPostNotExist):
+		ResponseError(c, CodePostNotExist)
+	case errors.Is(err, models.ErrorCommentNotExist):
+		ResponseError(c, CodeCommentNotExist)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
@@ -2,7 +2,10 @@ package controller
 
 import (
 	"bell_best/logic"
+	"bell_best/models"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	"strconv"
 )
@@ -32,8 +35,193 @@ func CommunityDetailHandler(c *gin.Context) {
 	data, err := logic.GetCommunityDetail(id)
 	if err != nil {
 		zap.L().Error("logic.GetCommunityList failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy) // 不轻易把服务端报错暴漏给外面
+		responseCommunityError(c, err) // 不轻易把服务端报错暴漏给外面
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// CreateCommunityHandler 创建社区
+func CreateCommunityHandler(c *gin.Context) {
+	p := new(models.ParamCommunity)
+	if !bindCommunityParam(c, p) {
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.CreateCommunity(userID, p)
+	if err != nil {
+		zap.L().Error("logic.CreateCommunity failed", zap.Error(err))
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// UpdateCommunityHandler 编辑社区的名称及简介
+func UpdateCommunityHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamCommunity)
+	if !bindCommunityParam(c, p) {
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.UpdateCommunity(userID, id, p)
+	if err != nil {
+		zap.L().Error("logic.UpdateCommunity failed", zap.Int64("community_id", id), zap.Error(err))
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// ArchiveCommunityHandler 归档(POST)或恢复(DELETE)社区
+func ArchiveCommunityHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	archived := c.Request.Method != "DELETE"
+	if err := logic.SetCommunityArchived(userID, id, archived); err != nil {
+		zap.L().Error("logic.SetCommunityArchived failed", zap.Int64("community_id", id), zap.Error(err))
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// SubscribeCommunityHandler 订阅(POST)或取消订阅(DELETE)社区
+func SubscribeCommunityHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	subscribe := c.Request.Method != "DELETE"
+	if err := logic.SubscribeCommunity(userID, id, subscribe); err != nil {
+		zap.L().Error("logic.SubscribeCommunity failed", zap.Int64("community_id", id), zap.Error(err))
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// CommunityModeratorsHandler 查询社区的版主
+func CommunityModeratorsHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetCommunityModerators(id)
+	if err != nil {
+		zap.L().Error("logic.GetCommunityModerators failed", zap.Int64("community_id", id), zap.Error(err))
+		responseCommunityError(c, err)
 		return
 	}
 	ResponseSuccess(c, data)
 }
+
+// AddModeratorHandler 任命社区版主
+func AddModeratorHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamModerator)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("AddModerator with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.AddCommunityModerator(userID, id, p.UserID); err != nil {
+		zap.L().Error("logic.AddCommunityModerator failed", zap.Int64("community_id", id), zap.Error(err))
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RemoveModeratorHandler 撤销社区版主
+func RemoveModeratorHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	moderatorID, err := strconv.ParseInt(c.Param("uid"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.RemoveCommunityModerator(userID, id, moderatorID); err != nil {
+		zap.L().Error("logic.RemoveCommunityModerator failed", zap.Int64("community_id", id), zap.Error(err))
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// bindCommunityParam 绑定并校验创建、编辑社区的参数，失败时直接返回响应
+func bindCommunityParam(c *gin.Context, p *models.ParamCommunity) bool {
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("community with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return false
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return false
+	}
+	return true
+}
+
+// responseCommunityError 把社区相关的错误转换为对应的响应码
+func responseCommunityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrorInvalidID):
+		ResponseError(c, CodeCommunityNotExist)
+	case errors.Is(err, models.ErrorCommunityExist):
+		ResponseError(c, CodeCommunityExist)
+	case errors.Is(err, models.ErrorUserNotExist):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorCommunityArchived):
+		ResponseError(c, CodeCommunityArchived)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
@@ -10,3 +10,10 @@ type _ResponsePostList struct {
 	Message string                  `json:"message"` // 提示信息
 	Data    []*models.ApiPostDetail `json:"data"`    // 数据
 }
+
+// _ResponsePostCursorList 按游标分页的帖子列表接口响应数据
+type _ResponsePostCursorList struct {
+	Code    ResCode             `json:"code"`    // 业务响应状态码
+	Message string              `json:"message"` // 提示信息
+	Data    *models.ApiPostList `json:"data"`    // 数据
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"bell_best/logic"
+	"bell_best/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// FollowUserHandler 关注(POST)或取消关注(DELETE)用户
+// POST/DELETE /api/v1/users/:id/follow
+func FollowUserHandler(c *gin.Context) {
+	uid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if c.Request.Method == "DELETE" {
+		err = logic.UnfollowUser(userID, uid)
+	} else {
+		err = logic.FollowUser(userID, uid)
+	}
+	if err != nil {
+		zap.L().Error("follow user failed", zap.Int64("user_id", userID), zap.Int64("followee_id", uid), zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrorFollowSelf):
+			ResponseErrorWithMsg(c, CodeInvalidParam, err.Error())
+		case errors.Is(err, models.ErrorUserNotExist):
+			ResponseError(c, CodeUserNotExist)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// TimelineHandler 当前用户关注的作者的帖子，按发帖时间倒序，按游标分页
+// GET /api/v1/me/timeline?cursor=&size=10
+func TimelineHandler(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamPostList{Size: 10}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("get timeline with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetTimeline(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetTimeline failed", zap.Int64("user_id", userID), zap.Error(err))
+		if errors.Is(err, logic.ErrorInvalidCursor) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"bell_best/pkg/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// JWKSHandler 公开非对称签名密钥的公钥，供其他服务校验token
+// 按JWKS的标准格式返回，不使用统一的响应封装
+func JWKSHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, jwt.JWKS())
+}
@@ -3,19 +3,22 @@ package controller
 import (
 	"bell_best/logic"
 	"bell_best/models"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	"strconv"
 )
 
-// CreatePostHandler 创建帖子
+// CreatePostHandler 创建帖子，可以保存为草稿或定时发布，返回创建的帖子
 func CreatePostHandler(c *gin.Context) {
 	// 1.获取参数及参数的校验
 	// c.ShouldBindJSON() // validator -->binding
-	p := new(models.Post)
+	p := new(models.ParamCreatePost)
 	if err := c.ShouldBindJSON(p); err != nil {
 		zap.L().Error("create post failed", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
+		return
 	}
 	// 从c取到当前发请求的用户id
 	userID, err := GetCurrentUserID(c)
@@ -25,13 +28,17 @@ func CreatePostHandler(c *gin.Context) {
 	}
 	p.AuthorID = userID
 	// 2.创建帖子
-	if err := logic.CreatePost(p); err != nil {
+	if err := logic.CreatePost(&p.Post, p.Draft); err != nil {
 		zap.L().Error("logic.CreatePost failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		if errors.Is(err, logic.ErrorInvalidTag) {
+			ResponseErrorWithMsg(c, CodeInvalidParam, err.Error())
+			return
+		}
+		responseCommunityError(c, err)
 		return
 	}
 	// 3.返回响应
-	ResponseSuccess(c, nil)
+	ResponseSuccess(c, &p.Post)
 }
 
 // GetPostDetailHandler 获取帖子详情的处理函数
@@ -45,10 +52,15 @@ func GetPostDetailHandler(c *gin.Context) {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	// 2.根据id取出帖子数据（查数据库）
-	data, err := logic.GetPostByID(pid)
+	// 2.根据id取出帖子数据（查数据库），登录时一起返回当前用户的投票
+	userID, _ := GetCurrentUserID(c) // 未登录时为0
+	data, err := logic.GetPostByID(userID, pid)
 	if err != nil {
 		zap.L().Error("logic.GetPostByID failed", zap.Error(err))
+		if errors.Is(err, models.ErrorPostNotExist) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -56,11 +68,127 @@ func GetPostDetailHandler(c *gin.Context) {
 	ResponseSuccess(c, data)
 }
 
+// UpdatePostHandler 编辑帖子，只有作者本人可以编辑
+func UpdatePostHandler(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamUpdatePost)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("update post with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UpdatePost(userID, pid, p); err != nil {
+		zap.L().Error("logic.UpdatePost failed", zap.Int64("post_id", pid), zap.Error(err))
+		responsePostError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DeletePostHandler 删除帖子，作者本人及有管理权限的用户可以删除
+func DeletePostHandler(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.DeletePost(userID, GetCurrentRole(c), pid); err != nil {
+		zap.L().Error("logic.DeletePost failed", zap.Int64("post_id", pid), zap.Error(err))
+		responsePostError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// PublishPostHandler 发布草稿，publish_at为将来的时间时定时发布
+// POST /api/v1/post/:id/publish
+func PublishPostHandler(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamPublishPost)
+	// 请求体可以为空，表示立即发布
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(p); err != nil {
+			zap.L().Error("publish post with invalid param", zap.Error(err))
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.PublishPost(userID, pid, p.PublishAt); err != nil {
+		zap.L().Error("logic.PublishPost failed", zap.Int64("post_id", pid), zap.Error(err))
+		responsePostError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DraftListHandler 当前用户的草稿及定时发布的帖子
+// GET /api/v1/me/drafts?page=1&size=10
+func DraftListHandler(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	page, size := getPageInfo(c)
+	data, err := logic.GetDraftList(userID, page, size)
+	if err != nil {
+		zap.L().Error("logic.GetDraftList failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// responsePostError 把编辑、删除、恢复帖子时的错误转换为对应的响应码
+func responsePostError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrorPostNotExist):
+		ResponseError(c, CodePostNotExist)
+	case errors.Is(err, logic.ErrorNotPostAuthor), errors.Is(err, logic.ErrorNotModerator):
+		ResponseError(c, CodeNoPermission)
+	case errors.Is(err, models.ErrorRevisionNotExist):
+		ResponseError(c, CodeRevisionNotExist)
+	case errors.Is(err, logic.ErrorCommunityArchived):
+		ResponseError(c, CodeCommunityArchived)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
 // GetPostListHandler 获取帖子列表的处理函数
 func GetPostListHandler(c *gin.Context) {
 	page, size := getPageInfo(c)
+	userID, _ := GetCurrentUserID(c) // 未登录时为0
 	// 获取数据
-	data, err := logic.GetPostList(page, size)
+	data, err := logic.GetPostList(userID, page, size)
 	if err != nil {
 		zap.L().Error("logic.GetPostList failed", zap.Error(err))
 		ResponseError(c, CodeServerBusy)
@@ -73,6 +201,9 @@ func GetPostListHandler(c *gin.Context) {
 // GetPostListHandler2 升级版帖子列表接口
 // @Summary 升级版帖子列表接口
 // @Description 可按社区按时间或分数排序查询帖子列表接口
+// @Description 携带cursor参数(第一页传空字符串)时按游标分页，返回 {posts, next_cursor}
+// @Description 携带有效的token时返回当前用户对每篇帖子的投票my_vote
+// @Description 登录后传subscribed=true只查询订阅的社区的帖子
 // @Tags 帖子相关接口
 // @Accept application/json
 // @Produce application/json
@@ -80,6 +211,7 @@ func GetPostListHandler(c *gin.Context) {
 // @Param object query models.ParamPostList false "查询参数"
 // @Security ApiKeyAuth
 // @Success 200 {object} _ResponsePostList
+// @Success 200 {object} _ResponsePostCursorList
 // @Router /posts2 [get]
 func GetPostListHandler2(c *gin.Context) {
 	// GET请求参数(query string): /api/v1/posts2?page=1&size=10&order=time
@@ -94,9 +226,44 @@ func GetPostListHandler2(c *gin.Context) {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	data, err := logic.GetPostListNew(p) // 更新：合二为一
+	userID, _ := GetCurrentUserID(c) // 未登录时为0
+	// 订阅的社区的帖子需要登录，不能再按社区或标签过滤
+	if p.Subscribed {
+		if userID == 0 {
+			ResponseError(c, CodeNeedLogin)
+			return
+		}
+		if p.CommunityID != 0 || p.Tag != "" {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+	}
+	// 携带cursor参数时按游标分页，第一页传空的cursor
+	if _, ok := c.GetQuery("cursor"); ok {
+		data, err := logic.GetPostListByCursor(userID, p)
+		if err != nil {
+			zap.L().Error("logic.GetPostListByCursor failed", zap.Error(err))
+			if errors.Is(err, logic.ErrorInvalidCursor) || errors.Is(err, logic.ErrorInvalidTag) {
+				ResponseError(c, CodeInvalidParam)
+				return
+			}
+			ResponseError(c, CodeServerBusy)
+			return
+		}
+		ResponseSuccess(c, data)
+		return
+	}
+	if p.Page < 1 {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetPostListNew(userID, p) // 更新：合二为一
 	if err != nil {
 		zap.L().Error("logic.GetPostList failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorInvalidTag) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -129,3 +296,50 @@ func GetPostListHandler2(c *gin.Context) {
 //	ResponseSuccess(c, data)
 //	// 返回响应}
 //}
+
+// SearchHandler 全文搜索帖子
+// GET /api/v1/search?q=关键词&community_id=1&since=&until=&page=1&size=10
+func SearchHandler(c *gin.Context) {
+	p := &models.ParamSearch{
+		Page: 1,
+		Size: 10,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("search with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if p.Page < 1 || p.Size < 1 || p.Size > 50 {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, _ := GetCurrentUserID(c) // 未登录时为0
+	data, err := logic.SearchPosts(userID, p)
+	if err != nil {
+		zap.L().Error("logic.SearchPosts failed", zap.String("q", p.Q), zap.Error(err))
+		if errors.Is(err, logic.ErrorEmptyQuery) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// TrendingTagsHandler 热门标签
+// GET /api/v1/tags/trending?size=10
+func TrendingTagsHandler(c *gin.Context) {
+	size, err := strconv.ParseInt(c.DefaultQuery("size", "10"), 10, 64)
+	if err != nil || size < 1 || size > 100 {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetTrendingTags(size)
+	if err != nil {
+		zap.L().Error("logic.GetTrendingTags failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"bell_best/pkg/rbac"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"strconv"
 )
 
-const CtxUserIDKey = "userID"
+const (
+	CtxUserIDKey  = "userID"
+	CtxTokenIDKey = "tokenID" // 当前access token的jti
+	CtxRoleKey    = "role"    // 当前用户的角色，来自access token
+)
 
 var ErrorUserNotLogin = errors.New("用户为登录")
 
@@ -25,7 +30,24 @@ func GetCurrentUserID(c *gin.Context) (userID int64, err error) {
 	return
 }
 
-// getPageInfo 获取分页参数
+// GetCurrentTokenID 获取当前请求使用的access token的jti
+func GetCurrentTokenID(c *gin.Context) (tokenID string, err error) {
+	tokenID = c.GetString(CtxTokenIDKey)
+	if tokenID == "" {
+		err = ErrorUserNotLogin
+	}
+	return
+}
+
+// GetCurrentRole 获取当前登录用户的角色，没有角色的旧token按普通用户处理
+func GetCurrentRole(c *gin.Context) string {
+	return rbac.Normalize(c.GetString(CtxRoleKey))
+}
+
+// maxPageSize 每页最多返回的数据量
+const maxPageSize = 100
+
+// getPageInfo 获取分页参数，不合法时使用默认值
 func getPageInfo(c *gin.Context) (int64, int64) {
 	pageStr := c.Query("page")
 	sizeStr := c.Query("size")
@@ -38,9 +60,15 @@ func getPageInfo(c *gin.Context) (int64, int64) {
 	if err != nil {
 		page = 1
 	}
+	if page < 1 {
+		page = 1
+	}
 	size, err = strconv.ParseInt(sizeStr, 10, 64)
-	if err != nil {
+	if err != nil || size < 1 {
 		size = 10
 	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
 	return page, size
 }
@@ -34,6 +34,15 @@ func ResponseErrorWithMsg(c *gin.Context, code ResCode, msg interface{}) {
 	})
 }
 
+// ResponseErrorWithStatus 使用指定的http状态码返回错误，如限流时返回429
+func ResponseErrorWithStatus(c *gin.Context, status int, code ResCode) {
+	c.JSON(status, &ResponseDate{
+		Code: code,
+		Msg:  code.Msg(),
+		Data: nil,
+	})
+}
+
 func ResponseSuccess(c *gin.Context, data interface{}) {
 	c.JSON(200, &ResponseDate{
 		Code: CodeSuccess,
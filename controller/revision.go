@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"bell_best/logic"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// PostRevisionsHandler 帖子的历史版本列表，按版本号倒序
+// GET /api/v1/post/:id/revisions?page=1&size=10
+func PostRevisionsHandler(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	page, size := getPageInfo(c)
+	data, err := logic.GetPostRevisions(pid, page, size)
+	if err != nil {
+		zap.L().Error("logic.GetPostRevisions failed", zap.Int64("post_id", pid), zap.Error(err))
+		responsePostError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// PostRevisionHandler 帖子的某个历史版本，diff为与上一个版本比较的unified diff
+// GET /api/v1/post/:id/revisions/:rev
+func PostRevisionHandler(c *gin.Context) {
+	pid, rev, ok := getRevisionParam(c)
+	if !ok {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetPostRevision(pid, rev)
+	if err != nil {
+		zap.L().Error("logic.GetPostRevision failed", zap.Int64("post_id", pid), zap.Int64("revision", rev), zap.Error(err))
+		responsePostError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// RollbackPostHandler 把帖子恢复为某个历史版本，恢复后的内容保存为新版本
+// POST /api/v1/post/:id/revisions/:rev/rollback
+func RollbackPostHandler(c *gin.Context) {
+	pid, rev, ok := getRevisionParam(c)
+	if !ok {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.RollbackPost(userID, GetCurrentRole(c), pid, rev); err != nil {
+		zap.L().Error("logic.RollbackPost failed", zap.Int64("post_id", pid), zap.Int64("revision", rev), zap.Error(err))
+		responsePostError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+func getRevisionParam(c *gin.Context) (pid, rev int64, ok bool) {
+	pid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	rev, err = strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil || rev < 1 {
+		return 0, 0, false
+	}
+	return pid, rev, true
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"bell_best/logic"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// multipart表单中文件以外的数据预留的大小
+const multipartOverhead = 1 << 20
+
+// UploadHandler 上传图片，表单字段为file，返回原图及缩略图地址
+// POST /api/v1/uploads
+func UploadHandler(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	maxSize := logic.MaxUploadSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		zap.L().Error("upload with invalid param", zap.Error(err))
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			ResponseError(c, CodeFileTooLarge)
+			return
+		}
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if fh.Size > maxSize {
+		ResponseError(c, CodeFileTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		zap.L().Error("open upload file failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	defer f.Close()
+	data, err := logic.Upload(userID, f)
+	if err != nil {
+		zap.L().Error("logic.Upload failed", zap.Int64("user_id", userID), zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrorFileTooLarge):
+			ResponseError(c, CodeFileTooLarge)
+		case errors.Is(err, logic.ErrorUnsupportedFileType):
+			ResponseError(c, CodeUnsupportedFileType)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, data)
+}
@@ -1,14 +1,18 @@
 package controller
 
 import (
-	"bell_best/dao/mysql"
+	"bell_best/dao/redis"
 	"bell_best/logic"
 	"bell_best/models"
+	"bell_best/pkg/jwt"
+	"bell_best/pkg/rbac"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
+	"math"
+	"strconv"
 )
 
 // SignUpHandler 处理注册请求的函数
@@ -35,7 +39,7 @@ func SignUpHandler(c *gin.Context) {
 	if err := logic.SignUp(p); err != nil {
 		zap.L().Error("logic.SignUp failed", zap.Error(err))
 		// ????如果已存在是不是返回俩？？？
-		if errors.Is(err, mysql.ErrorUserExist) {
+		if errors.Is(err, models.ErrorUserExist) {
 			ResponseError(c, CodeUserExist)
 		}
 		ResponseError(c, CodeServerBusy)
@@ -61,19 +65,207 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 	// 业务逻辑处理
-	user, err := logic.Login(p)
+	user, err := logic.Login(p, c.ClientIP())
 	if err != nil {
 		zap.L().Error("logic.Login failed", zap.String("username:", p.Username), zap.Error(err))
-		if errors.Is(err, mysql.ErrorUserNotExist) {
+		var locked *logic.LoginLockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(locked.RetryAfter.Seconds())), 10))
+			ResponseError(c, CodeLoginLocked)
+			return
+		}
+		if errors.Is(err, models.ErrorUserNotExist) {
 			ResponseError(c, CodeUserNotExist)
+			return
 		}
 		ResponseError(c, CodeInvalidPassword)
 		return
 	}
 	// 返回响应
-	ResponseSuccess(c, gin.H{
-		"token":     user.Token,
-		"user_id":   fmt.Sprintf("%d", user.UserID), // id值大于1<<2`53-1,int64值大于1<<2`63-1
-		"user_name": user.Username,
-	})
+	ResponseSuccess(c, tokenResponse(user))
+}
+
+// RefreshTokenHandler 用refresh token换取新的access token和refresh token
+func RefreshTokenHandler(c *gin.Context) {
+	p := new(models.ParamRefreshToken)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("RefreshToken with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	user, err := logic.RefreshToken(p)
+	if err != nil {
+		zap.L().Error("logic.RefreshToken failed", zap.Error(err))
+		if errors.Is(err, redis.ErrRefreshTokenNotExist) || errors.Is(err, models.ErrorUserNotExist) {
+			ResponseError(c, CodeInvalidToken)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, tokenResponse(user))
+}
+
+// LogoutHandler 退出登录
+func LogoutHandler(c *gin.Context) {
+	p := new(models.ParamLogout)
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(p); err != nil {
+			zap.L().Error("Logout with invalid param", zap.Error(err))
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	tokenID, err := GetCurrentTokenID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.Logout(userID, tokenID, p); err != nil {
+		zap.L().Error("logic.Logout failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UpdateProfileHandler 修改当前用户的资料
+func UpdateProfileHandler(c *gin.Context) {
+	p := new(models.ParamUpdateProfile)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("UpdateProfile with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, err := logic.UpdateProfile(userID, p)
+	if err != nil {
+		zap.L().Error("logic.UpdateProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		if errors.Is(err, models.ErrorUserNotExist) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// GetUserPageHandler 作者主页，返回作者的资料及帖子
+// GET /api/v1/users/:id?size=10&order=time&cursor=
+func GetUserPageHandler(c *gin.Context) {
+	authorID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := &models.ParamPostList{
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		zap.L().Error("get user page with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, _ := GetCurrentUserID(c) // 未登录时为0
+	data, err := logic.GetAuthorPage(userID, authorID, p)
+	if err != nil {
+		zap.L().Error("logic.GetAuthorPage failed", zap.Int64("author_id", authorID), zap.Error(err))
+		if errors.Is(err, models.ErrorUserNotExist) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		if errors.Is(err, logic.ErrorInvalidCursor) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// SetUserRoleHandler 修改用户的角色
+func SetUserRoleHandler(c *gin.Context) {
+	uid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamUserRole)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("SetUserRole with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	operatorID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SetUserRole(operatorID, uid, p.Role); err != nil {
+		zap.L().Error("logic.SetUserRole failed", zap.Int64("user_id", uid), zap.Error(err))
+		switch {
+		case errors.Is(err, models.ErrorUserNotExist):
+			ResponseError(c, CodeUserNotExist)
+		case errors.Is(err, logic.ErrorInvalidRole):
+			ResponseError(c, CodeInvalidParam)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RevokeUserHandler 强制用户下线
+func RevokeUserHandler(c *gin.Context) {
+	uid, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	operatorID, err := GetCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.RevokeUser(operatorID, uid); err != nil {
+		zap.L().Error("logic.RevokeUser failed", zap.Int64("user_id", uid), zap.Error(err))
+		if errors.Is(err, models.ErrorUserNotExist) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// tokenResponse 登录和刷新token的响应数据
+func tokenResponse(user *models.User) gin.H {
+	return gin.H{
+		"token":         user.Token,
+		"refresh_token": user.RefreshToken,
+		"expires_in":    int64(jwt.AccessTokenExpire().Seconds()), // access token的有效期(秒)
+		"user_id":       fmt.Sprintf("%d", user.UserID),           // id值大于1<<2`53-1,int64值大于1<<2`63-1
+		"user_name":     user.Username,
+		"role":          rbac.Normalize(user.Role),
+	}
 }
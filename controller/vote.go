@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"bell_best/dao/redis"
 	"bell_best/logic"
 	"bell_best/models"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
@@ -30,7 +32,16 @@ func PostVoteController(c *gin.Context) {
 	// 具体投票的业务逻辑
 	if err := logic.VoteForPost(userID, p); err != nil {
 		zap.L().Error("logic.VoteForPost failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		switch {
+		case errors.Is(err, models.ErrorPostNotExist), errors.Is(err, redis.ErrVotePostNotExist):
+			ResponseError(c, CodePostNotExist)
+		case errors.Is(err, redis.ErrVoteTimeExpired):
+			ResponseError(c, CodeVoteTimeExpired)
+		case errors.Is(err, redis.ErrVoteRepested):
+			ResponseError(c, CodeVoteRepeated)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
 		return
 	}
 
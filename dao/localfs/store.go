@@ -0,0 +1,99 @@
+// Package localfs 把上传的文件保存在本地目录，适合单实例部署
+package localfs
+
+import (
+	"bell_best/setting"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultDir       = "./uploads"
+	DefaultPublicURL = "/static/uploads"
+)
+
+var ErrorInvalidKey = errors.New("无效的文件路径")
+
+// Store 基于本地目录的文件存储
+type Store struct {
+	dir       string
+	publicURL string
+}
+
+// Paths 返回配置的目录和访问地址前缀，未配置时使用默认值
+func Paths(cfg *setting.LocalBlobConfig) (dir, publicURL string) {
+	dir, publicURL = DefaultDir, DefaultPublicURL
+	if cfg != nil {
+		if cfg.Dir != "" {
+			dir = cfg.Dir
+		}
+		if cfg.PublicURL != "" {
+			publicURL = cfg.PublicURL
+		}
+	}
+	return dir, strings.TrimSuffix(publicURL, "/")
+}
+
+// New 创建文件存储，目录不存在时自动创建
+func New(cfg *setting.LocalBlobConfig) (*Store, error) {
+	dir, publicURL := Paths(cfg)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, publicURL: publicURL}, nil
+}
+
+// path 把key转换成目录下的文件路径，拒绝跳出目录的key
+func (s *Store) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", ErrorInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 保存文件，先写临时文件再重命名，不会出现写了一半的文件
+func (s *Store) Put(key string, data []byte, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// Delete 删除文件，文件不存在时不报错
+func (s *Store) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL 返回文件的访问地址
+func (s *Store) URL(key string) string {
+	return s.publicURL + "/" + key
+}
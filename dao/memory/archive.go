@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sync"
+)
+
+// VoteArchiveStore 内存中的帖子票数归档存储
+type VoteArchiveStore struct {
+	mu    sync.Mutex
+	votes map[int64]models.PostVotes
+}
+
+func NewVoteArchiveStore() *VoteArchiveStore {
+	return &VoteArchiveStore{votes: make(map[int64]models.PostVotes)}
+}
+
+// SavePostVotes 保存帖子的票数，重复归档时覆盖之前的结果
+func (s *VoteArchiveStore) SavePostVotes(votes []*models.PostVotes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range votes {
+		s.votes[v.PostID] = *v
+	}
+	return nil
+}
+
+// GetPostVotesByIDs 查询已归档的帖子票数
+func (s *VoteArchiveStore) GetPostVotesByIDs(postIDs []int64) (map[int64]*models.PostVotes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make(map[int64]*models.PostVotes, len(postIDs))
+	for _, id := range postIDs {
+		if v, ok := s.votes[id]; ok {
+			data[id] = &v
+		}
+	}
+	return data, nil
+}
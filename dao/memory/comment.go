@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sort"
+	"sync"
+)
+
+// CommentStore 内存中的评论存储
+type CommentStore struct {
+	mu       sync.RWMutex
+	comments map[int64]*models.Comment
+}
+
+func NewCommentStore() *CommentStore {
+	return &CommentStore{comments: make(map[int64]*models.Comment)}
+}
+
+// CreateComment 创建评论
+func (s *CommentStore) CreateComment(c *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comment := *c
+	s.comments[comment.ID] = &comment
+	return nil
+}
+
+// GetCommentByID 根据id查询单条评论
+func (s *CommentStore) GetCommentByID(cid int64) (*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.comments[cid]
+	if !ok {
+		return nil, models.ErrorCommentNotExist
+	}
+	comment := *c
+	return &comment, nil
+}
+
+// GetCommentList 按时间顺序查询帖子下id大于cursor的评论
+func (s *CommentStore) GetCommentList(postID, cursor, size int64) ([]*models.Comment, error) {
+	return s.filter(size, func(c *models.Comment) bool {
+		return c.PostID == postID && c.ID > cursor
+	}), nil
+}
+
+// GetRootCommentList 按时间顺序查询帖子下id大于cursor的顶层评论
+func (s *CommentStore) GetRootCommentList(postID, cursor, size int64) ([]*models.Comment, error) {
+	return s.filter(size, func(c *models.Comment) bool {
+		return c.PostID == postID && c.ParentID == 0 && c.ID > cursor
+	}), nil
+}
+
+// GetCommentsByRootIDs 查询给定顶层评论下的全部回复
+func (s *CommentStore) GetCommentsByRootIDs(rootIDs []int64) ([]*models.Comment, error) {
+	roots := make(map[int64]struct{}, len(rootIDs))
+	for _, id := range rootIDs {
+		roots[id] = struct{}{}
+	}
+	return s.filter(-1, func(c *models.Comment) bool {
+		_, ok := roots[c.RootID]
+		return ok && c.ParentID != 0
+	}), nil
+}
+
+// GetCommentNumByPostIDs 查询每篇帖子的评论数
+func (s *CommentStore) GetCommentNumByPostIDs(postIDs []int64) (map[int64]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	want := make(map[int64]struct{}, len(postIDs))
+	for _, id := range postIDs {
+		want[id] = struct{}{}
+	}
+	data := make(map[int64]int64, len(postIDs))
+	for _, c := range s.comments {
+		if _, ok := want[c.PostID]; ok {
+			data[c.PostID]++
+		}
+	}
+	return data, nil
+}
+
+// filter 按id顺序返回满足条件的评论，size小于0表示不限制数量
+func (s *CommentStore) filter(size int64, match func(c *models.Comment) bool) []*models.Comment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*models.Comment, 0)
+	for _, c := range s.comments {
+		if match(c) {
+			comment := *c
+			list = append(list, &comment)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if size >= 0 && int64(len(list)) > size {
+		list = list[:size]
+	}
+	return list
+}
@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CommunityStore 内存中的社区存储
+type CommunityStore struct {
+	mu          sync.RWMutex
+	communities map[int64]*models.CommunityDetail
+	moderators  map[int64][]int64            // community_id -> 版主的user_id，按任命顺序
+	subscribed  map[int64]map[int64]struct{} // user_id -> 订阅的community_id
+}
+
+// NewCommunityStore 使用给定的社区初始化存储
+func NewCommunityStore(communities ...*models.CommunityDetail) *CommunityStore {
+	s := &CommunityStore{
+		communities: make(map[int64]*models.CommunityDetail),
+		moderators:  make(map[int64][]int64),
+		subscribed:  make(map[int64]map[int64]struct{}),
+	}
+	for _, c := range communities {
+		cc := *c
+		s.communities[cc.ID] = &cc
+	}
+	return s
+}
+
+// GetCommunityList 按社区id顺序返回所有社区
+func (s *CommunityStore) GetCommunityList() ([]*models.Community, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*models.Community, 0, len(s.communities))
+	for _, c := range s.communities {
+		list = append(list, &models.Community{ID: c.ID, Name: c.Name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// GetCommunityDetailByID 根据id查询社区详情
+func (s *CommunityStore) GetCommunityDetailByID(id int64) (*models.CommunityDetail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.communities[id]
+	if !ok {
+		return nil, models.ErrorInvalidID
+	}
+	cc := *c
+	return &cc, nil
+}
+
+// GetCommunitiesByIDs 根据id列表批量查询社区详情，不存在的id忽略
+func (s *CommunityStore) GetCommunitiesByIDs(ids []int64) ([]*models.CommunityDetail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*models.CommunityDetail, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := s.communities[id]; ok {
+			cc := *c
+			list = append(list, &cc)
+		}
+	}
+	return list, nil
+}
+
+// checkName 检查社区名称是否被其他社区使用
+func (s *CommunityStore) checkName(name string, excludeID int64) error {
+	for _, c := range s.communities {
+		if c.Name == name && c.ID != excludeID {
+			return models.ErrorCommunityExist
+		}
+	}
+	return nil
+}
+
+// CreateCommunity 创建社区，社区id在已有的最大id上加1
+func (s *CommunityStore) CreateCommunity(c *models.CommunityDetail) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkName(c.Name, 0); err != nil {
+		return err
+	}
+	var maxID int64
+	for id := range s.communities {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	c.ID = maxID + 1
+	c.Archived = false
+	c.CreateTime = time.Now()
+	cc := *c
+	s.communities[cc.ID] = &cc
+	return nil
+}
+
+// UpdateCommunity 更新社区的名称、简介及归档状态
+func (s *CommunityStore) UpdateCommunity(c *models.CommunityDetail) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.communities[c.ID]
+	if !ok {
+		return models.ErrorInvalidID
+	}
+	if err := s.checkName(c.Name, c.ID); err != nil {
+		return err
+	}
+	old.Name, old.Introduction, old.Archived = c.Name, c.Introduction, c.Archived
+	return nil
+}
+
+// GetCommunityModerators 查询社区的版主id
+func (s *CommunityStore) GetCommunityModerators(communityID int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]int64(nil), s.moderators[communityID]...), nil
+}
+
+// IsCommunityModerator 判断用户是不是社区的版主
+func (s *CommunityStore) IsCommunityModerator(communityID, uid int64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, id := range s.moderators[communityID] {
+		if id == uid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddCommunityModerator 添加社区版主，已经是版主时忽略
+func (s *CommunityStore) AddCommunityModerator(communityID, uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.moderators[communityID] {
+		if id == uid {
+			return nil
+		}
+	}
+	s.moderators[communityID] = append(s.moderators[communityID], uid)
+	return nil
+}
+
+// RemoveCommunityModerator 移除社区版主
+func (s *CommunityStore) RemoveCommunityModerator(communityID, uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := s.moderators[communityID]
+	for i, id := range ids {
+		if id == uid {
+			s.moderators[communityID] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SubscribeCommunity 订阅社区，已经订阅时返回false
+func (s *CommunityStore) SubscribeCommunity(communityID, uid int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.communities[communityID]
+	if !ok {
+		return false, nil
+	}
+	if _, ok := s.subscribed[uid][communityID]; ok {
+		return false, nil
+	}
+	addToSet(s.subscribed, uid, communityID)
+	c.Subscribers++
+	return true, nil
+}
+
+// UnsubscribeCommunity 取消订阅社区，没有订阅时返回false
+func (s *CommunityStore) UnsubscribeCommunity(communityID, uid int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribed[uid][communityID]; !ok {
+		return false, nil
+	}
+	delete(s.subscribed[uid], communityID)
+	if c, ok := s.communities[communityID]; ok {
+		c.Subscribers--
+	}
+	return true, nil
+}
+
+// GetSubscribedCommunityIDs 查询用户订阅的社区id
+func (s *CommunityStore) GetSubscribedCommunityIDs(uid int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedIDs(s.subscribed[uid]), nil
+}
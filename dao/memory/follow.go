@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sort"
+	"sync"
+)
+
+// FollowStore 内存中的关注关系存储
+type FollowStore struct {
+	mu        sync.RWMutex
+	following map[int64]map[int64]struct{} // 粉丝 -> 关注的用户
+	followers map[int64]map[int64]struct{} // 用户 -> 粉丝
+}
+
+func NewFollowStore() *FollowStore {
+	return &FollowStore{
+		following: make(map[int64]map[int64]struct{}),
+		followers: make(map[int64]map[int64]struct{}),
+	}
+}
+
+// Follow 关注用户，已经关注时返回false
+func (s *FollowStore) Follow(followerID, followeeID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.following[followerID][followeeID]; ok {
+		return false, nil
+	}
+	addToSet(s.following, followerID, followeeID)
+	addToSet(s.followers, followeeID, followerID)
+	return true, nil
+}
+
+// Unfollow 取消关注，没有关注时返回false
+func (s *FollowStore) Unfollow(followerID, followeeID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.following[followerID][followeeID]; !ok {
+		return false, nil
+	}
+	delete(s.following[followerID], followeeID)
+	delete(s.followers[followeeID], followerID)
+	return true, nil
+}
+
+// GetFollowerIDs 查询用户的粉丝id
+func (s *FollowStore) GetFollowerIDs(uid int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedIDs(s.followers[uid]), nil
+}
+
+// GetFollowingIDs 查询用户关注的、粉丝数不少于minFollowers的用户id
+func (s *FollowStore) GetFollowingIDs(uid, minFollowers int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]int64, 0, len(s.following[uid]))
+	for _, id := range sortedIDs(s.following[uid]) {
+		if int64(len(s.followers[id])) >= minFollowers {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// GetFollowCount 查询用户的粉丝数及关注数
+func (s *FollowStore) GetFollowCount(uid int64) (*models.FollowCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &models.FollowCount{
+		Followers: int64(len(s.followers[uid])),
+		Following: int64(len(s.following[uid])),
+	}, nil
+}
+
+func sortedIDs(set map[int64]struct{}) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+type lockEntry struct {
+	token    string
+	expireAt time.Time
+}
+
+// LockStore 内存中的锁，只在单个进程内有效
+type LockStore struct {
+	mu    sync.Mutex
+	locks map[string]lockEntry // name -> 持有者及过期时间
+}
+
+func NewLockStore() *LockStore {
+	return &LockStore{locks: make(map[string]lockEntry)}
+}
+
+// TryLock 尝试获取名为name的锁，锁在ttl后自动释放
+func (s *LockStore) TryLock(name, token string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if l, ok := s.locks[name]; ok && now.Before(l.expireAt) {
+		return false, nil
+	}
+	s.locks[name] = lockEntry{token: token, expireAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Unlock 释放锁，锁已被其他持有者获取时不做处理
+func (s *LockStore) Unlock(name, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.locks[name]; ok && l.token == token {
+		delete(s.locks, name)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+type failEntry struct {
+	count    int64
+	expireAt time.Time
+}
+
+// LoginGuardStore 内存中的登录失败次数及禁止登录记录
+type LoginGuardStore struct {
+	mu    sync.Mutex
+	fails map[string]*failEntry
+	locks map[string]time.Time // key -> 解除禁止的时间
+}
+
+func NewLoginGuardStore() *LoginGuardStore {
+	return &LoginGuardStore{
+		fails: make(map[string]*failEntry),
+		locks: make(map[string]time.Time),
+	}
+}
+
+// GetLoginLocks 查询每个key还要被禁止登录多久
+func (s *LoginGuardStore) GetLoginLocks(keys []string) ([]time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	data := make([]time.Duration, 0, len(keys))
+	for _, key := range keys {
+		var d time.Duration
+		if until, ok := s.locks[key]; ok && until.After(now) {
+			d = until.Sub(now)
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
+
+// IncrLoginFailures 登录失败次数加1并返回窗口期内的失败次数
+func (s *LoginGuardStore) IncrLoginFailures(key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	e, ok := s.fails[key]
+	if !ok || !e.expireAt.After(now) {
+		e = &failEntry{}
+		s.fails[key] = e
+	}
+	e.count++
+	e.expireAt = now.Add(window)
+	return e.count, nil
+}
+
+// LockLogin 在d时间内禁止登录
+func (s *LoginGuardStore) LockLogin(key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[key] = time.Now().Add(d)
+	return nil
+}
+
+// ResetLoginFailures 清除失败次数及禁止登录
+func (s *LoginGuardStore) ResetLoginFailures(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.fails, key)
+	delete(s.locks, key)
+	return nil
+}
@@ -0,0 +1,268 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PostStore 内存中的帖子存储
+type PostStore struct {
+	mu    sync.RWMutex
+	posts map[int64]*models.Post
+	tags  map[int64][]string               // post_id -> 标签，和mysql一样不随帖子返回
+	revs  map[int64][]*models.PostRevision // post_id -> 历史版本，按版本号升序
+}
+
+func NewPostStore() *PostStore {
+	return &PostStore{
+		posts: make(map[int64]*models.Post),
+		tags:  make(map[int64][]string),
+		revs:  make(map[int64][]*models.PostRevision),
+	}
+}
+
+// CreatePost 创建帖子
+func (s *PostStore) CreatePost(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post := *p
+	if post.Status == models.PostStatusDeleted {
+		post.Status = models.PostStatusNormal
+	}
+	post.CreateTime = time.Now()
+	post.UpdateTime = post.CreateTime
+	post.Tags = nil
+	s.posts[post.ID] = &post
+	if len(p.Tags) > 0 {
+		s.tags[post.ID] = append([]string(nil), p.Tags...)
+	}
+	s.addRevision(&post, post.AuthorID, post.CreateTime)
+	return nil
+}
+
+// GetPostTags 根据帖子id列表批量查询帖子的标签
+func (s *PostStore) GetPostTags(pids []int64) (map[int64][]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data := make(map[int64][]string, len(pids))
+	for _, pid := range pids {
+		if tags, ok := s.tags[pid]; ok {
+			data[pid] = append([]string(nil), tags...)
+		}
+	}
+	return data, nil
+}
+
+// GetPostByID 根据id查询单个帖子数据，已删除的帖子视为不存在
+func (s *PostStore) GetPostByID(pid int64) (*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.posts[pid]
+	if !ok || p.Status != models.PostStatusNormal {
+		return nil, models.ErrorPostNotExist
+	}
+	post := *p
+	return &post, nil
+}
+
+// GetPostList 按创建时间倒序分页查询帖子列表
+func (s *PostStore) GetPostList(page, size int64) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make([]*models.Post, 0, len(s.posts))
+	for _, p := range s.posts {
+		if p.Status != models.PostStatusNormal {
+			continue
+		}
+		post := *p
+		all = append(all, &post)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].CreateTime.After(all[j].CreateTime)
+	})
+	start := (page - 1) * size
+	if start < 0 || start >= int64(len(all)) {
+		return []*models.Post{}, nil
+	}
+	end := start + size
+	if end > int64(len(all)) {
+		end = int64(len(all))
+	}
+	return all[start:end], nil
+}
+
+// GetPostListByIDs 按给定的id顺序查询帖子
+func (s *PostStore) GetPostListByIDs(ids []string) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*models.Post, 0, len(ids))
+	for _, idStr := range ids {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		if p, ok := s.posts[id]; ok && p.Status == models.PostStatusNormal {
+			post := *p
+			list = append(list, &post)
+		}
+	}
+	return list, nil
+}
+
+// UpdatePost 更新帖子的标题、内容及更新时间，草稿也可以编辑，修改后的内容保存为新的历史版本
+func (s *PostStore) UpdatePost(p *models.Post, editorID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post, ok := s.posts[p.ID]
+	if !ok || post.Status == models.PostStatusDeleted {
+		return models.ErrorPostNotExist
+	}
+	post.Title = p.Title
+	post.Content = p.Content
+	post.UpdateTime = p.UpdateTime
+	s.addRevision(post, editorID, p.UpdateTime)
+	return nil
+}
+
+func (s *PostStore) addRevision(p *models.Post, editorID int64, t time.Time) {
+	s.revs[p.ID] = append(s.revs[p.ID], &models.PostRevision{
+		PostID:     p.ID,
+		Revision:   int64(len(s.revs[p.ID]) + 1),
+		EditorID:   editorID,
+		Title:      p.Title,
+		Content:    p.Content,
+		CreateTime: t,
+	})
+}
+
+// GetPostRevisions 按版本号倒序分页查询帖子的历史版本，不返回内容
+func (s *PostStore) GetPostRevisions(pid, page, size int64) ([]*models.PostRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := s.revs[pid]
+	list := make([]*models.PostRevision, 0, size)
+	for i := int64(len(all)) - 1 - (page-1)*size; i >= 0 && int64(len(list)) < size; i-- {
+		r := *all[i]
+		r.Content = ""
+		list = append(list, &r)
+	}
+	return list, nil
+}
+
+// GetPostRevision 查询帖子的第rev个版本
+func (s *PostStore) GetPostRevision(pid, rev int64) (*models.PostRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := s.revs[pid]
+	if rev < 1 || rev > int64(len(all)) {
+		return nil, models.ErrorRevisionNotExist
+	}
+	r := *all[rev-1]
+	return &r, nil
+}
+
+// DeletePost 软删除帖子，只修改帖子状态
+func (s *PostStore) DeletePost(pid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if post, ok := s.posts[pid]; ok {
+		post.Status = models.PostStatusDeleted
+	}
+	return nil
+}
+
+func isDraft(p *models.Post) bool {
+	return p.Status == models.PostStatusDraft || p.Status == models.PostStatusScheduled
+}
+
+// GetDraftByID 根据id查询草稿或定时发布的帖子
+func (s *PostStore) GetDraftByID(pid int64) (*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.posts[pid]
+	if !ok || !isDraft(p) {
+		return nil, models.ErrorPostNotExist
+	}
+	post := *p
+	return &post, nil
+}
+
+// GetDraftList 按更新时间倒序分页查询作者的草稿及定时发布的帖子
+func (s *PostStore) GetDraftList(authorID, page, size int64) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make([]*models.Post, 0)
+	for _, p := range s.posts {
+		if p.AuthorID == authorID && isDraft(p) {
+			post := *p
+			all = append(all, &post)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].UpdateTime.After(all[j].UpdateTime)
+	})
+	start := (page - 1) * size
+	if start < 0 || start >= int64(len(all)) {
+		return []*models.Post{}, nil
+	}
+	return all[start:min(start+size, int64(len(all)))], nil
+}
+
+// GetDuePosts 查询发布时间已到的定时发布帖子
+func (s *PostStore) GetDuePosts(now time.Time, size int64) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	due := make([]*models.Post, 0)
+	for _, p := range s.posts {
+		if p.Status == models.PostStatusScheduled && p.PublishAt != nil && !p.PublishAt.After(now) {
+			post := *p
+			due = append(due, &post)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].PublishAt.Before(*due[j].PublishAt)
+	})
+	if int64(len(due)) > size {
+		due = due[:size]
+	}
+	return due, nil
+}
+
+// SchedulePost 把草稿或定时发布的帖子设为在publishAt定时发布
+func (s *PostStore) SchedulePost(pid int64, publishAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.posts[pid]; ok && isDraft(p) {
+		p.Status = models.PostStatusScheduled
+		p.PublishAt = &publishAt
+	}
+	return nil
+}
+
+// PublishPost 把状态为from的帖子改为正常，发帖时间改为t
+func (s *PostStore) PublishPost(pid int64, from int32, t time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.posts[pid]
+	if !ok || p.Status != from {
+		return false, nil
+	}
+	p.Status = models.PostStatusNormal
+	p.PublishAt = nil
+	p.CreateTime, p.UpdateTime = t, t
+	return true, nil
+}
+
+// UnpublishPost 把正常的帖子改回发布前p的状态、定时发布时间及发帖时间
+func (s *PostStore) UnpublishPost(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if post, ok := s.posts[p.ID]; ok && post.Status == models.PostStatusNormal {
+		post.Status, post.PublishAt = p.Status, p.PublishAt
+		post.CreateTime, post.UpdateTime = p.CreateTime, p.UpdateTime
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"bell_best/models"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// RateLimitStore 内存中的令牌桶，只在单个进程内有效
+type RateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewRateLimitStore() *RateLimitStore {
+	return &RateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+// TakeToken 从key对应的令牌桶中取一个令牌，与dao/redis中的令牌桶脚本一致
+func (s *RateLimitStore) TakeToken(key string, rate float64, burst int64) (*models.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), ts: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+math.Max(now.Sub(b.ts).Seconds(), 0)*rate)
+	b.ts = now
+	res := &models.RateLimitResult{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1-b.tokens)*1000/rate) * float64(time.Millisecond))
+	}
+	res.Remaining = int64(b.tokens)
+	res.Reset = time.Duration(math.Ceil((float64(burst)-b.tokens)*1000/rate) * float64(time.Millisecond))
+	return res, nil
+}
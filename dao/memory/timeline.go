@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"bell_best/models"
+	"strconv"
+)
+
+// PushTimeline 把帖子推送到userIDs的时间线，每条时间线只保留最新的size个帖子
+func (s *VoteStore) PushTimeline(userIDs []int64, p *models.Post, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.FormatInt(p.ID, 10)
+	for _, uid := range userIDs {
+		tl := s.userTimeline(uid)
+		tl[id] = float64(p.CreateTime.Unix())
+		tl.trim(size)
+	}
+	return nil
+}
+
+// RemoveFromTimelines 把删除的帖子从userIDs的时间线中移除
+func (s *VoteStore) RemoveFromTimelines(userIDs []int64, pid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.FormatInt(pid, 10)
+	for _, uid := range userIDs {
+		delete(s.timeline[uid], id)
+	}
+	return nil
+}
+
+// AddAuthorToTimeline 关注作者后把作者的帖子合并到时间线，只保留最新的size个帖子
+func (s *VoteStore) AddAuthorToTimeline(userID, authorID, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tl := s.userTimeline(userID)
+	for id, t := range s.interSet(models.OrderTime, s.author[authorID]) {
+		tl[id] = t
+	}
+	tl.trim(size)
+	return nil
+}
+
+// RemoveAuthorFromTimeline 取消关注后把作者的帖子从时间线中移除
+func (s *VoteStore) RemoveAuthorFromTimeline(userID, authorID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.author[authorID] {
+		delete(s.timeline[userID], id)
+	}
+	return nil
+}
+
+// GetTimelineIDsByCursor 按游标查询时间线中的帖子id，pullAuthorIDs中作者的帖子在读取时合并
+func (s *VoteStore) GetTimelineIDsByCursor(userID int64, pullAuthorIDs []int64, cursor *models.PostCursor, size int64) ([]string, *models.PostCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	merged := make(zset, len(s.timeline[userID]))
+	for id, t := range s.timeline[userID] {
+		merged[id] = t
+	}
+	for _, authorID := range pullAuthorIDs {
+		for id, t := range s.interSet(models.OrderTime, s.author[authorID]) {
+			merged[id] = t
+		}
+	}
+	ids, next := merged.revRangeByCursor(cursor, size)
+	return ids, next, nil
+}
+
+func (s *VoteStore) userTimeline(uid int64) zset {
+	if s.timeline[uid] == nil {
+		s.timeline[uid] = make(zset)
+	}
+	return s.timeline[uid]
+}
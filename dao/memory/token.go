@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"bell_best/dao/redis"
+	"bell_best/models"
+	"sync"
+	"time"
+)
+
+type refreshEntry struct {
+	session  models.RefreshSession
+	expireAt time.Time
+}
+
+// TokenStore 内存中的refresh token及access token黑名单存储
+type TokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]*refreshEntry       // refresh token -> 会话
+	denied  map[string]time.Time           // jti -> 过期时间
+	access  map[int64]map[string]time.Time // user id -> jti -> 过期时间
+}
+
+func NewTokenStore() *TokenStore {
+	return &TokenStore{
+		refresh: make(map[string]*refreshEntry),
+		denied:  make(map[string]time.Time),
+		access:  make(map[int64]map[string]time.Time),
+	}
+}
+
+// SaveAccessToken 记录签发给用户的access token
+func (s *TokenStore) SaveAccessToken(userID int64, tokenID string, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	tokens := s.access[userID]
+	if tokens == nil {
+		tokens = make(map[string]time.Time)
+		s.access[userID] = tokens
+	}
+	for jti, expireAt := range tokens {
+		if now.After(expireAt) {
+			delete(tokens, jti)
+		}
+	}
+	tokens[tokenID] = now.Add(expire)
+	return nil
+}
+
+// SaveRefreshToken 保存refresh token对应的会话
+func (s *TokenStore) SaveRefreshToken(token string, session *models.RefreshSession, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[token] = &refreshEntry{session: *session, expireAt: time.Now().Add(expire)}
+	return nil
+}
+
+// TakeRefreshToken 取出并删除refresh token
+func (s *TokenStore) TakeRefreshToken(token string) (*models.RefreshSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.refresh[token]
+	delete(s.refresh, token)
+	if !ok || time.Now().After(e.expireAt) {
+		return nil, redis.ErrRefreshTokenNotExist
+	}
+	session := e.session
+	return &session, nil
+}
+
+// RemoveRefreshToken 删除用户的refresh token，不属于该用户的token不做处理
+func (s *TokenStore) RemoveRefreshToken(userID int64, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.refresh[token]; ok && e.session.UserID == userID {
+		delete(s.refresh, token)
+	}
+	return nil
+}
+
+// DenyToken 拉黑access token
+func (s *TokenStore) DenyToken(tokenID string, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.denied[tokenID] = time.Now().Add(expire)
+	return nil
+}
+
+// IsTokenDenied 判断access token是否已被拉黑
+func (s *TokenStore) IsTokenDenied(tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expireAt, ok := s.denied[tokenID]
+	return ok && time.Now().Before(expireAt), nil
+}
+
+// RevokeUserTokens 作废用户所有的refresh token，并拉黑用户所有未过期的access token
+func (s *TokenStore) RevokeUserTokens(userID int64, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for jti, expireAt := range s.access[userID] {
+		if now.Before(expireAt) {
+			s.denied[jti] = now.Add(expire)
+		}
+	}
+	delete(s.access, userID)
+	for token, e := range s.refresh {
+		if e.session.UserID != userID {
+			continue
+		}
+		if e.session.TokenID != "" {
+			s.denied[e.session.TokenID] = time.Now().Add(expire)
+		}
+		delete(s.refresh, token)
+	}
+	return nil
+}
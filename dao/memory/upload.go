@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UploadStore 内存中的上传图片记录存储
+type UploadStore struct {
+	mu      sync.Mutex
+	uploads map[int64]*models.Upload
+}
+
+func NewUploadStore() *UploadStore {
+	return &UploadStore{uploads: make(map[int64]*models.Upload)}
+}
+
+// CreateUpload 保存上传图片的记录
+func (s *UploadStore) CreateUpload(u *models.Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload := *u
+	now := time.Now()
+	upload.CreateTime, upload.UpdateTime = now, now
+	s.uploads[upload.ID] = &upload
+	return nil
+}
+
+// SetPostUploads 把作者上传的图片关联到帖子，帖子不再引用的图片取消关联
+func (s *UploadStore) SetPostUploads(userID, postID int64, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keep := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		keep[id] = struct{}{}
+	}
+	now := time.Now()
+	for _, u := range s.uploads {
+		_, ok := keep[u.ID]
+		switch {
+		case u.PostID == postID && !ok:
+			u.PostID, u.UpdateTime = 0, now
+		case u.PostID == 0 && ok && u.UserID == userID:
+			u.PostID, u.UpdateTime = postID, now
+		}
+	}
+	return nil
+}
+
+// GetOrphanUploads 查询没有帖子引用、且在before之前就没有变化的图片
+func (s *UploadStore) GetOrphanUploads(before time.Time, size int64) ([]*models.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]*models.Upload, 0)
+	for _, u := range s.uploads {
+		if u.PostID == 0 && u.UpdateTime.Before(before) {
+			upload := *u
+			data = append(data, &upload)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].UpdateTime.Before(data[j].UpdateTime) })
+	if int64(len(data)) > size {
+		data = data[:size]
+	}
+	return data, nil
+}
+
+// DeleteOrphanUpload 删除仍然没有帖子引用的图片记录
+func (s *UploadStore) DeleteOrphanUpload(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok || u.PostID != 0 {
+		return false, nil
+	}
+	delete(s.uploads, id)
+	return true, nil
+}
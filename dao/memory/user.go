@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/password"
+	"bell_best/pkg/rbac"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// memory包提供logic层存储接口的内存实现
+// 不依赖MySQL和Redis，返回的错误与dao/mysql、dao/redis保持一致，方便在测试中替换
+
+// UserStore 内存中的用户存储
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[int64]*models.User // user_id -> user
+	names map[string]int64       // username -> user_id
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{
+		users: make(map[int64]*models.User),
+		names: make(map[string]int64),
+	}
+}
+
+// CheckUserExist 检查指定用户名的用户是否存在
+func (s *UserStore) CheckUserExist(username string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.names[username]; ok {
+		return models.ErrorUserExist
+	}
+	return nil
+}
+
+// InsertUser 保存一条新的用户记录
+func (s *UserStore) InsertUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.names[user.Username]; ok {
+		return models.ErrorUserExist
+	}
+	hashed, err := password.Hash(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	u := *user
+	if u.Role == "" {
+		u.Role = rbac.RoleUser
+	}
+	u.CreateTime = time.Now()
+	s.users[u.UserID] = &u
+	s.names[u.Username] = u.UserID
+	return nil
+}
+
+// Login 校验用户名和密码，成功时把用户id填充到user中
+func (s *UserStore) Login(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid, ok := s.names[user.Username]
+	if !ok {
+		return models.ErrorUserNotExist
+	}
+	u := s.users[uid]
+	ok, rehash, err := password.Verify(user.Password, u.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.ErrorInvalidPassword
+	}
+	if rehash {
+		if hashed, err := password.Hash(user.Password); err == nil {
+			u.Password = hashed
+		}
+	}
+	user.UserID = u.UserID
+	user.Role = u.Role
+	return nil
+}
+
+// GetUserByID 根据id获取用户信息
+func (s *UserStore) GetUserByID(uid int64) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[uid]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &models.User{UserID: u.UserID, Username: u.Username, Role: u.Role}, nil
+}
+
+// GetUserByUsername 根据用户名获取用户信息
+func (s *UserStore) GetUserByUsername(username string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.names[username]
+	if !ok {
+		return nil, models.ErrorUserNotExist
+	}
+	u := s.users[uid]
+	return &models.User{UserID: u.UserID, Username: u.Username, Role: u.Role}, nil
+}
+
+// UpdateUserRole 修改用户的角色
+func (s *UserStore) UpdateUserRole(uid int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[uid]
+	if !ok {
+		return models.ErrorUserNotExist
+	}
+	u.Role = role
+	return nil
+}
+
+// GetUserProfile 根据id获取用户的资料
+func (s *UserStore) GetUserProfile(uid int64) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[uid]
+	if !ok {
+		return nil, models.ErrorUserNotExist
+	}
+	return &models.User{
+		UserID:      u.UserID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		CreateTime:  u.CreateTime,
+	}, nil
+}
+
+// UpdateUserProfile 更新用户的资料
+func (s *UserStore) UpdateUserProfile(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[user.UserID]
+	if !ok {
+		return models.ErrorUserNotExist
+	}
+	u.DisplayName, u.Bio, u.AvatarURL = user.DisplayName, user.Bio, user.AvatarURL
+	return nil
+}
+
+// GetUsersByIDs 根据id列表批量获取用户信息，不存在的id忽略
+func (s *UserStore) GetUsersByIDs(uids []int64) ([]*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := make([]*models.User, 0, len(uids))
+	for _, uid := range uids {
+		if u, ok := s.users[uid]; ok {
+			users = append(users, &models.User{UserID: u.UserID, Username: u.Username})
+		}
+	}
+	return users, nil
+}
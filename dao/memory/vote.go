@@ -0,0 +1,374 @@
+package memory
+
+import (
+	"bell_best/dao/redis"
+	"bell_best/models"
+	"bell_best/pkg/rank"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 与dao/redis中的投票规则保持一致
+const oneWeekInSeconds = 7 * 24 * 60 * 60
+
+// VoteStore 内存中的投票及帖子排序存储
+type VoteStore struct {
+	mu        sync.Mutex
+	postTime  zset
+	postScore zset                           // 默认算法计算的分数
+	commScore map[int64]zset                 // community_id -> 社区算法计算的分数
+	community map[int64]map[string]struct{}  // community_id -> post ids
+	author    map[int64]map[string]struct{}  // author_id -> post ids
+	tag       map[string]map[string]struct{} // 标签 -> post ids
+	tagUsage  map[int64]zset                 // unix时间/3600 -> (标签 -> 使用次数)
+	voted     map[string]zset                // post_id -> (user_id -> direction)
+	timeline  map[int64]zset                 // user_id -> 关注的作者推送的帖子及发帖时间
+	archived  bool                           // 是否归档过
+	until     float64                        // 已归档帖子的最晚发帖时间
+}
+
+func NewVoteStore() *VoteStore {
+	return &VoteStore{
+		postTime:  make(zset),
+		postScore: make(zset),
+		commScore: make(map[int64]zset),
+		community: make(map[int64]map[string]struct{}),
+		author:    make(map[int64]map[string]struct{}),
+		tag:       make(map[string]map[string]struct{}),
+		tagUsage:  make(map[int64]zset),
+		voted:     make(map[string]zset),
+		timeline:  make(map[int64]zset),
+	}
+}
+
+// CreatePost 记录帖子的发帖时间、初始分数、所属社区及作者
+func (s *VoteStore) CreatePost(p *models.Post, r rank.Ranker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.FormatInt(p.ID, 10)
+	now := time.Now()
+	s.postTime[id] = float64(now.Unix())
+	s.postScore[id] = rank.Default().Score(0, 0, now, now)
+	s.communityScore(p.CommunityID)[id] = r.Score(0, 0, now, now)
+	addToSet(s.community, p.CommunityID, id)
+	addToSet(s.author, p.AuthorID, id)
+	hour := now.Unix() / 3600
+	for _, tag := range p.Tags {
+		addToSet(s.tag, tag, id)
+		if s.tagUsage[hour] == nil {
+			s.tagUsage[hour] = make(zset)
+		}
+		s.tagUsage[hour][tag]++
+	}
+	return nil
+}
+
+// communityScore 社区的分数zset，不存在时创建
+func (s *VoteStore) communityScore(communityID int64) zset {
+	scores := s.commScore[communityID]
+	if scores == nil {
+		scores = make(zset)
+		s.commScore[communityID] = scores
+	}
+	return scores
+}
+
+// addToSet 把id加到key对应的集合中
+func addToSet[K, V comparable](sets map[K]map[V]struct{}, key K, id V) {
+	if sets[key] == nil {
+		sets[key] = make(map[V]struct{})
+	}
+	sets[key][id] = struct{}{}
+}
+
+// RemovePost 把帖子从时间、分数、社区及作者的排序中移除并删除投票记录
+func (s *VoteStore) RemovePost(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.FormatInt(p.ID, 10)
+	delete(s.postTime, id)
+	delete(s.postScore, id)
+	delete(s.commScore[p.CommunityID], id)
+	delete(s.voted, id)
+	delete(s.community[p.CommunityID], id)
+	delete(s.author[p.AuthorID], id)
+	for _, tag := range p.Tags {
+		delete(s.tag[tag], id)
+	}
+	return nil
+}
+
+// VoteForPost 为帖子投票
+func (s *VoteStore) VoteForPost(userID, postID string, communityID int64, value float64, r rank.Ranker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	postTime, ok := s.postTime[postID]
+	if !ok {
+		return redis.ErrVotePostNotExist
+	}
+	if float64(now.Unix())-postTime > oneWeekInSeconds {
+		return redis.ErrVoteTimeExpired
+	}
+	votes := s.voted[postID]
+	if votes == nil {
+		votes = make(zset)
+		s.voted[postID] = votes
+	}
+	ov := votes[userID]
+	if value == ov {
+		return redis.ErrVoteRepested
+	}
+	if value == 0 {
+		delete(votes, userID)
+	} else {
+		votes[userID] = value
+	}
+	ups, downs := votes.count()
+	created := time.Unix(int64(postTime), 0)
+	s.postScore[postID] = rank.Default().Score(ups, downs, created, now)
+	s.communityScore(communityID)[postID] = r.Score(ups, downs, created, now)
+	return nil
+}
+
+// RecomputeScores 用默认算法及社区的排序算法r重新计算社区下仍在投票期内的帖子的分数
+func (s *VoteStore) RecomputeScores(communityID int64, r rank.Ranker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	def, scores := rank.Default(), s.communityScore(communityID)
+	for id := range s.community[communityID] {
+		postTime, ok := s.postTime[id]
+		if !ok || float64(now.Unix())-postTime > oneWeekInSeconds {
+			continue
+		}
+		ups, downs := s.voted[id].count()
+		created := time.Unix(int64(postTime), 0)
+		s.postScore[id] = def.Score(ups, downs, created, now)
+		scores[id] = r.Score(ups, downs, created, now)
+	}
+	return nil
+}
+
+// GetPostIDsInOrder 按时间或分数分页查询帖子id
+func (s *VoteStore) GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := (p.Page - 1) * p.Size
+	return s.orderSet(p.Order).revRange(start, start+p.Size-1), nil
+}
+
+// GetCommunityPostIDsInOrder 按社区分页查询帖子id
+func (s *VoteStore) GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := (p.Page - 1) * p.Size
+	return s.communitySet(p).revRange(start, start+p.Size-1), nil
+}
+
+// communitySet 社区帖子集合与时间zset的交集，按分数排序时直接使用社区的分数
+func (s *VoteStore) communitySet(p *models.ParamPostList) zset {
+	if p.Order == models.OrderScore {
+		return s.commScore[p.CommunityID]
+	}
+	return s.interSet(p.Order, s.community[p.CommunityID])
+}
+
+// communitiesSet 多个社区帖子集合的并集与时间或分数zset的交集
+func (s *VoteStore) communitiesSet(order string, communityIDs []int64) zset {
+	union := make(map[string]struct{})
+	for _, cid := range communityIDs {
+		for id := range s.community[cid] {
+			union[id] = struct{}{}
+		}
+	}
+	return s.interSet(order, union)
+}
+
+// tagSet 标签帖子集合与时间或分数zset的交集，社区id不为0时再与社区帖子求交集
+func (s *VoteStore) tagSet(p *models.ParamPostList) zset {
+	if p.CommunityID == 0 {
+		return s.interSet(p.Order, s.tag[p.Tag])
+	}
+	if p.Order == models.OrderScore {
+		return interZset(s.commScore[p.CommunityID], s.tag[p.Tag])
+	}
+	return s.interSet(p.Order, s.tag[p.Tag], s.community[p.CommunityID])
+}
+
+// interSet 帖子id集合与时间或分数zset的交集
+func (s *VoteStore) interSet(order string, sets ...map[string]struct{}) zset {
+	return interZset(s.orderSet(order), sets...)
+}
+
+// interZset 帖子id集合与orderSet的交集
+func interZset(orderSet zset, sets ...map[string]struct{}) zset {
+	inter := make(zset)
+next:
+	for id := range sets[0] {
+		for _, set := range sets[1:] {
+			if _, ok := set[id]; !ok {
+				continue next
+			}
+		}
+		if score, ok := orderSet[id]; ok {
+			inter[id] = score
+		}
+	}
+	return inter
+}
+
+// GetPostIDsByCursor 按游标查询帖子id
+func (s *VoteStore) GetPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, next := s.orderSet(p.Order).revRangeByCursor(cursor, p.Size)
+	return ids, next, nil
+}
+
+// GetCommunityPostIDsByCursor 按社区及游标查询帖子id
+func (s *VoteStore) GetCommunityPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, next := s.communitySet(p).revRangeByCursor(cursor, p.Size)
+	return ids, next, nil
+}
+
+// GetCommunitiesPostIDsInOrder 按多个社区分页查询帖子id
+func (s *VoteStore) GetCommunitiesPostIDsInOrder(p *models.ParamPostList, communityIDs []int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := (p.Page - 1) * p.Size
+	return s.communitiesSet(p.Order, communityIDs).revRange(start, start+p.Size-1), nil
+}
+
+// GetCommunitiesPostIDsByCursor 按多个社区及游标查询帖子id
+func (s *VoteStore) GetCommunitiesPostIDsByCursor(p *models.ParamPostList, communityIDs []int64, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, next := s.communitiesSet(p.Order, communityIDs).revRangeByCursor(cursor, p.Size)
+	return ids, next, nil
+}
+
+// GetAuthorPostIDsByCursor 按作者及游标查询帖子id
+func (s *VoteStore) GetAuthorPostIDsByCursor(authorID int64, p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, next := s.interSet(p.Order, s.author[authorID]).revRangeByCursor(cursor, p.Size)
+	return ids, next, nil
+}
+
+// GetTagPostIDsInOrder 按标签分页查询帖子id
+func (s *VoteStore) GetTagPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := (p.Page - 1) * p.Size
+	return s.tagSet(p).revRange(start, start+p.Size-1), nil
+}
+
+// GetTagPostIDsByCursor 按标签及游标查询帖子id
+func (s *VoteStore) GetTagPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, next := s.tagSet(p).revRangeByCursor(cursor, p.Size)
+	return ids, next, nil
+}
+
+// GetTrendingTags 按最近window内新帖子使用的次数返回前size个标签
+func (s *VoteStore) GetTrendingTags(window time.Duration, size int64) ([]*models.TagCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hours := max(int64(math.Ceil(window.Hours())), 1)
+	now := time.Now().Unix() / 3600
+	counts := make(zset)
+	for h := now - hours + 1; h <= now; h++ {
+		for tag, n := range s.tagUsage[h] {
+			counts[tag] += n
+		}
+	}
+	tags := counts.revRange(0, size-1)
+	data := make([]*models.TagCount, 0, len(tags))
+	for _, tag := range tags {
+		data = append(data, &models.TagCount{Tag: tag, Count: int64(counts[tag])})
+	}
+	return data, nil
+}
+
+// GetPostVoteData 查询每篇帖子的赞成票、反对票及userID的投票
+func (s *VoteStore) GetPostVoteData(ids []string, userID string) ([]*models.PostVoteData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]*models.PostVoteData, 0, len(ids))
+	for _, id := range ids {
+		ups, downs := s.voted[id].count()
+		data = append(data, &models.PostVoteData{UpVotes: ups, DownVotes: downs, MyVote: int8(s.voted[id][userID])})
+	}
+	return data, nil
+}
+
+// GetVoteExpiredPostIDs 按发帖时间从早到晚查询投票期已结束、还没有归档的帖子
+func (s *VoteStore) GetVoteExpiredPostIDs(limit int64) ([]string, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	max := float64(time.Now().Unix() - oneWeekInSeconds)
+	ids := make([]string, 0)
+	for id, t := range s.postTime {
+		if t <= max && (!s.archived || t > s.until) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, 0, nil
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := s.postTime[ids[i]], s.postTime[ids[j]]
+		return ti < tj || (ti == tj && ids[i] < ids[j])
+	})
+	// 同一秒发的帖子放在同一批
+	n := len(ids)
+	if int64(n) > limit {
+		n = int(limit)
+		for n < len(ids) && s.postTime[ids[n]] == s.postTime[ids[n-1]] {
+			n++
+		}
+	}
+	ids = ids[:n]
+	return ids, s.postTime[ids[n-1]], nil
+}
+
+// GetPostVotes 查询帖子的赞成票及反对票数
+func (s *VoteStore) GetPostVotes(ids []string) ([]*models.PostVotes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]*models.PostVotes, 0, len(ids))
+	for _, id := range ids {
+		pid, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ups, downs := s.voted[id].count()
+		data = append(data, &models.PostVotes{PostID: pid, UpVotes: ups, DownVotes: downs})
+	}
+	return data, nil
+}
+
+// ArchivePostVotes 删除已归档帖子的投票记录
+func (s *VoteStore) ArchivePostVotes(ids []string, until float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.voted, id)
+	}
+	s.archived, s.until = true, until
+	return nil
+}
+
+func (s *VoteStore) orderSet(order string) zset {
+	if order == models.OrderScore {
+		return s.postScore
+	}
+	return s.postTime
+}
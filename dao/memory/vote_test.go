@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/rank"
+	"testing"
+)
+
+func TestRemovePostDeletesVotes(t *testing.T) {
+	s := NewVoteStore()
+	p := &models.Post{ID: 1, AuthorID: 2, CommunityID: 1}
+	if err := s.CreatePost(p, rank.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VoteForPost("3", "1", p.CommunityID, 1, rank.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.voted["1"]) != 1 {
+		t.Fatalf("voted = %v, want one vote", s.voted["1"])
+	}
+	if err := s.RemovePost(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.voted["1"]; ok {
+		t.Errorf("votes of removed post not deleted: %v", s.voted["1"])
+	}
+}
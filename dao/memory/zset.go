@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"bell_best/models"
+	"sort"
+)
+
+// zset 模拟redis的有序集合，member -> score
+type zset map[string]float64
+
+// revRangeByCursor 按分数从大到小返回游标之后的size个member及下一页的游标
+func (z zset) revRangeByCursor(cursor *models.PostCursor, size int64) ([]string, *models.PostCursor) {
+	all := z.revRange(0, int64(len(z))-1)
+	ids := make([]string, 0, size)
+	for _, m := range all {
+		if int64(len(ids)) == size {
+			break
+		}
+		if cursor != nil {
+			s := z[m]
+			if s > cursor.Score || (s == cursor.Score && m >= cursor.ID) {
+				continue
+			}
+		}
+		ids = append(ids, m)
+	}
+	if int64(len(ids)) < size || size == 0 {
+		return ids, nil
+	}
+	last := ids[len(ids)-1]
+	return ids, &models.PostCursor{Score: z[last], ID: last}
+}
+
+// revRange 按分数从大到小返回第start到end(包含)个member，分数相同时按member倒序，与ZREVRANGE一致
+func (z zset) revRange(start, end int64) []string {
+	members := make([]string, 0, len(z))
+	for m := range z {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		si, sj := z[members[i]], z[members[j]]
+		if si != sj {
+			return si > sj
+		}
+		return members[i] > members[j]
+	})
+	n := int64(len(members))
+	if start < 0 || start >= n || end < start {
+		return []string{}
+	}
+	if end >= n {
+		end = n - 1
+	}
+	return members[start : end+1]
+}
+
+// trim 只保留分数最大的size个member，与ZREMRANGEBYRANK key 0 -size-1一致
+func (z zset) trim(size int64) {
+	for _, m := range z.revRange(size, int64(len(z))-1) {
+		delete(z, m)
+	}
+}
+
+// count 投票记录中赞成票及反对票的数量
+func (z zset) count() (ups, downs int64) {
+	for _, v := range z {
+		switch v {
+		case 1:
+			ups++
+		case -1:
+			downs++
+		}
+	}
+	return
+}
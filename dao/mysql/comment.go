@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"bell_best/models"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateComment 创建评论
+func CreateComment(c *models.Comment) (err error) {
+	sqlStr := `insert into comment(comment_id,post_id,parent_id,root_id,author_id,content,create_time) values(?,?,?,?,?,?,?)`
+	_, err = db.Exec(sqlStr, c.ID, c.PostID, c.ParentID, c.RootID, c.AuthorID, c.Content, c.CreateTime)
+	return
+}
+
+// GetCommentByID 根据id查询单条评论
+func GetCommentByID(cid int64) (comment *models.Comment, err error) {
+	comment = new(models.Comment)
+	sqlStr := `select comment_id,post_id,parent_id,root_id,author_id,content,create_time from comment where comment_id = ?`
+	err = db.Get(comment, sqlStr, cid)
+	if err == sql.ErrNoRows {
+		err = models.ErrorCommentNotExist
+	}
+	return
+}
+
+// GetCommentList 按时间顺序查询帖子下id大于cursor的评论
+func GetCommentList(postID, cursor, size int64) (comments []*models.Comment, err error) {
+	sqlStr := `select comment_id,post_id,parent_id,root_id,author_id,content,create_time from comment
+	where post_id = ? and comment_id > ? order by comment_id limit ?`
+	comments = make([]*models.Comment, 0, size)
+	err = db.Select(&comments, sqlStr, postID, cursor, size)
+	return
+}
+
+// GetRootCommentList 按时间顺序查询帖子下id大于cursor的顶层评论
+func GetRootCommentList(postID, cursor, size int64) (comments []*models.Comment, err error) {
+	sqlStr := `select comment_id,post_id,parent_id,root_id,author_id,content,create_time from comment
+	where post_id = ? and parent_id = 0 and comment_id > ? order by comment_id limit ?`
+	comments = make([]*models.Comment, 0, size)
+	err = db.Select(&comments, sqlStr, postID, cursor, size)
+	return
+}
+
+// GetCommentsByRootIDs 查询给定顶层评论下的全部回复
+func GetCommentsByRootIDs(rootIDs []int64) (comments []*models.Comment, err error) {
+	if len(rootIDs) == 0 {
+		return
+	}
+	sqlStr := `select comment_id,post_id,parent_id,root_id,author_id,content,create_time from comment
+	where root_id in (?) and parent_id != 0 order by comment_id`
+	query, args, err := sqlx.In(sqlStr, rootIDs)
+	if err != nil {
+		return
+	}
+	query = db.Rebind(query)
+	err = db.Select(&comments, query, args...)
+	return
+}
+
+// GetCommentNumByPostIDs 查询每篇帖子的评论数，没有评论的帖子不在结果中
+func GetCommentNumByPostIDs(postIDs []int64) (data map[int64]int64, err error) {
+	data = make(map[int64]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return
+	}
+	sqlStr := `select post_id,count(comment_id) as num from comment where post_id in (?) group by post_id`
+	query, args, err := sqlx.In(sqlStr, postIDs)
+	if err != nil {
+		return
+	}
+	query = db.Rebind(query)
+	var rows []struct {
+		PostID int64 `db:"post_id"`
+		Num    int64 `db:"num"`
+	}
+	if err = db.Select(&rows, query, args...); err != nil {
+		return
+	}
+	for _, row := range rows {
+		data[row.PostID] = row.Num
+	}
+	return
+}
@@ -3,6 +3,11 @@ package mysql
 import (
 	"bell_best/models"
 	"database/sql"
+	"errors"
+	"strings"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
@@ -20,11 +25,190 @@ func GetCommunityList() (communityList []*models.Community, err error) {
 // GetCommunityDetailByID 根据id查询社区详情
 func GetCommunityDetailByID(id int64) (community *models.CommunityDetail, err error) {
 	community = new(models.CommunityDetail)
-	sqlStr := `select community_id,community_name,introduction,create_time from community where community_id = ?`
+	sqlStr := `select community_id,community_name,introduction,archived,subscriber_count,create_time from community where community_id = ?`
 	if err := db.Get(community, sqlStr, id); err != nil {
 		if err == sql.ErrNoRows {
-			err = ErrorInvalidID
+			err = models.ErrorInvalidID
 		}
 	}
 	return community, err
 }
+
+// GetCommunitiesByIDs 根据id列表批量查询社区详情
+func GetCommunitiesByIDs(ids []int64) (communities []*models.CommunityDetail, err error) {
+	if len(ids) == 0 {
+		return
+	}
+	sqlStr := `select community_id,community_name,introduction,archived,subscriber_count,create_time from community where community_id in (?)`
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return
+	}
+	query = db.Rebind(query)
+	err = db.Select(&communities, query, args...)
+	return
+}
+
+// checkCommunityName 检查社区名称是否被其他社区使用
+func checkCommunityName(name string, excludeID int64) error {
+	sqlStr := `select count(community_id) from community where community_name = ? and community_id != ?`
+	var count int
+	if err := db.Get(&count, sqlStr, name, excludeID); err != nil {
+		return err
+	}
+	if count > 0 {
+		return models.ErrorCommunityExist
+	}
+	return nil
+}
+
+// createCommunityRetries 并发创建社区时community_id冲突的重试次数
+const createCommunityRetries = 5
+
+// CreateCommunity 创建社区，社区id在已有的最大id上加1，
+// 并发创建时名称冲突返回ErrorCommunityExist，id冲突则重新分配
+func CreateCommunity(c *models.CommunityDetail) (err error) {
+	if err = checkCommunityName(c.Name, 0); err != nil {
+		return err
+	}
+	sqlStr := `insert into community (community_id,community_name,introduction)
+	select coalesce(max(community_id),0)+1,?,? from community`
+	var ret sql.Result
+	for i := 0; i < createCommunityRetries; i++ {
+		ret, err = db.Exec(sqlStr, c.Name, c.Introduction)
+		key, dup := duplicateKey(err)
+		if !dup {
+			break
+		}
+		if strings.HasSuffix(key, "idx_community_name") {
+			return models.ErrorCommunityExist
+		}
+	}
+	if err != nil {
+		return err
+	}
+	id, err := ret.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sqlStr = `select community_id,community_name,introduction,archived,subscriber_count,create_time from community where id = ?`
+	return db.Get(c, sqlStr, id)
+}
+
+// duplicateKey 判断是否为唯一索引冲突(1062)，返回冲突的索引名
+func duplicateKey(err error) (key string, ok bool) {
+	var me *driver.MySQLError
+	if !errors.As(err, &me) || me.Number != 1062 {
+		return "", false
+	}
+	// Duplicate entry 'xxx' for key 'idx_xxx'，MySQL 8带有表名前缀'table.idx_xxx'
+	if i := strings.LastIndex(me.Message, "for key "); i >= 0 {
+		key = strings.Trim(me.Message[i+len("for key "):], "'`")
+	}
+	return key, true
+}
+
+// UpdateCommunity 更新社区的名称、简介及归档状态
+func UpdateCommunity(c *models.CommunityDetail) (err error) {
+	if err = checkCommunityName(c.Name, c.ID); err != nil {
+		return err
+	}
+	sqlStr := `update community set community_name = ?,introduction = ?,archived = ? where community_id = ?`
+	_, err = db.Exec(sqlStr, c.Name, c.Introduction, c.Archived, c.ID)
+	return
+}
+
+// GetCommunityModerators 查询社区的版主id
+func GetCommunityModerators(communityID int64) (uids []int64, err error) {
+	sqlStr := `select user_id from community_moderator where community_id = ? order by id`
+	err = db.Select(&uids, sqlStr, communityID)
+	return
+}
+
+// IsCommunityModerator 判断用户是不是社区的版主
+func IsCommunityModerator(communityID, uid int64) (bool, error) {
+	sqlStr := `select count(id) from community_moderator where community_id = ? and user_id = ?`
+	var count int
+	if err := db.Get(&count, sqlStr, communityID, uid); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddCommunityModerator 添加社区版主，已经是版主时忽略
+func AddCommunityModerator(communityID, uid int64) (err error) {
+	sqlStr := `insert ignore into community_moderator (community_id,user_id) values(?,?)`
+	_, err = db.Exec(sqlStr, communityID, uid)
+	return
+}
+
+// RemoveCommunityModerator 移除社区版主
+func RemoveCommunityModerator(communityID, uid int64) (err error) {
+	sqlStr := `delete from community_moderator where community_id = ? and user_id = ?`
+	_, err = db.Exec(sqlStr, communityID, uid)
+	return
+}
+
+// SubscribeCommunity 订阅社区，订阅关系和社区的订阅人数在同一个事务中修改，已经订阅时返回false
+func SubscribeCommunity(communityID, uid int64) (ok bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := `insert ignore into community_subscriber (community_id,user_id) values(?,?)`
+	ret, err := tx.Exec(sqlStr, communityID, uid)
+	if err != nil {
+		return false, err
+	}
+	if ok, err = updateSubscriberCount(tx, ret, communityID, 1); err != nil {
+		return false, err
+	}
+	return ok, tx.Commit()
+}
+
+// UnsubscribeCommunity 取消订阅社区，没有订阅时返回false
+func UnsubscribeCommunity(communityID, uid int64) (ok bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := `delete from community_subscriber where community_id = ? and user_id = ?`
+	ret, err := tx.Exec(sqlStr, communityID, uid)
+	if err != nil {
+		return false, err
+	}
+	if ok, err = updateSubscriberCount(tx, ret, communityID, -1); err != nil {
+		return false, err
+	}
+	return ok, tx.Commit()
+}
+
+// updateSubscriberCount 订阅关系有变化时修改社区的订阅人数，返回是否有变化
+func updateSubscriberCount(tx *sqlx.Tx, ret sql.Result, communityID, delta int64) (bool, error) {
+	n, err := ret.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	sqlStr := `update community set subscriber_count = subscriber_count + ? where community_id = ?`
+	if _, err = tx.Exec(sqlStr, delta, communityID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetSubscribedCommunityIDs 查询用户订阅的社区id
+func GetSubscribedCommunityIDs(uid int64) (ids []int64, err error) {
+	sqlStr := `select community_id from community_subscriber where user_id = ? order by community_id`
+	err = db.Select(&ids, sqlStr, uid)
+	return
+}
@@ -0,0 +1,30 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+func TestDuplicateKey(t *testing.T) {
+	tests := []struct {
+		err     error
+		wantKey string
+		wantOK  bool
+	}{
+		{nil, "", false},
+		{errors.New("Duplicate entry"), "", false},
+		{&driver.MySQLError{Number: 1146, Message: "Table 'x' doesn't exist"}, "", false},
+		{&driver.MySQLError{Number: 1062, Message: "Duplicate entry 'Go' for key 'idx_community_name'"}, "idx_community_name", true},
+		{&driver.MySQLError{Number: 1062, Message: "Duplicate entry '3' for key 'community.idx_community_id'"}, "community.idx_community_id", true},
+		{fmt.Errorf("exec: %w", &driver.MySQLError{Number: 1062, Message: "Duplicate entry 'Go' for key 'community.idx_community_name'"}), "community.idx_community_name", true},
+	}
+	for _, tt := range tests {
+		key, ok := duplicateKey(tt.err)
+		if key != tt.wantKey || ok != tt.wantOK {
+			t.Errorf("duplicateKey(%v) = %q, %v, want %q, %v", tt.err, key, ok, tt.wantKey, tt.wantOK)
+		}
+	}
+}
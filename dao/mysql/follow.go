@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"bell_best/models"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// Follow 关注用户，关注关系和双方的粉丝数、关注数在同一个事务中修改，已经关注时返回false
+func Follow(followerID, followeeID int64) (ok bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	ret, err := tx.Exec(`insert ignore into follow(follower_id,followee_id) values(?,?)`, followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	if ok, err = updateFollowCount(tx, ret, followerID, followeeID, 1); err != nil {
+		return false, err
+	}
+	return ok, tx.Commit()
+}
+
+// Unfollow 取消关注，没有关注时返回false
+func Unfollow(followerID, followeeID int64) (ok bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	ret, err := tx.Exec(`delete from follow where follower_id = ? and followee_id = ?`, followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	if ok, err = updateFollowCount(tx, ret, followerID, followeeID, -1); err != nil {
+		return false, err
+	}
+	return ok, tx.Commit()
+}
+
+// updateFollowCount 关注关系有变化时修改粉丝数及关注数，返回是否有变化
+func updateFollowCount(tx *sqlx.Tx, ret sql.Result, followerID, followeeID, delta int64) (bool, error) {
+	n, err := ret.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if _, err = tx.Exec(`update user set following_count = following_count + ? where user_id = ?`, delta, followerID); err != nil {
+		return false, err
+	}
+	if _, err = tx.Exec(`update user set follower_count = follower_count + ? where user_id = ?`, delta, followeeID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetFollowerIDs 查询用户的粉丝id
+func GetFollowerIDs(uid int64) (ids []int64, err error) {
+	sqlStr := `select follower_id from follow where followee_id = ?`
+	err = db.Select(&ids, sqlStr, uid)
+	return
+}
+
+// GetFollowingIDs 查询用户关注的、粉丝数不少于minFollowers的用户id
+func GetFollowingIDs(uid, minFollowers int64) (ids []int64, err error) {
+	sqlStr := `select f.followee_id from follow f join user u on u.user_id = f.followee_id
+	where f.follower_id = ? and u.follower_count >= ?`
+	err = db.Select(&ids, sqlStr, uid, minFollowers)
+	return
+}
+
+// GetFollowCount 查询用户的粉丝数及关注数
+func GetFollowCount(uid int64) (count *models.FollowCount, err error) {
+	count = new(models.FollowCount)
+	sqlStr := `select follower_count,following_count from user where user_id = ?`
+	err = db.Get(count, sqlStr, uid)
+	if err == sql.ErrNoRows {
+		err = models.ErrorUserNotExist
+	}
+	return
+}
@@ -2,38 +2,86 @@ package mysql
 
 import (
 	"bell_best/models"
+	"database/sql"
 	"github.com/jmoiron/sqlx"
 	"strings"
+	"time"
 )
 
-// CreatePost 创建帖子
+// CreatePost 创建帖子，帖子、标签和第1个历史版本在同一个事务中保存
 func CreatePost(p *models.Post) (err error) {
-	sqlStr := "insert into post(post_id,title,content,post.author_id,community_id) values(?,?,?,?,?)"
-	_, err = db.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID)
-	return
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := "insert into post(post_id,title,content,post.author_id,community_id,status,publish_at) values(?,?,?,?,?,?,?)"
+	if _, err = tx.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID, p.Status, p.PublishAt); err != nil {
+		return err
+	}
+	for _, tag := range p.Tags {
+		if _, err = tx.Exec(`insert into post_tag(post_id,tag) values(?,?)`, p.ID, tag); err != nil {
+			return err
+		}
+	}
+	if err = insertRevision(tx, p.ID, 1, p.AuthorID, p.Title, p.Content, p.CreateTime); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetPostByID 根据id查询单个帖子数据
+// GetPostTags 根据帖子id列表批量查询帖子的标签
+func GetPostTags(pids []int64) (map[int64][]string, error) {
+	data := make(map[int64][]string, len(pids))
+	if len(pids) == 0 {
+		return data, nil
+	}
+	sqlStr := `select post_id,tag from post_tag where post_id in (?) order by id`
+	query, args, err := sqlx.In(sqlStr, pids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		PostID int64  `db:"post_id"`
+		Tag    string `db:"tag"`
+	}
+	if err = db.Select(&rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		data[r.PostID] = append(data[r.PostID], r.Tag)
+	}
+	return data, nil
+}
+
+// GetPostByID 根据id查询单个帖子数据，已删除的帖子视为不存在
 func GetPostByID(pid int64) (post *models.Post, err error) {
 	post = new(models.Post)
-	sqlStr := "select post_id,title,content,author_id,community_id,create_time from post where post_id = ?"
+	sqlStr := "select post_id,title,content,author_id,community_id,status,create_time,update_time from post where post_id = ? and status = ?"
 	// db.Exec和Get的用法？？？？？？？？？？？？？？？？
-	err = db.Get(post, sqlStr, pid)
+	err = db.Get(post, sqlStr, pid, models.PostStatusNormal)
+	if err == sql.ErrNoRows {
+		err = models.ErrorPostNotExist
+	}
 	return
 }
 
 // GetPostList 查询帖子列表函数
 func GetPostList(page, size int64) (posts []*models.Post, err error) {
-	sqlStr := `select post_id,title,content,author_id,community_id,create_time from post ORDER BY create_time DESC limit ?,?`
+	sqlStr := `select post_id,title,content,author_id,community_id,status,create_time,update_time from post where status = ? ORDER BY create_time DESC limit ?,?`
 	posts = make([]*models.Post, 0, 2)
-	err = db.Select(&posts, sqlStr, (page-1)*size, size)
+	err = db.Select(&posts, sqlStr, models.PostStatusNormal, (page-1)*size, size)
 	return
 }
 
 // GetPostListByIDs 根据给定的id列表查询帖子数量
 func GetPostListByIDs(ids []string) (postList []*models.Post, err error) {
-	sqlStr := `select post_id,title,content,author_id,community_id,create_time from post where post_id in (?) order by FIND_IN_SET(post_id,?)`
-	query, args, err := sqlx.In(sqlStr, ids, strings.Join(ids, ","))
+	sqlStr := `select post_id,title,content,author_id,community_id,status,create_time,update_time from post where post_id in (?) and status = ? order by FIND_IN_SET(post_id,?)`
+	query, args, err := sqlx.In(sqlStr, ids, models.PostStatusNormal, strings.Join(ids, ","))
 	if err != nil {
 		return
 	}
@@ -41,3 +89,134 @@ func GetPostListByIDs(ids []string) (postList []*models.Post, err error) {
 	err = db.Select(&postList, query, args...) // "..."！！！！！！！！！！
 	return
 }
+
+// UpdatePost 更新帖子的标题、内容及更新时间，草稿也可以编辑
+// 修改后的内容保存为一个新的历史版本，editorID为修改者
+func UpdatePost(p *models.Post, editorID int64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	// 锁住帖子，同一个帖子的修改按顺序生成版本号
+	old := new(models.Post)
+	sqlStr := `select post_id,title,content,author_id,create_time from post where post_id = ? and status != ? for update`
+	if err = tx.Get(old, sqlStr, p.ID, models.PostStatusDeleted); err != nil {
+		if err == sql.ErrNoRows {
+			err = models.ErrorPostNotExist
+		}
+		return err
+	}
+	var rev int64
+	if err = tx.Get(&rev, `select coalesce(max(revision),0) from post_revision where post_id = ?`, p.ID); err != nil {
+		return err
+	}
+	// 有历史版本之前发的帖子，先把修改前的内容保存为第1个版本
+	if rev == 0 {
+		rev = 1
+		if err = insertRevision(tx, old.ID, rev, old.AuthorID, old.Title, old.Content, old.CreateTime); err != nil {
+			return err
+		}
+	}
+	if err = insertRevision(tx, p.ID, rev+1, editorID, p.Title, p.Content, p.UpdateTime); err != nil {
+		return err
+	}
+	sqlStr = `update post set title = ?, content = ?, update_time = ? where post_id = ?`
+	if _, err = tx.Exec(sqlStr, p.Title, p.Content, p.UpdateTime, p.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRevision(tx *sqlx.Tx, pid, rev, editorID int64, title, content string, t time.Time) error {
+	sqlStr := `insert into post_revision(post_id,revision,editor_id,title,content,create_time) values(?,?,?,?,?,?)`
+	_, err := tx.Exec(sqlStr, pid, rev, editorID, title, content, t)
+	return err
+}
+
+// GetPostRevisions 按版本号倒序分页查询帖子的历史版本，不查询内容
+func GetPostRevisions(pid, page, size int64) (revs []*models.PostRevision, err error) {
+	sqlStr := `select post_id,revision,editor_id,title,create_time from post_revision
+	where post_id = ? order by revision desc limit ?,?`
+	revs = make([]*models.PostRevision, 0, size)
+	err = db.Select(&revs, sqlStr, pid, (page-1)*size, size)
+	return
+}
+
+// GetPostRevision 查询帖子的第rev个版本
+func GetPostRevision(pid, rev int64) (r *models.PostRevision, err error) {
+	r = new(models.PostRevision)
+	sqlStr := `select post_id,revision,editor_id,title,content,create_time from post_revision where post_id = ? and revision = ?`
+	err = db.Get(r, sqlStr, pid, rev)
+	if err == sql.ErrNoRows {
+		err = models.ErrorRevisionNotExist
+	}
+	return
+}
+
+// DeletePost 软删除帖子，只修改帖子状态
+func DeletePost(pid int64) (err error) {
+	sqlStr := `update post set status = ? where post_id = ?`
+	_, err = db.Exec(sqlStr, models.PostStatusDeleted, pid)
+	return
+}
+
+// GetDraftByID 根据id查询草稿或定时发布的帖子
+func GetDraftByID(pid int64) (post *models.Post, err error) {
+	post = new(models.Post)
+	sqlStr := `select post_id,title,content,author_id,community_id,status,publish_at,create_time,update_time
+	from post where post_id = ? and status in (?,?)`
+	err = db.Get(post, sqlStr, pid, models.PostStatusDraft, models.PostStatusScheduled)
+	if err == sql.ErrNoRows {
+		err = models.ErrorPostNotExist
+	}
+	return
+}
+
+// GetDraftList 按更新时间倒序分页查询作者的草稿及定时发布的帖子
+func GetDraftList(authorID, page, size int64) (posts []*models.Post, err error) {
+	sqlStr := `select post_id,title,content,author_id,community_id,status,publish_at,create_time,update_time
+	from post where author_id = ? and status in (?,?) order by update_time desc limit ?,?`
+	posts = make([]*models.Post, 0, size)
+	err = db.Select(&posts, sqlStr, authorID, models.PostStatusDraft, models.PostStatusScheduled, (page-1)*size, size)
+	return
+}
+
+// GetDuePosts 查询发布时间已到的定时发布帖子
+func GetDuePosts(now time.Time, size int64) (posts []*models.Post, err error) {
+	sqlStr := `select post_id,title,content,author_id,community_id,status,publish_at,create_time,update_time
+	from post where status = ? and publish_at <= ? order by publish_at limit ?`
+	posts = make([]*models.Post, 0, size)
+	err = db.Select(&posts, sqlStr, models.PostStatusScheduled, now, size)
+	return
+}
+
+// SchedulePost 把草稿或定时发布的帖子设为在publishAt定时发布
+func SchedulePost(pid int64, publishAt time.Time) (err error) {
+	sqlStr := `update post set status = ?, publish_at = ? where post_id = ? and status in (?,?)`
+	_, err = db.Exec(sqlStr, models.PostStatusScheduled, publishAt, pid, models.PostStatusDraft, models.PostStatusScheduled)
+	return
+}
+
+// PublishPost 把状态为from的帖子改为正常，发帖时间改为t
+// 只有状态仍为from时才会修改，多个实例同时发布同一个帖子时只有一个会成功
+func PublishPost(pid int64, from int32, t time.Time) (bool, error) {
+	sqlStr := `update post set status = ?, publish_at = null, create_time = ?, update_time = ? where post_id = ? and status = ?`
+	ret, err := db.Exec(sqlStr, models.PostStatusNormal, t, t, pid, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
+
+// UnpublishPost 把正常的帖子改回发布前p的状态、定时发布时间及发帖时间
+func UnpublishPost(p *models.Post) (err error) {
+	sqlStr := `update post set status = ?, publish_at = ?, create_time = ?, update_time = ? where post_id = ? and status = ?`
+	_, err = db.Exec(sqlStr, p.Status, p.PublishAt, p.CreateTime, p.UpdateTime, p.ID, models.PostStatusNormal)
+	return
+}
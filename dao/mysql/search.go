@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"bell_best/models"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 基于post表的FULLTEXT索引(ngram分词)搜索帖子，索引由MySQL维护，不需要单独更新
+
+// ngramTokenSize MySQL的ngram_token_size，默认为2，短于它的词(如单个汉字)不在FULLTEXT索引中，用MATCH查不到
+const ngramTokenSize = 2
+
+// likeEscaper 转义LIKE的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// booleanQuery 把搜索词转换为BOOLEAN MODE的查询，每个词都必须出现
+// ngram分词下，一个词会按短语匹配其中所有的二元组；短于ngram_token_size的词单独返回，改用LIKE匹配
+func booleanQuery(q string) (query string, short []string) {
+	words := strings.Fields(q)
+	parts := make([]string, 0, len(words))
+	for _, w := range words {
+		// 去掉BOOLEAN MODE的操作符
+		w = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`+-<>()~*"@`, r) {
+				return -1
+			}
+			return r
+		}, w)
+		switch {
+		case w == "":
+		case utf8.RuneCountInString(w) < ngramTokenSize:
+			short = append(short, w)
+		default:
+			parts = append(parts, "+"+w)
+		}
+	}
+	return strings.Join(parts, " "), short
+}
+
+// SearchPosts 搜索帖子，按相关度从高到低分页返回帖子id及命中总数
+// 只有单字的搜索词时没有相关度，按发帖时间倒序返回
+func SearchPosts(p *models.ParamSearch) (ids []string, total int64, err error) {
+	query, short := booleanQuery(p.Q)
+	if query == "" && len(short) == 0 {
+		return nil, 0, nil
+	}
+	where := ` from post where status = ?`
+	args := []interface{}{models.PostStatusNormal}
+	if query != "" {
+		where += ` and match(title,content) against(? in boolean mode)`
+		args = append(args, query)
+	}
+	for _, w := range short {
+		where += ` and (title like ? or content like ?)`
+		like := "%" + likeEscaper.Replace(w) + "%"
+		args = append(args, like, like)
+	}
+	if p.CommunityID != 0 {
+		where += ` and community_id = ?`
+		args = append(args, p.CommunityID)
+	}
+	if p.Since > 0 {
+		where += ` and create_time >= from_unixtime(?)`
+		args = append(args, p.Since)
+	}
+	if p.Until > 0 {
+		where += ` and create_time < from_unixtime(?)`
+		args = append(args, p.Until)
+	}
+	if err = db.Get(&total, `select count(post_id)`+where, args...); err != nil || total == 0 {
+		return
+	}
+	sqlStr := `select post_id` + where + ` order by `
+	if query != "" {
+		sqlStr += `match(title,content) against(? in boolean mode) desc, `
+		args = append(args, query)
+	}
+	sqlStr += `create_time desc, post_id desc limit ?,?`
+	args = append(args, (p.Page-1)*p.Size, p.Size)
+	var pids []int64
+	if err = db.Select(&pids, sqlStr, args...); err != nil {
+		return
+	}
+	ids = make([]string, 0, len(pids))
+	for _, pid := range pids {
+		ids = append(ids, strconv.FormatInt(pid, 10))
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBooleanQuery(t *testing.T) {
+	tests := []struct {
+		q         string
+		wantQuery string
+		wantShort []string
+	}{
+		{"全文搜索", "+全文搜索", nil},
+		{"golang  mysql", "+golang +mysql", nil},
+		{`+go -lang "x" (y)*`, "+go +lang", []string{"x", "y"}},
+		// 单个字不在ngram索引中，改用LIKE匹配
+		{"搜", "", []string{"搜"}},
+		{"搜 全文", "+全文", []string{"搜"}},
+		{"+-*", "", nil},
+	}
+	for _, tt := range tests {
+		query, short := booleanQuery(tt.q)
+		if query != tt.wantQuery || !reflect.DeepEqual(short, tt.wantShort) {
+			t.Errorf("booleanQuery(%q) = %q, %q, want %q, %q", tt.q, query, short, tt.wantQuery, tt.wantShort)
+		}
+	}
+	if got := likeEscaper.Replace(`100%_a\b`); got != `100\%\_a\\b` {
+		t.Errorf("likeEscaper = %q", got)
+	}
+}
@@ -0,0 +1,204 @@
+package mysql
+
+import (
+	"bell_best/models"
+	"time"
+)
+
+// 下面的类型把包级函数包装成logic层需要的存储接口
+
+// PostStore 基于MySQL的帖子存储
+type PostStore struct{}
+
+func (PostStore) CreatePost(p *models.Post) error { return CreatePost(p) }
+
+func (PostStore) GetPostByID(pid int64) (*models.Post, error) { return GetPostByID(pid) }
+
+func (PostStore) GetPostList(page, size int64) ([]*models.Post, error) {
+	return GetPostList(page, size)
+}
+
+func (PostStore) GetPostListByIDs(ids []string) ([]*models.Post, error) {
+	return GetPostListByIDs(ids)
+}
+
+func (PostStore) GetPostTags(pids []int64) (map[int64][]string, error) { return GetPostTags(pids) }
+
+func (PostStore) UpdatePost(p *models.Post, editorID int64) error { return UpdatePost(p, editorID) }
+
+func (PostStore) DeletePost(pid int64) error { return DeletePost(pid) }
+
+func (PostStore) GetDraftByID(pid int64) (*models.Post, error) { return GetDraftByID(pid) }
+
+func (PostStore) GetDraftList(authorID, page, size int64) ([]*models.Post, error) {
+	return GetDraftList(authorID, page, size)
+}
+
+func (PostStore) GetDuePosts(now time.Time, size int64) ([]*models.Post, error) {
+	return GetDuePosts(now, size)
+}
+
+func (PostStore) SchedulePost(pid int64, publishAt time.Time) error {
+	return SchedulePost(pid, publishAt)
+}
+
+func (PostStore) PublishPost(pid int64, from int32, t time.Time) (bool, error) {
+	return PublishPost(pid, from, t)
+}
+
+func (PostStore) UnpublishPost(p *models.Post) error { return UnpublishPost(p) }
+
+func (PostStore) GetPostRevisions(pid, page, size int64) ([]*models.PostRevision, error) {
+	return GetPostRevisions(pid, page, size)
+}
+
+func (PostStore) GetPostRevision(pid, rev int64) (*models.PostRevision, error) {
+	return GetPostRevision(pid, rev)
+}
+
+// FollowStore 基于MySQL的关注关系存储
+type FollowStore struct{}
+
+func (FollowStore) Follow(followerID, followeeID int64) (bool, error) {
+	return Follow(followerID, followeeID)
+}
+
+func (FollowStore) Unfollow(followerID, followeeID int64) (bool, error) {
+	return Unfollow(followerID, followeeID)
+}
+
+func (FollowStore) GetFollowerIDs(uid int64) ([]int64, error) { return GetFollowerIDs(uid) }
+
+func (FollowStore) GetFollowingIDs(uid, minFollowers int64) ([]int64, error) {
+	return GetFollowingIDs(uid, minFollowers)
+}
+
+func (FollowStore) GetFollowCount(uid int64) (*models.FollowCount, error) { return GetFollowCount(uid) }
+
+// UserStore 基于MySQL的用户存储
+type UserStore struct{}
+
+func (UserStore) CheckUserExist(username string) error { return CheckUserExist(username) }
+
+func (UserStore) InsertUser(user *models.User) error { return InsertUser(user) }
+
+func (UserStore) Login(user *models.User) error { return Login(user) }
+
+func (UserStore) GetUserByID(uid int64) (*models.User, error) { return GetUserByID(uid) }
+
+func (UserStore) GetUserByUsername(username string) (*models.User, error) {
+	return GetUserByUsername(username)
+}
+
+func (UserStore) UpdateUserRole(uid int64, role string) error { return UpdateUserRole(uid, role) }
+
+func (UserStore) GetUserProfile(uid int64) (*models.User, error) { return GetUserProfile(uid) }
+
+func (UserStore) UpdateUserProfile(user *models.User) error { return UpdateUserProfile(user) }
+
+func (UserStore) GetUsersByIDs(uids []int64) ([]*models.User, error) { return GetUsersByIDs(uids) }
+
+// CommunityStore 基于MySQL的社区存储
+type CommunityStore struct{}
+
+func (CommunityStore) GetCommunityList() ([]*models.Community, error) {
+	return GetCommunityList()
+}
+
+func (CommunityStore) GetCommunityDetailByID(id int64) (*models.CommunityDetail, error) {
+	return GetCommunityDetailByID(id)
+}
+
+func (CommunityStore) GetCommunitiesByIDs(ids []int64) ([]*models.CommunityDetail, error) {
+	return GetCommunitiesByIDs(ids)
+}
+
+func (CommunityStore) CreateCommunity(c *models.CommunityDetail) error { return CreateCommunity(c) }
+
+func (CommunityStore) UpdateCommunity(c *models.CommunityDetail) error { return UpdateCommunity(c) }
+
+func (CommunityStore) GetCommunityModerators(communityID int64) ([]int64, error) {
+	return GetCommunityModerators(communityID)
+}
+
+func (CommunityStore) IsCommunityModerator(communityID, uid int64) (bool, error) {
+	return IsCommunityModerator(communityID, uid)
+}
+
+func (CommunityStore) AddCommunityModerator(communityID, uid int64) error {
+	return AddCommunityModerator(communityID, uid)
+}
+
+func (CommunityStore) RemoveCommunityModerator(communityID, uid int64) error {
+	return RemoveCommunityModerator(communityID, uid)
+}
+
+func (CommunityStore) SubscribeCommunity(communityID, uid int64) (bool, error) {
+	return SubscribeCommunity(communityID, uid)
+}
+
+func (CommunityStore) UnsubscribeCommunity(communityID, uid int64) (bool, error) {
+	return UnsubscribeCommunity(communityID, uid)
+}
+
+func (CommunityStore) GetSubscribedCommunityIDs(uid int64) ([]int64, error) {
+	return GetSubscribedCommunityIDs(uid)
+}
+
+// CommentStore 基于MySQL的评论存储
+type CommentStore struct{}
+
+func (CommentStore) CreateComment(c *models.Comment) error { return CreateComment(c) }
+
+func (CommentStore) GetCommentByID(cid int64) (*models.Comment, error) { return GetCommentByID(cid) }
+
+func (CommentStore) GetCommentList(postID, cursor, size int64) ([]*models.Comment, error) {
+	return GetCommentList(postID, cursor, size)
+}
+
+func (CommentStore) GetRootCommentList(postID, cursor, size int64) ([]*models.Comment, error) {
+	return GetRootCommentList(postID, cursor, size)
+}
+
+func (CommentStore) GetCommentsByRootIDs(rootIDs []int64) ([]*models.Comment, error) {
+	return GetCommentsByRootIDs(rootIDs)
+}
+
+func (CommentStore) GetCommentNumByPostIDs(postIDs []int64) (map[int64]int64, error) {
+	return GetCommentNumByPostIDs(postIDs)
+}
+
+// SearchStore 基于MySQL全文索引的帖子搜索，索引随post表自动更新
+type SearchStore struct{}
+
+func (SearchStore) IndexPost(p *models.Post) error { return nil }
+
+func (SearchStore) RemovePost(pid int64) error { return nil }
+
+func (SearchStore) SearchPosts(p *models.ParamSearch) ([]string, int64, error) {
+	return SearchPosts(p)
+}
+
+// VoteArchiveStore 基于MySQL的帖子票数归档存储
+type VoteArchiveStore struct{}
+
+func (VoteArchiveStore) SavePostVotes(votes []*models.PostVotes) error { return SavePostVotes(votes) }
+
+func (VoteArchiveStore) GetPostVotesByIDs(postIDs []int64) (map[int64]*models.PostVotes, error) {
+	return GetPostVotesByIDs(postIDs)
+}
+
+// UploadStore 基于MySQL的上传图片记录存储
+type UploadStore struct{}
+
+func (UploadStore) CreateUpload(u *models.Upload) error { return CreateUpload(u) }
+
+func (UploadStore) SetPostUploads(userID, postID int64, ids []int64) error {
+	return SetPostUploads(userID, postID, ids)
+}
+
+func (UploadStore) GetOrphanUploads(before time.Time, size int64) ([]*models.Upload, error) {
+	return GetOrphanUploads(before, size)
+}
+
+func (UploadStore) DeleteOrphanUpload(id int64) (bool, error) { return DeleteOrphanUpload(id) }
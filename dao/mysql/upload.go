@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"bell_best/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateUpload 保存上传图片的记录
+func CreateUpload(u *models.Upload) (err error) {
+	sqlStr := `insert into upload(upload_id,user_id,blob_key,thumb_key,content_type,size,width,height) values(?,?,?,?,?,?,?,?)`
+	_, err = db.Exec(sqlStr, u.ID, u.UserID, u.Key, u.ThumbKey, u.ContentType, u.Size, u.Width, u.Height)
+	return
+}
+
+// SetPostUploads 把作者上传的图片关联到帖子，已被其他帖子引用的图片不变；帖子不再引用的图片取消关联
+func SetPostUploads(userID, postID int64, ids []int64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if len(ids) == 0 {
+		if _, err = tx.Exec(`update upload set post_id = 0 where post_id = ?`, postID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	query, args, err := sqlx.In(`update upload set post_id = 0 where post_id = ? and upload_id not in (?)`, postID, ids)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(tx.Rebind(query), args...); err != nil {
+		return err
+	}
+	query, args, err = sqlx.In(`update upload set post_id = ? where user_id = ? and post_id = 0 and upload_id in (?)`, postID, userID, ids)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(tx.Rebind(query), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOrphanUploads 查询没有帖子引用、且在before之前就没有变化的图片
+func GetOrphanUploads(before time.Time, size int64) (data []*models.Upload, err error) {
+	sqlStr := `select upload_id,user_id,post_id,blob_key,thumb_key,content_type,size,width,height,create_time,update_time
+	from upload where post_id = 0 and update_time < ? order by update_time limit ?`
+	err = db.Select(&data, sqlStr, before, size)
+	return
+}
+
+// DeleteOrphanUpload 删除仍然没有帖子引用的图片记录，清理期间被帖子引用的图片不会被删除
+func DeleteOrphanUpload(id int64) (bool, error) {
+	ret, err := db.Exec(`delete from upload where upload_id = ? and post_id = 0`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
@@ -2,15 +2,21 @@ package mysql
 
 import (
 	"bell_best/models"
+	"bell_best/pkg/password"
 	"crypto/md5"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/hex"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 // 吧每一步数据库操作封装成函数
 // 待logic层根据业务需求调用
 
 // !!!!!!!
+// 旧版本用md5加盐保存密码，现在只用来校验还没升级的老密码
 const secret = "liwenzhou.com"
 
 // CheckUserExist 检查指定用户名的用户是否存在
@@ -23,7 +29,7 @@ func CheckUserExist(username string) (err error) {
 		return err
 	}
 	if count > 0 {
-		return ErrorUserExist
+		return models.ErrorUserExist
 	}
 	return
 }
@@ -31,16 +37,19 @@ func CheckUserExist(username string) (err error) {
 // InsertUser 想在数据库中插入一条新的用户记录
 func InsertUser(user *models.User) (err error) {
 	// 对密码进行加密
-	user.Password = encryptPassword(user.Password)
+	user.Password, err = password.Hash(user.Password)
+	if err != nil {
+		return err
+	}
 	// 执行SQL语句入库
-	sqlStr := `insert into user (user_id,username,password) values(?,?,?)`
+	sqlStr := `insert into user (user_id,username,password,role) values(?,?,?,?)`
 	// Exec!!!!!!!!!!!!!!
-	_, err = db.Exec(sqlStr, user.UserID, user.Username, user.Password)
+	_, err = db.Exec(sqlStr, user.UserID, user.Username, user.Password, user.Role)
 	return
 }
 
 // !!!!!!!!!!!!!!!!!!!!!!!!!!
-// encryptPassword 对密码进行加密
+// encryptPassword 旧版本的密码加密方式
 func encryptPassword(oPassword string) string {
 	h := md5.New()
 	// !!!!!!!!!!!
@@ -51,26 +60,122 @@ func encryptPassword(oPassword string) string {
 // Login 验证-返回登录成功或失败，表参是用户输入数据，与数据库保存数据对比
 func Login(user *models.User) (err error) {
 	oPassword := user.Password
-	sqlStr := `select user_id,username,password from user where username = ?`
+	sqlStr := `select user_id,username,password,role from user where username = ?`
 	err = db.Get(user, sqlStr, user.Username)
 	if err == sql.ErrNoRows {
-		return ErrorUserNotExist
+		return models.ErrorUserNotExist
 	}
 	if err != nil {
 		return err
 	}
 	// 判断密码是否正确
-	password := encryptPassword(oPassword)
-	if password != user.Password {
-		return ErrorInvalidPassword
+	ok, rehash, err := checkPassword(oPassword, user.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.ErrorInvalidPassword
+	}
+	// 老密码或参数过时的密码在登录成功后用当前算法重新保存，失败不影响本次登录
+	if rehash {
+		if err := updatePassword(user.UserID, oPassword); err != nil {
+			zap.L().Error("rehash password failed", zap.Int64("user_id", user.UserID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// checkPassword 校验密码，兼容旧版本md5加密的密码，旧密码校验通过后总是需要重新哈希
+func checkPassword(oPassword, hashed string) (ok, rehash bool, err error) {
+	ok, rehash, err = password.Verify(oPassword, hashed)
+	if errors.Is(err, password.ErrUnknownFormat) {
+		ok = subtle.ConstantTimeCompare([]byte(encryptPassword(oPassword)), []byte(hashed)) == 1
+		return ok, ok, nil
 	}
 	return
 }
 
+// updatePassword 用当前算法重新哈希并保存用户密码
+func updatePassword(uid int64, oPassword string) error {
+	hashed, err := password.Hash(oPassword)
+	if err != nil {
+		return err
+	}
+	sqlStr := `update user set password = ? where user_id = ?`
+	_, err = db.Exec(sqlStr, hashed, uid)
+	return err
+}
+
 // GetUserByID 根据id获取用户信息
 func GetUserByID(uid int64) (user *models.User, err error) {
 	user = new(models.User)
-	sqlStr := `select user_id,username from user where user_id = ?`
+	sqlStr := `select user_id,username,role from user where user_id = ?`
 	err = db.Get(user, sqlStr, uid)
 	return
 }
+
+// GetUserByUsername 根据用户名获取用户信息
+func GetUserByUsername(username string) (user *models.User, err error) {
+	user = new(models.User)
+	sqlStr := `select user_id,username,role from user where username = ?`
+	err = db.Get(user, sqlStr, username)
+	if err == sql.ErrNoRows {
+		err = models.ErrorUserNotExist
+	}
+	return
+}
+
+// UpdateUserRole 修改用户的角色，用户不存在时返回ErrorUserNotExist
+func UpdateUserRole(uid int64, role string) (err error) {
+	sqlStr := `update user set role = ? where user_id = ?`
+	ret, err := db.Exec(sqlStr, role, uid)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+	// 角色没有变化时影响的行数也是0，再确认用户是否存在
+	var count int64
+	if err = db.Get(&count, `select count(user_id) from user where user_id = ?`, uid); err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrorUserNotExist
+	}
+	return nil
+}
+
+// GetUserProfile 根据id获取用户的资料
+func GetUserProfile(uid int64) (user *models.User, err error) {
+	user = new(models.User)
+	sqlStr := `select user_id,username,display_name,bio,avatar_url,create_time from user where user_id = ?`
+	err = db.Get(user, sqlStr, uid)
+	if err == sql.ErrNoRows {
+		err = models.ErrorUserNotExist
+	}
+	return
+}
+
+// UpdateUserProfile 更新用户的资料
+func UpdateUserProfile(user *models.User) (err error) {
+	sqlStr := `update user set display_name = ?,bio = ?,avatar_url = ? where user_id = ?`
+	_, err = db.Exec(sqlStr, user.DisplayName, user.Bio, user.AvatarURL, user.UserID)
+	return
+}
+
+// GetUsersByIDs 根据id列表批量获取用户信息
+func GetUsersByIDs(uids []int64) (users []*models.User, err error) {
+	if len(uids) == 0 {
+		return
+	}
+	sqlStr := `select user_id,username from user where user_id in (?)`
+	query, args, err := sqlx.In(sqlStr, uids)
+	if err != nil {
+		return
+	}
+	query = db.Rebind(query)
+	err = db.Select(&users, query, args...)
+	return
+}
@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"bell_best/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// SavePostVotes 保存投票期结束的帖子的票数，重复归档时覆盖之前的结果
+func SavePostVotes(votes []*models.PostVotes) (err error) {
+	if len(votes) == 0 {
+		return
+	}
+	sqlStr := `insert into post_vote(post_id,up_votes,down_votes) values(:post_id,:up_votes,:down_votes)
+	on duplicate key update up_votes = values(up_votes),down_votes = values(down_votes)`
+	_, err = db.NamedExec(sqlStr, votes)
+	return
+}
+
+// GetPostVotesByIDs 查询已归档的帖子票数，没有归档的帖子不在结果中
+func GetPostVotesByIDs(postIDs []int64) (data map[int64]*models.PostVotes, err error) {
+	data = make(map[int64]*models.PostVotes, len(postIDs))
+	if len(postIDs) == 0 {
+		return
+	}
+	sqlStr := `select post_id,up_votes,down_votes from post_vote where post_id in (?)`
+	query, args, err := sqlx.In(sqlStr, postIDs)
+	if err != nil {
+		return
+	}
+	query = db.Rebind(query)
+	var rows []*models.PostVotes
+	if err = db.Select(&rows, query, args...); err != nil {
+		return
+	}
+	for _, row := range rows {
+		data[row.PostID] = row
+	}
+	return
+}
@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"bell_best/models"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+// 帖子投票期结束后票数不再变化，把赞成票及反对票数归档到mysql，然后删除 KeyPostVotedPF
+// KeyVoteArchivedUntil 记录已归档帖子的最晚发帖时间，下一次从这个时间之后继续
+
+// GetVoteExpiredPostIDs 按发帖时间从早到晚查询投票期已结束、还没有归档的帖子，最多返回limit个
+// until是这一批帖子中最晚的发帖时间，归档完成后传给ArchivePostVotes
+func GetVoteExpiredPostIDs(limit int64) (ids []string, until float64, err error) {
+	min := "-inf"
+	v, err := client.Get(ctx, GetRedisKey(KeyVoteArchivedUntil)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+	if err == nil {
+		min = "(" + v
+	}
+	max := strconv.FormatInt(time.Now().Unix()-oneWeekInSeconds, 10)
+	zs, err := client.ZRangeByScoreWithScores(ctx, GetRedisKey(KeyPostTime), &redis.ZRangeBy{
+		Min:   min,
+		Max:   max,
+		Count: limit,
+	}).Result()
+	if err != nil || len(zs) == 0 {
+		return nil, 0, err
+	}
+	until = zs[len(zs)-1].Score
+	if int64(len(zs)) == limit {
+		// 同一秒发的帖子要在同一批归档，否则下一次会从这一秒之后开始而漏掉剩下的
+		n := len(zs)
+		for n > 0 && zs[n-1].Score == until {
+			n--
+		}
+		if n > 0 {
+			zs, until = zs[:n], zs[n-1].Score
+		} else {
+			// 这一批都是同一秒发的帖子，一次取完
+			s := strconv.FormatFloat(until, 'f', -1, 64)
+			zs, err = client.ZRangeByScoreWithScores(ctx, GetRedisKey(KeyPostTime), &redis.ZRangeBy{Min: s, Max: s}).Result()
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	ids = make([]string, 0, len(zs))
+	for _, z := range zs {
+		ids = append(ids, z.Member.(string))
+	}
+	return
+}
+
+// GetPostVotes 查询帖子的赞成票及反对票数
+func GetPostVotes(ids []string) (data []*models.PostVotes, err error) {
+	pipeline := client.Pipeline()
+	upCmds := make([]*redis.IntCmd, 0, len(ids))
+	downCmds := make([]*redis.IntCmd, 0, len(ids))
+	for _, id := range ids {
+		key := GetRedisKey(KeyPostVotedPF + id)
+		upCmds = append(upCmds, pipeline.ZCount(ctx, key, "1", "1"))
+		downCmds = append(downCmds, pipeline.ZCount(ctx, key, "-1", "-1"))
+	}
+	if _, err = pipeline.Exec(ctx); err != nil {
+		return nil, err
+	}
+	data = make([]*models.PostVotes, 0, len(ids))
+	for i, id := range ids {
+		pid, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, &models.PostVotes{PostID: pid, UpVotes: upCmds[i].Val(), DownVotes: downCmds[i].Val()})
+	}
+	return
+}
+
+// ArchivePostVotes 票数保存到mysql之后删除帖子的投票记录，并记录归档到的发帖时间
+func ArchivePostVotes(ids []string, until float64) error {
+	pipeline := client.TxPipeline()
+	for _, id := range ids {
+		pipeline.Del(ctx, GetRedisKey(KeyPostVotedPF+id))
+	}
+	pipeline.Set(ctx, GetRedisKey(KeyVoteArchivedUntil), strconv.FormatFloat(until, 'f', -1, 64), 0)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
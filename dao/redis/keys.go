@@ -7,9 +7,31 @@ package redis
 const (
 	KeyPrefix      = "bluebell:"
 	KeyPostTime    = "post:time"   // zset;帖子及发帖时间
-	KeyPostScore   = "post:score"  // zset;帖子及投票的分数
+	KeyPostScore   = "post:score"  // zset;帖子及默认排序算法计算的分数
 	KeyPostVotedPF = "post:voted:" // zset;记录用户及投票类型;参数是post id
 	KeyCommunityPF = "community:"  // set;保存每个分区下帖子的id
+	KeyAuthorPF    = "author:"     // set;保存每个作者的帖子id;参数是user id
+	KeyTagPF       = "tag:"        // set;保存每个标签下帖子的id;参数是标签
+
+	KeyCommunityScorePF = "community:score:" // zset;社区的帖子及按社区的排序算法计算的分数;参数是community id
+
+	KeyTimelinePF       = "timeline:"        // zset;关注的作者推送的帖子及发帖时间，只保留最新的一部分;参数是user id
+	KeyTimelineMergedPF = "timeline:merged:" // zset;时间线与粉丝多的作者的帖子合并后的缓存;参数是user id
+
+	KeyTagUsagePF    = "tag:usage:"    // zset;每小时新帖子使用的标签及次数;参数是unix时间/3600
+	KeyTagTrendingPF = "tag:trending:" // zset;热门标签的缓存;参数是统计的小时数
+
+	KeyVoteArchivedUntil = "post:archived" // string;发帖时间不晚于该值的帖子票数已归档到mysql
+
+	KeyRefreshTokenPF = "token:refresh:" // hash;refresh token对应的会话;参数是refresh token的sha256
+	KeyUserTokensPF   = "token:user:"    // set;用户当前所有的refresh token;参数是user id
+	KeyTokenDeniedPF  = "token:denied:"  // string;已注销的access token;参数是jti
+	KeyUserAccessPF   = "token:access:"  // zset;用户签发过且未过期的access token,分数是过期时间;参数是user id
+
+	KeyLockPF      = "lock:"       // string;多实例部署时后台任务使用的锁;参数是任务名
+	KeyRateLimitPF = "ratelimit:"  // hash;限流的令牌桶;参数是路由分组及用户id或ip
+	KeyLoginFailPF = "login:fail:" // string;窗口期内登录失败的次数;参数是user:<用户名>或ip:<ip>
+	KeyLoginLockPF = "login:lock:" // string;暂时禁止登录;参数同上
 )
 
 // 给redis key加上前缀
@@ -0,0 +1,14 @@
+package redis
+
+import "time"
+
+// TryLock 尝试获取名为name的锁，锁的值为持有者的token，锁在ttl后自动释放
+// 用于多实例部署时保证后台任务同一时间只在一个实例上执行
+func TryLock(name, token string, ttl time.Duration) (bool, error) {
+	return client.SetNX(ctx, GetRedisKey(KeyLockPF+name), token, ttl).Result()
+}
+
+// Unlock 释放锁，锁已过期并被其他实例获取时不做处理
+func Unlock(name, token string) error {
+	return unlockScript.Run(ctx, client, []string{GetRedisKey(KeyLockPF + name)}, token).Err()
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// GetLoginLocks 查询每个key还要被禁止登录多久，没有被禁止时为0
+func GetLoginLocks(keys []string) ([]time.Duration, error) {
+	pipeline := client.Pipeline()
+	cmds := make([]*redis.DurationCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipeline.PTTL(ctx, GetRedisKey(KeyLoginLockPF+key)))
+	}
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return nil, err
+	}
+	data := make([]time.Duration, 0, len(keys))
+	for _, cmd := range cmds {
+		// key不存在或没有过期时间时PTTL返回负数
+		d := cmd.Val()
+		if d < 0 {
+			d = 0
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
+
+// IncrLoginFailures 登录失败次数加1并返回窗口期内的失败次数，最后一次失败window之后清零
+func IncrLoginFailures(key string, window time.Duration) (int64, error) {
+	pipeline := client.TxPipeline()
+	incr := pipeline.Incr(ctx, GetRedisKey(KeyLoginFailPF+key))
+	pipeline.PExpire(ctx, GetRedisKey(KeyLoginFailPF+key), window)
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// LockLogin 在d时间内禁止登录
+func LockLogin(key string, d time.Duration) error {
+	return client.Set(ctx, GetRedisKey(KeyLoginLockPF+key), 1, d).Err()
+}
+
+// ResetLoginFailures 登录成功后清除失败次数及禁止登录
+func ResetLoginFailures(key string) error {
+	return client.Del(ctx, GetRedisKey(KeyLoginFailPF+key), GetRedisKey(KeyLoginLockPF+key)).Err()
+}
@@ -3,10 +3,16 @@ package redis
 import (
 	"bell_best/models"
 	"github.com/go-redis/redis/v8"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// 标签每小时使用次数的保存时间，热门标签最多统计这么长时间
+const tagUsageExpire = 7*24*time.Hour + time.Hour
+
 func getIDsFormKey(key string, page, size int64) ([]string, error) {
 	start := (page - 1) * size
 	end := start + size - 1
@@ -14,6 +20,54 @@ func getIDsFormKey(key string, page, size int64) ([]string, error) {
 	return client.ZRevRange(ctx, key, start, end).Result()
 }
 
+// getIDsFormKeyByCursor 按分数从大到小查询游标之后的size个id，并返回下一页的游标
+// 分数相同的member按字典序倒序排列，和ZREVRANGE的顺序一致
+func getIDsFormKeyByCursor(key string, cursor *models.PostCursor, size int64) (ids []string, next *models.PostCursor, err error) {
+	var zs []redis.Z
+	if cursor == nil {
+		zs, err = client.ZRevRangeWithScores(ctx, key, 0, size-1).Result()
+	} else {
+		zs, err = zRangeAfterCursor(key, cursor, size)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ids = make([]string, 0, len(zs))
+	for _, z := range zs {
+		ids = append(ids, z.Member.(string))
+	}
+	if int64(len(zs)) == size {
+		last := zs[len(zs)-1]
+		next = &models.PostCursor{Score: last.Score, ID: last.Member.(string)}
+	}
+	return
+}
+
+// zRangeAfterCursor 查询游标之后的size个member及分数
+func zRangeAfterCursor(key string, cursor *models.PostCursor, size int64) ([]redis.Z, error) {
+	score := strconv.FormatFloat(cursor.Score, 'f', -1, 64)
+	res, err := cursorRangeScript.Run(ctx, client, []string{key}, score, cursor.ID, size).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	zs := make([]redis.Z, 0, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		s, err := strconv.ParseFloat(res[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		zs = append(zs, redis.Z{Score: s, Member: res[i]})
+	}
+	return zs, nil
+}
+
+func getOrderKey(order string) string {
+	if order == models.OrderScore {
+		return GetRedisKey(KeyPostScore)
+	}
+	return GetRedisKey(KeyPostTime)
+}
+
 func GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 	// 从redis获取id
 	// 1. 根据用户请求中携带的order参数确定要查询的redis key
@@ -24,58 +78,231 @@ func GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 	return getIDsFormKey(key, p.Page, p.Size)
 }
 
-// GetPostVoteData 根据ids查询每篇帖子的投赞成票的数据
-func GetPostVoteData(ids []string) (data []int64, err error) {
-	//data = make([]int64, 0,len(ids))
-	//for _, id := range ids {
-	//	key := GetRedisKey(KeyPostVotedPF + id)
-	//	v1 := client.ZCount(ctx, key, "1", "1").Val()
-	//	data = append(data, v1)
-
+// GetPostVoteData 根据ids查询每篇帖子的赞成票、反对票及userID的投票，userID为空时不查询
+func GetPostVoteData(ids []string, userID string) (data []*models.PostVoteData, err error) {
 	// 使用pipeline一次发送多条命令减少RTT
 	pipeline := client.Pipeline()
+	upCmds := make([]*redis.IntCmd, 0, len(ids))
+	downCmds := make([]*redis.IntCmd, 0, len(ids))
+	myCmds := make([]*redis.FloatCmd, 0, len(ids))
 	for _, id := range ids {
 		key := GetRedisKey(KeyPostVotedPF + id)
-		pipeline.ZCount(ctx, key, "1", "1")
+		upCmds = append(upCmds, pipeline.ZCount(ctx, key, "1", "1"))
+		downCmds = append(downCmds, pipeline.ZCount(ctx, key, "-1", "-1"))
+		if userID != "" {
+			myCmds = append(myCmds, pipeline.ZScore(ctx, key, userID))
+		}
 	}
-	cmders, err := pipeline.Exec(ctx)
-	if err != nil {
+	// 用户没有投过票时ZSCORE返回redis.Nil
+	if _, err = pipeline.Exec(ctx); err != nil && err != redis.Nil {
 		return nil, err
 	}
-	data = make([]int64, 0, len(ids))
-	for _, cmder := range cmders {
-		v := cmder.(*redis.IntCmd).Val()
+	data = make([]*models.PostVoteData, 0, len(ids))
+	for i := range ids {
+		v := &models.PostVoteData{UpVotes: upCmds[i].Val(), DownVotes: downCmds[i].Val()}
+		if userID != "" {
+			v.MyVote = int8(myCmds[i].Val())
+		}
 		data = append(data, v)
 	}
-	return
+	return data, nil
+}
+
+// GetPostIDsByCursor 按游标查询ids
+func GetPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	return getIDsFormKeyByCursor(getOrderKey(p.Order), cursor, p.Size)
 }
 
 // GetCommunityPostIDsInOrder 按社区查询ids
 func GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
-	orderKey := GetRedisKey(KeyPostTime)
-	if p.Order == models.OrderScore {
-		orderKey = GetRedisKey(KeyPostScore)
+	key, err := communityOrderKey(p)
+	if err != nil {
+		return nil, err
 	}
-	// 使用zinterstore 把分区的帖子set与帖子分数的zset 生成一个新的zset
-	// 针对新的zset按之前的逻辑取数据
+	// 存在的话就直接根据key查询ids
+	return getIDsFormKey(key, p.Page, p.Size)
+}
+
+// GetCommunityPostIDsByCursor 按社区及游标查询ids
+func GetCommunityPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	key, err := communityOrderKey(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getIDsFormKeyByCursor(key, cursor, p.Size)
+}
+
+// GetCommunitiesPostIDsInOrder 按多个社区查询ids
+func GetCommunitiesPostIDsInOrder(p *models.ParamPostList, communityIDs []int64) ([]string, error) {
+	key, err := communitiesOrderKey(p, communityIDs)
+	if err != nil {
+		return nil, err
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
+
+// GetCommunitiesPostIDsByCursor 按多个社区及游标查询ids
+func GetCommunitiesPostIDsByCursor(p *models.ParamPostList, communityIDs []int64, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	key, err := communitiesOrderKey(p, communityIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getIDsFormKeyByCursor(key, cursor, p.Size)
+}
+
+// GetAuthorPostIDsByCursor 按游标分页查询某个作者的帖子id
+func GetAuthorPostIDsByCursor(authorID int64, p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	orderKey := getOrderKey(p.Order)
+	aKey := KeyAuthorPF + strconv.FormatInt(authorID, 10)
+	key, err := setOrderKey(orderKey+":"+aKey, orderKey, GetRedisKey(aKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	return getIDsFormKeyByCursor(key, cursor, p.Size)
+}
 
+// communityOrderKey 返回社区帖子按时间或分数排序的zset，按分数排序时直接使用社区的分数
+func communityOrderKey(p *models.ParamPostList) (string, error) {
+	if p.Order == models.OrderScore {
+		return communityScoreKey(p.CommunityID), nil
+	}
+	orderKey := getOrderKey(p.Order)
 	// 社区的key
 	cKey := GetRedisKey(KeyCommunityPF + strconv.Itoa(int(p.CommunityID)))
+	return setOrderKey(orderKey+strconv.Itoa(int(p.CommunityID)), orderKey, cKey)
+}
+
+// communitiesOrderKey 返回多个社区的帖子合并后按时间或分数排序的缓存zset，只有一个社区时使用该社区的排序
+// 各社区的排序算法可能不同，合并后按分数排序时使用默认算法计算的post:score
+// 缓存key包含排好序的社区id，订阅相同社区的用户共用同一个缓存
+func communitiesOrderKey(p *models.ParamPostList, communityIDs []int64) (string, error) {
+	if len(communityIDs) == 1 {
+		return communityOrderKey(&models.ParamPostList{CommunityID: communityIDs[0], Order: p.Order})
+	}
+	ids := append([]int64(nil), communityIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	cids := make([]string, 0, len(ids))
+	setKeys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cid := strconv.FormatInt(id, 10)
+		cids = append(cids, cid)
+		setKeys = append(setKeys, GetRedisKey(KeyCommunityPF+cid))
+	}
+	orderKey := getOrderKey(p.Order)
+	return unionOrderKey(orderKey+":"+KeyCommunityPF+strings.Join(cids, ","), orderKey, setKeys...)
+}
+
+// GetTagPostIDsInOrder 按标签查询ids，可同时按社区过滤
+func GetTagPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	key, err := tagOrderKey(p)
+	if err != nil {
+		return nil, err
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
+
+// GetTagPostIDsByCursor 按标签及游标查询ids，可同时按社区过滤
+func GetTagPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	key, err := tagOrderKey(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getIDsFormKeyByCursor(key, cursor, p.Size)
+}
+
+// tagOrderKey 返回标签下的帖子按时间或分数排序的缓存zset，社区id不为0时再与社区的帖子求交集
+func tagOrderKey(p *models.ParamPostList) (string, error) {
+	orderKey := getOrderKey(p.Order)
+	cacheKey := tagCacheKey(orderKey, p.CommunityID, p.Tag)
+	sets := []string{GetRedisKey(KeyTagPF + p.Tag)}
+	if p.CommunityID == 0 {
+		return setOrderKey(cacheKey, orderKey, sets...)
+	}
+	// 按分数排序时社区的分数zset本身就只包含社区的帖子
+	if p.Order == models.OrderScore {
+		return setOrderKey(cacheKey, communityScoreKey(p.CommunityID), sets...)
+	}
+	sets = append(sets, GetRedisKey(KeyCommunityPF+strconv.Itoa(int(p.CommunityID))))
+	return setOrderKey(cacheKey, orderKey, sets...)
+}
+
+// tagCacheKey 标签帖子列表的缓存key
+func tagCacheKey(orderKey string, communityID int64, tag string) string {
+	if communityID != 0 {
+		return orderKey + strconv.Itoa(int(communityID)) + ":" + KeyTagPF + tag
+	}
+	return orderKey + ":" + KeyTagPF + tag
+}
+
+// setOrderKey 把帖子id的set(多个set时取交集)按时间或分数排序，结果缓存在key中
+func setOrderKey(key, orderKey string, setKeys ...string) (string, error) {
+	// 使用zinterstore 把帖子id的set与帖子分数的zset 生成一个新的zset
+	// 针对新的zset按之前的逻辑取数据
 	// 利用缓存key减少zinterstore执行的次数
-	key := orderKey + strconv.Itoa(int(p.CommunityID))
 	if client.Exists(ctx, key).Val() < 1 {
 		// 不存在，需要计算
 		pipeline := client.Pipeline()
+		// 社区set中member的分数是1，权重设为0，结果只保留时间或分数
+		// 不能用MAX聚合，部分排序算法的分数小于1
+		weights := make([]float64, len(setKeys), len(setKeys)+1)
 		pipeline.ZInterStore(ctx, key, &redis.ZStore{
-			Aggregate: "MAX",
-			Keys:      []string{cKey, orderKey},
+			Keys:    append(setKeys, orderKey),
+			Weights: append(weights, 1),
 		}) // zinterstore 计算
 		pipeline.Expire(ctx, key, 60*time.Second) // 设置超时时间
 		_, err := pipeline.Exec(ctx)
 		if err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// unionOrderKey 把多个帖子id的set取并集后按时间或分数排序，结果缓存在key中
+func unionOrderKey(key, orderKey string, setKeys ...string) (string, error) {
+	if client.Exists(ctx, key).Val() < 1 {
+		// 先ZUNIONSTORE合并各个set，再与时间或分数的zset求交集，结果只保留时间或分数
+		// 放在事务中执行，其他请求不会读到只合并了一半的结果
+		pipeline := client.TxPipeline()
+		pipeline.ZUnionStore(ctx, key, &redis.ZStore{Keys: setKeys})
+		pipeline.ZInterStore(ctx, key, &redis.ZStore{
+			Keys:    []string{key, orderKey},
+			Weights: []float64{0, 1},
+		})
+		pipeline.Expire(ctx, key, 60*time.Second)
+		if _, err := pipeline.Exec(ctx); err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// GetTrendingTags 按最近window内新帖子使用的次数返回前size个标签
+// 使用次数按小时分桶保存，ZUNIONSTORE合并最近的桶，结果缓存一分钟
+func GetTrendingTags(window time.Duration, size int64) ([]*models.TagCount, error) {
+	hours := int64(math.Ceil(window.Hours()))
+	hours = min(max(hours, 1), int64(tagUsageExpire/time.Hour)-1)
+	key := GetRedisKey(KeyTagTrendingPF + strconv.FormatInt(hours, 10))
+	if client.Exists(ctx, key).Val() < 1 {
+		now := time.Now().Unix() / 3600
+		keys := make([]string, 0, hours)
+		for h := now - hours + 1; h <= now; h++ {
+			keys = append(keys, GetRedisKey(KeyTagUsagePF+strconv.FormatInt(h, 10)))
+		}
+		pipeline := client.Pipeline()
+		pipeline.ZUnionStore(ctx, key, &redis.ZStore{Keys: keys})
+		pipeline.Expire(ctx, key, 60*time.Second)
+		if _, err := pipeline.Exec(ctx); err != nil {
 			return nil, err
 		}
 	}
-	// 存在的话就直接根据key查询ids
-	return getIDsFormKey(key, p.Page, p.Size)
+	zs, err := client.ZRevRangeWithScores(ctx, key, 0, size-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]*models.TagCount, 0, len(zs))
+	for _, z := range zs {
+		data = append(data, &models.TagCount{Tag: z.Member.(string), Count: int64(z.Score)})
+	}
+	return data, nil
 }
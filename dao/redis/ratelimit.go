@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"bell_best/models"
+	"time"
+)
+
+// TakeToken 从key对应的令牌桶中取一个令牌，令牌桶每秒补充rate个令牌，最多保存burst个
+func TakeToken(key string, rate float64, burst int64) (*models.RateLimitResult, error) {
+	res, err := tokenBucketScript.Run(ctx, client, []string{GetRedisKey(KeyRateLimitPF + key)}, rate, burst).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	return &models.RateLimitResult{
+		Allowed:    res[0] == 1,
+		Limit:      burst,
+		Remaining:  res[1],
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		Reset:      time.Duration(res[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package redis
+
+import "github.com/go-redis/redis/v8"
+
+// 投票、发帖及限流需要同时读写多个key，放在lua脚本中在redis服务端原子执行
+// 脚本通过返回值区分结果，对应的错误见vote.go
+
+// 投票脚本的返回值
+const (
+	voteOK          = 0
+	voteExpired     = 1
+	voteRepeated    = 2
+	votePostMissing = 3
+)
+
+// createPostScript 记录帖子的发帖时间、初始分数、所属社区、作者及标签，并统计标签的使用次数
+// KEYS[1] post:time  KEYS[2] post:score  KEYS[3] community:score:<community_id>  KEYS[4] community:<community_id>
+// KEYS[5] author:<author_id>  KEYS[6] tag:usage:<小时>  KEYS[7..] tag:<标签>
+// ARGV[1] post_id  ARGV[2] 发帖时间  ARGV[3] 默认算法的初始分数  ARGV[4] 社区算法的初始分数
+// ARGV[5] 使用次数的过期秒数  ARGV[6..] 标签，与KEYS[7..]一一对应
+var createPostScript = redis.NewScript(`
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+redis.call('ZADD', KEYS[3], ARGV[4], ARGV[1])
+redis.call('SADD', KEYS[4], ARGV[1])
+redis.call('SADD', KEYS[5], ARGV[1])
+for i = 7, #KEYS do
+	redis.call('SADD', KEYS[i], ARGV[1])
+	redis.call('ZINCRBY', KEYS[6], 1, ARGV[i - 1])
+end
+if #KEYS > 6 then
+	redis.call('EXPIRE', KEYS[6], ARGV[5])
+end
+return 0
+`)
+
+// voteScript 检查投票期及重复投票，记录投票并按最新的票数重新计算默认算法及社区算法的分数
+// KEYS[1] post:time  KEYS[2] post:score  KEYS[3] community:score:<community_id>  KEYS[4] post:voted:<post_id>
+// ARGV[1] post_id  ARGV[2] user_id  ARGV[3] 投票(1/0/-1)  ARGV[4] 当前时间  ARGV[5] 投票期的秒数
+// ARGV[6] 默认算法名称  ARGV[7] 默认算法参数  ARGV[8] 社区算法名称  ARGV[9] 社区算法参数
+// 排序算法的公式与pkg/rank保持一致
+var voteScript = redis.NewScript(`
+local function valid(alg)
+	return alg == 'simple' or alg == 'hot' or alg == 'gravity' or alg == 'wilson'
+end
+if not valid(ARGV[6]) then
+	return redis.error_reply('unknown ranker ' .. ARGV[6])
+end
+if not valid(ARGV[8]) then
+	return redis.error_reply('unknown ranker ' .. ARGV[8])
+end
+local postTime = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not postTime then
+	return 3
+end
+postTime = tonumber(postTime)
+local now = tonumber(ARGV[4])
+if now - postTime > tonumber(ARGV[5]) then
+	return 1
+end
+local value = tonumber(ARGV[3])
+local ov = tonumber(redis.call('ZSCORE', KEYS[4], ARGV[2]) or '0')
+if value == ov then
+	return 2
+end
+if value == 0 then
+	redis.call('ZREM', KEYS[4], ARGV[2])
+else
+	redis.call('ZADD', KEYS[4], value, ARGV[2])
+end
+
+local ups = redis.call('ZCOUNT', KEYS[4], 1, 1)
+local downs = redis.call('ZCOUNT', KEYS[4], -1, -1)
+local function score(alg, param)
+	if alg == 'simple' then
+		return postTime + (ups - downs) * param
+	elseif alg == 'hot' then
+		local s = ups - downs
+		local sign = 0
+		if s > 0 then sign = 1 elseif s < 0 then sign = -1 end
+		return sign * math.log10(math.max(math.abs(s), 1)) + (postTime - 1134028003) / param
+	elseif alg == 'gravity' then
+		local hours = math.max(now - postTime, 0) / 3600
+		return (ups - downs) / math.pow(hours + 2, param)
+	end
+	-- wilson
+	local n = ups + downs
+	if n == 0 then
+		return 0
+	end
+	local p, z2 = ups / n, param * param
+	return (p + z2 / (2 * n) - param * math.sqrt((p * (1 - p) + z2 / (4 * n)) / n)) / (1 + z2 / n)
+end
+redis.call('ZADD', KEYS[2], 'XX', string.format('%.17g', score(ARGV[6], tonumber(ARGV[7]))), ARGV[1])
+redis.call('ZADD', KEYS[3], string.format('%.17g', score(ARGV[8], tonumber(ARGV[9]))), ARGV[1])
+return 0
+`)
+
+// rescoreScript 后台任务重新计算分数后写回，已删除的帖子不再加回去
+// KEYS[1] post:time  KEYS[2] post:score  KEYS[3] community:score:<community_id>
+// ARGV每三个一组：post_id  默认算法的分数  社区算法的分数
+// 默认算法的分数为空表示帖子的投票期已结束，只在社区的分数中补上缺少的帖子
+var rescoreScript = redis.NewScript(`
+for i = 1, #ARGV, 3 do
+	local id = ARGV[i]
+	if redis.call('ZSCORE', KEYS[1], id) then
+		if ARGV[i + 1] == '' then
+			redis.call('ZADD', KEYS[3], 'NX', ARGV[i + 2], id)
+		else
+			redis.call('ZADD', KEYS[2], 'XX', ARGV[i + 1], id)
+			redis.call('ZADD', KEYS[3], ARGV[i + 2], id)
+		end
+	end
+end
+return 0
+`)
+
+// tokenBucketScript 令牌桶限流，令牌数及上次补充的时间保存在hash中
+// 使用redis服务端的时间，多个实例的时钟不一致也不影响
+// KEYS[1] ratelimit:<group>:<id>
+// ARGV[1] 每秒补充的令牌数  ARGV[2] 令牌桶容量
+// 返回 {是否允许, 剩余令牌数, 需要等待的毫秒数, 装满需要的毫秒数}
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local rate, burst = tonumber(ARGV[1]), tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens, ts = tonumber(bucket[1]), tonumber(bucket[2])
+if not tokens then
+	tokens, ts = burst, now
+end
+tokens = math.min(burst, tokens + math.max(now - ts, 0) * rate / 1000)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+local reset = math.ceil((burst - tokens) * 1000 / rate)
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.17g', tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), wait, reset}
+`)
+
+// unlockScript 锁的值等于持有者的token时才删除，避免误删锁过期后被其他实例拿到的锁
+// KEYS[1] lock:<name>  ARGV[1] token
+// 返回删除的key数
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// cursorRangeScript 按分数从大到小返回游标(分数, member)之后的ARGV[3]个member及分数
+// 分数大于游标的member用ZCOUNT的开区间直接跳过，分数相同的member按字典序倒序排列，用二分查找定位游标的位置
+// 每页只需要O(log n)次查询，不会因为大量帖子分数相同而反复扫描
+// member是数字形式的帖子id，lua中的字符串比较与zset的字典序一致
+// KEYS[1] 排序的zset  ARGV[1] 游标的分数  ARGV[2] 游标的member  ARGV[3] 数量
+// 返回 {member, 分数, member, 分数, ...}
+var cursorRangeScript = redis.NewScript(`
+local key, score, id, size = KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3])
+local lo = redis.call('ZCOUNT', key, '(' .. score, '+inf')
+local hi = lo + redis.call('ZCOUNT', key, score, score)
+while lo < hi do
+	local mid = math.floor((lo + hi) / 2)
+	local m = redis.call('ZREVRANGE', key, mid, mid)[1]
+	if m >= id then
+		lo = mid + 1
+	else
+		hi = mid
+	end
+end
+return redis.call('ZREVRANGE', key, lo, lo + size - 1, 'WITHSCORES')
+`)
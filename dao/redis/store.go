@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/rank"
+	"time"
+)
+
+// VoteStore 基于Redis的投票及帖子排序存储，把包级函数包装成logic层需要的接口
+type VoteStore struct{}
+
+func (VoteStore) CreatePost(p *models.Post, r rank.Ranker) error { return CreatePost(p, r) }
+
+func (VoteStore) RemovePost(p *models.Post) error { return RemovePost(p) }
+
+func (VoteStore) VoteForPost(userID, postID string, communityID int64, value float64, r rank.Ranker) error {
+	return VoteForPost(userID, postID, communityID, value, r)
+}
+
+func (VoteStore) GetVoteExpiredPostIDs(limit int64) ([]string, float64, error) {
+	return GetVoteExpiredPostIDs(limit)
+}
+
+func (VoteStore) GetPostVotes(ids []string) ([]*models.PostVotes, error) {
+	return GetPostVotes(ids)
+}
+
+func (VoteStore) ArchivePostVotes(ids []string, until float64) error {
+	return ArchivePostVotes(ids, until)
+}
+
+func (VoteStore) RecomputeScores(communityID int64, r rank.Ranker) error {
+	return RecomputeScores(communityID, r)
+}
+
+func (VoteStore) GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	return GetPostIDsInOrder(p)
+}
+
+func (VoteStore) GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	return GetCommunityPostIDsInOrder(p)
+}
+
+func (VoteStore) GetPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	return GetPostIDsByCursor(p, cursor)
+}
+
+func (VoteStore) GetCommunityPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	return GetCommunityPostIDsByCursor(p, cursor)
+}
+
+func (VoteStore) GetCommunitiesPostIDsInOrder(p *models.ParamPostList, communityIDs []int64) ([]string, error) {
+	return GetCommunitiesPostIDsInOrder(p, communityIDs)
+}
+
+func (VoteStore) GetCommunitiesPostIDsByCursor(p *models.ParamPostList, communityIDs []int64, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	return GetCommunitiesPostIDsByCursor(p, communityIDs, cursor)
+}
+
+func (VoteStore) GetTagPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	return GetTagPostIDsInOrder(p)
+}
+
+func (VoteStore) GetTagPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	return GetTagPostIDsByCursor(p, cursor)
+}
+
+func (VoteStore) GetTrendingTags(window time.Duration, size int64) ([]*models.TagCount, error) {
+	return GetTrendingTags(window, size)
+}
+
+func (VoteStore) GetAuthorPostIDsByCursor(authorID int64, p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	return GetAuthorPostIDsByCursor(authorID, p, cursor)
+}
+
+func (VoteStore) GetPostVoteData(ids []string, userID string) ([]*models.PostVoteData, error) {
+	return GetPostVoteData(ids, userID)
+}
+
+// TimelineStore 基于Redis的时间线存储
+type TimelineStore struct{}
+
+func (TimelineStore) PushTimeline(userIDs []int64, p *models.Post, size int64) error {
+	return PushTimeline(userIDs, p, size)
+}
+
+func (TimelineStore) RemoveFromTimelines(userIDs []int64, pid int64) error {
+	return RemoveFromTimelines(userIDs, pid)
+}
+
+func (TimelineStore) AddAuthorToTimeline(userID, authorID, size int64) error {
+	return AddAuthorToTimeline(userID, authorID, size)
+}
+
+func (TimelineStore) RemoveAuthorFromTimeline(userID, authorID int64) error {
+	return RemoveAuthorFromTimeline(userID, authorID)
+}
+
+func (TimelineStore) GetTimelineIDsByCursor(userID int64, pullAuthorIDs []int64, cursor *models.PostCursor, size int64) ([]string, *models.PostCursor, error) {
+	return GetTimelineIDsByCursor(userID, pullAuthorIDs, cursor, size)
+}
+
+// TokenStore 基于Redis的refresh token及access token黑名单存储
+type TokenStore struct{}
+
+func (TokenStore) SaveAccessToken(userID int64, tokenID string, expire time.Duration) error {
+	return SaveAccessToken(userID, tokenID, expire)
+}
+
+func (TokenStore) SaveRefreshToken(token string, s *models.RefreshSession, expire time.Duration) error {
+	return SaveRefreshToken(token, s, expire)
+}
+
+func (TokenStore) TakeRefreshToken(token string) (*models.RefreshSession, error) {
+	return TakeRefreshToken(token)
+}
+
+func (TokenStore) RemoveRefreshToken(userID int64, token string) error {
+	return RemoveRefreshToken(userID, token)
+}
+
+func (TokenStore) DenyToken(tokenID string, expire time.Duration) error {
+	return DenyToken(tokenID, expire)
+}
+
+func (TokenStore) IsTokenDenied(tokenID string) (bool, error) {
+	return IsTokenDenied(tokenID)
+}
+
+func (TokenStore) RevokeUserTokens(userID int64, expire time.Duration) error {
+	return RevokeUserTokens(userID, expire)
+}
+
+// RateLimitStore 基于Redis的令牌桶限流，多个实例共享同一个令牌桶
+type RateLimitStore struct{}
+
+func (RateLimitStore) TakeToken(key string, rate float64, burst int64) (*models.RateLimitResult, error) {
+	return TakeToken(key, rate, burst)
+}
+
+// LoginGuardStore 基于Redis的登录失败次数及禁止登录记录
+type LoginGuardStore struct{}
+
+func (LoginGuardStore) GetLoginLocks(keys []string) ([]time.Duration, error) {
+	return GetLoginLocks(keys)
+}
+
+func (LoginGuardStore) IncrLoginFailures(key string, window time.Duration) (int64, error) {
+	return IncrLoginFailures(key, window)
+}
+
+func (LoginGuardStore) LockLogin(key string, d time.Duration) error { return LockLogin(key, d) }
+
+func (LoginGuardStore) ResetLoginFailures(key string) error { return ResetLoginFailures(key) }
+
+// LockStore 基于Redis的分布式锁
+type LockStore struct{}
+
+func (LockStore) TryLock(name, token string, ttl time.Duration) (bool, error) {
+	return TryLock(name, token, ttl)
+}
+
+func (LockStore) Unlock(name, token string) error { return Unlock(name, token) }
@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"bell_best/models"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+// 时间线：粉丝不多的作者发帖时把帖子推送到每个粉丝的时间线(写扩散)，
+// 粉丝很多的作者不推送，粉丝读取时间线时再把这些作者的帖子合并进来(读扩散)
+
+func timelineKey(uid int64) string {
+	return GetRedisKey(KeyTimelinePF + strconv.FormatInt(uid, 10))
+}
+
+func authorKey(uid int64) string {
+	return GetRedisKey(KeyAuthorPF + strconv.FormatInt(uid, 10))
+}
+
+// PushTimeline 把帖子推送到userIDs的时间线，每条时间线只保留最新的size个帖子
+func PushTimeline(userIDs []int64, p *models.Post, size int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	z := &redis.Z{Score: float64(p.CreateTime.Unix()), Member: p.ID}
+	pipeline := client.Pipeline()
+	for _, uid := range userIDs {
+		key := timelineKey(uid)
+		pipeline.ZAdd(ctx, key, z)
+		pipeline.ZRemRangeByRank(ctx, key, 0, -size-1)
+	}
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// RemoveFromTimelines 把删除的帖子从userIDs的时间线中移除
+func RemoveFromTimelines(userIDs []int64, pid int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	pipeline := client.Pipeline()
+	for _, uid := range userIDs {
+		pipeline.ZRem(ctx, timelineKey(uid), pid)
+	}
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// AddAuthorToTimeline 关注作者后把作者的帖子合并到时间线，只保留最新的size个帖子
+func AddAuthorToTimeline(userID, authorID, size int64) error {
+	key := timelineKey(userID)
+	tmp := key + ":tmp"
+	pipeline := client.TxPipeline()
+	// 作者set中member的分数是1，权重设为0，结果只保留发帖时间
+	pipeline.ZInterStore(ctx, tmp, &redis.ZStore{
+		Keys:    []string{authorKey(authorID), GetRedisKey(KeyPostTime)},
+		Weights: []float64{0, 1},
+	})
+	pipeline.ZUnionStore(ctx, key, &redis.ZStore{Keys: []string{key, tmp}, Aggregate: "MAX"})
+	pipeline.ZRemRangeByRank(ctx, key, 0, -size-1)
+	pipeline.Del(ctx, tmp, GetRedisKey(KeyTimelineMergedPF+strconv.FormatInt(userID, 10)))
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// RemoveAuthorFromTimeline 取消关注后把作者的帖子从时间线中移除
+func RemoveAuthorFromTimeline(userID, authorID int64) error {
+	ids, err := client.SMembers(ctx, authorKey(authorID)).Result()
+	if err != nil {
+		return err
+	}
+	pipeline := client.TxPipeline()
+	if len(ids) > 0 {
+		members := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			members = append(members, id)
+		}
+		pipeline.ZRem(ctx, timelineKey(userID), members...)
+	}
+	pipeline.Del(ctx, GetRedisKey(KeyTimelineMergedPF+strconv.FormatInt(userID, 10)))
+	_, err = pipeline.Exec(ctx)
+	return err
+}
+
+// GetTimelineIDsByCursor 按游标查询时间线中的帖子id，pullAuthorIDs中作者的帖子在读取时合并
+// 查询第一页时重新合并，结果缓存一分钟，之后翻页使用缓存
+func GetTimelineIDsByCursor(userID int64, pullAuthorIDs []int64, cursor *models.PostCursor, size int64) ([]string, *models.PostCursor, error) {
+	key := timelineKey(userID)
+	if len(pullAuthorIDs) > 0 {
+		merged := GetRedisKey(KeyTimelineMergedPF + strconv.FormatInt(userID, 10))
+		if cursor == nil || client.Exists(ctx, merged).Val() < 1 {
+			keys := make([]string, 0, len(pullAuthorIDs))
+			for _, id := range pullAuthorIDs {
+				keys = append(keys, authorKey(id))
+			}
+			pipeline := client.TxPipeline()
+			pipeline.ZUnionStore(ctx, merged, &redis.ZStore{Keys: keys})
+			pipeline.ZInterStore(ctx, merged, &redis.ZStore{
+				Keys:    []string{merged, GetRedisKey(KeyPostTime)},
+				Weights: []float64{0, 1},
+			})
+			pipeline.ZUnionStore(ctx, merged, &redis.ZStore{Keys: []string{merged, key}, Aggregate: "MAX"})
+			pipeline.Expire(ctx, merged, 60*time.Second)
+			if _, err := pipeline.Exec(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
+		key = merged
+	}
+	return getIDsFormKeyByCursor(key, cursor, size)
+}
@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"bell_best/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrRefreshTokenNotExist = errors.New("refresh token not exist")
+
+// redis中只保存refresh token的sha256，库里的数据泄露也拿不到可用的token
+func refreshTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return GetRedisKey(KeyRefreshTokenPF + hex.EncodeToString(sum[:]))
+}
+
+func userTokensKey(userID int64) string {
+	return GetRedisKey(KeyUserTokensPF + strconv.FormatInt(userID, 10))
+}
+
+func userAccessKey(userID int64) string {
+	return GetRedisKey(KeyUserAccessPF + strconv.FormatInt(userID, 10))
+}
+
+// SaveAccessToken 记录签发给用户的access token，强制下线时据此拉黑所有未过期的token
+func SaveAccessToken(userID int64, tokenID string, expire time.Duration) error {
+	key := userAccessKey(userID)
+	now := time.Now()
+	pipeline := client.TxPipeline()
+	// 顺便清理已经过期的token
+	pipeline.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipeline.ZAdd(ctx, key, &redis.Z{Score: float64(now.Add(expire).Unix()), Member: tokenID})
+	pipeline.Expire(ctx, key, expire)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// SaveRefreshToken 保存refresh token对应的会话，并记录到用户的会话集合中
+func SaveRefreshToken(token string, s *models.RefreshSession, expire time.Duration) error {
+	key := refreshTokenKey(token)
+	pipeline := client.TxPipeline()
+	pipeline.HSet(ctx, key, map[string]interface{}{
+		"user_id":  s.UserID,
+		"username": s.Username,
+		"jti":      s.TokenID,
+	})
+	pipeline.Expire(ctx, key, expire)
+	pipeline.SAdd(ctx, userTokensKey(s.UserID), key)
+	pipeline.Expire(ctx, userTokensKey(s.UserID), expire)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// TakeRefreshToken 取出并删除refresh token，同一个token只能使用一次
+func TakeRefreshToken(token string) (*models.RefreshSession, error) {
+	key := refreshTokenKey(token)
+	// 在一个事务中读取并删除，并发请求中只有一个能拿到会话
+	pipeline := client.TxPipeline()
+	get := pipeline.HGetAll(ctx, key)
+	pipeline.Del(ctx, key)
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return nil, err
+	}
+	s, err := parseRefreshSession(get.Val())
+	if err != nil {
+		return nil, err
+	}
+	client.SRem(ctx, userTokensKey(s.UserID), key)
+	return s, nil
+}
+
+// RemoveRefreshToken 删除用户的refresh token，不属于该用户的token不做处理
+func RemoveRefreshToken(userID int64, token string) error {
+	key := refreshTokenKey(token)
+	n, err := client.SRem(ctx, userTokensKey(userID), key).Result()
+	if err != nil || n == 0 {
+		return err
+	}
+	return client.Del(ctx, key).Err()
+}
+
+// DenyToken 拉黑access token，过期时间不短于token剩余的有效期即可
+func DenyToken(tokenID string, expire time.Duration) error {
+	return client.Set(ctx, GetRedisKey(KeyTokenDeniedPF+tokenID), 1, expire).Err()
+}
+
+// IsTokenDenied 判断access token是否已被拉黑
+func IsTokenDenied(tokenID string) (bool, error) {
+	n, err := client.Exists(ctx, GetRedisKey(KeyTokenDeniedPF+tokenID)).Result()
+	return n > 0, err
+}
+
+// RevokeUserTokens 作废用户所有的refresh token，并拉黑用户所有未过期的access token
+// 刷新后旧的refresh token已不在会话集合中，所以access token以签发记录为准
+func RevokeUserTokens(userID int64, expire time.Duration) error {
+	setKey := userTokensKey(userID)
+	accessKey := userAccessKey(userID)
+	keys, err := client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
+	jtis, err := client.ZRangeByScore(ctx, accessKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return err
+	}
+	pipeline := client.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipeline.HGet(ctx, key, "jti"))
+	}
+	if _, err = pipeline.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if jti := cmd.Val(); jti != "" {
+			jtis = append(jtis, jti)
+		}
+	}
+	pipeline = client.TxPipeline()
+	for _, jti := range jtis {
+		pipeline.Set(ctx, GetRedisKey(KeyTokenDeniedPF+jti), 1, expire)
+	}
+	if len(keys) > 0 {
+		pipeline.Del(ctx, keys...)
+	}
+	pipeline.Del(ctx, setKey, accessKey)
+	_, err = pipeline.Exec(ctx)
+	return err
+}
+
+func parseRefreshSession(fields map[string]string) (*models.RefreshSession, error) {
+	if len(fields) == 0 {
+		return nil, ErrRefreshTokenNotExist
+	}
+	uid, err := strconv.ParseInt(fields["user_id"], 10, 64)
+	if err != nil {
+		return nil, ErrRefreshTokenNotExist
+	}
+	return &models.RefreshSession{
+		UserID:   uid,
+		Username: fields["username"],
+		TokenID:  fields["jti"],
+	}, nil
+}
@@ -1,28 +1,29 @@
 package redis
 
 import (
+	"bell_best/models"
+	"bell_best/pkg/rank"
 	"context"
 	"errors"
 	"github.com/go-redis/redis/v8"
-	"math"
 	"strconv"
 	"time"
 )
 
-// 基于用户投票的相关算法:http://www.ruanyifeng.com/blog/algorithm/
-// 本项目使用简化版的投票分数
-// 投一票就加432分	86400/200 -> 200张赞成票可以给你的帖子续一天
+// 帖子的分数由排序算法(pkg/rank)根据赞成票、反对票及发帖时间计算
+// 默认算法的分数保存在post:score中，社区配置的算法的分数保存在community:score:<社区id>中
+// 每次投票后按最新的票数重新计算分数，后台任务定期重新计算投票期内所有帖子的分数
 
 /* 投票的几种情况
 direction=1时，有两种情况：
-	1. 之前没有投过票，现在投赞成票--> 更新分数和投票记录	差值绝对值是1	+432
-	2. 之前投反对票，现在改赞成票--> 更新分数和投票记录	差值绝对值是2	+432*2
+	1. 之前没有投过票，现在投赞成票--> 赞成票+1
+	2. 之前投反对票，现在改赞成票--> 赞成票+1 反对票-1
 direction=0时，有两种情况：
-	1. 之前投反对票，现在取消投票--> 更新分数和投票记录	差值绝对值是1	+432
-	2. 之前投赞成票，现在取消投票--> 更新分数和投票记录	差值绝对值是1	-432
+	1. 之前投反对票，现在取消投票--> 反对票-1
+	2. 之前投赞成票，现在取消投票--> 赞成票-1
 direction=-1时，有两种情况：
-	1. 之前没有投过票，现在投反对票--> 更新分数和投票记录	差值绝对值是1	-432
-	2. 之前投赞成票，现在改反对票--> 更新分数和投票记录		差值绝对值是2	-432*2
+	1. 之前没有投过票，现在投反对票--> 反对票+1
+	2. 之前投赞成票，现在改反对票--> 赞成票-1 反对票+1
 
 投票的限制：
 每个帖子自发帖之日起只在一个星期内允许投票
@@ -32,70 +33,157 @@ direction=-1时，有两种情况：
 
 const (
 	oneWeekInSeconds = 7 * 24 * 60 * 60
-	scorePerVote     = 432 // 每一票值多少分
+	recomputeBatch   = 500 // 后台重新计算分数时每批处理的帖子数
 )
 
 var (
-	ErrVoteTimeExpired = errors.New("vote time expired")
-	ctx                = context.Background()
-	ErrVoteRepested    = errors.New("vote repested")
+	ErrVoteTimeExpired  = errors.New("vote time expired")
+	ctx                 = context.Background()
+	ErrVoteRepested     = errors.New("vote repested")
+	ErrVotePostNotExist = errors.New("vote post not exist")
 )
 
-func CreatePost(postID, communityID int64) error {
+// communityScoreKey 社区内按分数排序的zset
+func communityScoreKey(communityID int64) string {
+	return GetRedisKey(KeyCommunityScorePF + strconv.FormatInt(communityID, 10))
+}
+
+// CreatePost 记录帖子的发帖时间、初始分数、所属社区及作者，r是帖子所在社区使用的排序算法
+func CreatePost(p *models.Post, r rank.Ranker) error {
+	now := time.Now()
+	keys := []string{
+		GetRedisKey(KeyPostTime),                                       // 帖子时间
+		GetRedisKey(KeyPostScore),                                      // 帖子分数
+		communityScoreKey(p.CommunityID),                               // 帖子在社区内的分数
+		GetRedisKey(KeyCommunityPF + strconv.Itoa(int(p.CommunityID))), // 把帖子id加到社区的set
+		GetRedisKey(KeyAuthorPF + strconv.FormatInt(p.AuthorID, 10)),   // 把帖子id加到作者的set
+		GetRedisKey(KeyTagUsagePF + strconv.FormatInt(now.Unix()/3600, 10)),
+	}
+	args := []interface{}{p.ID, now.Unix(), rank.Default().Score(0, 0, now, now), r.Score(0, 0, now, now),
+		int64(tagUsageExpire.Seconds())}
+	for _, tag := range p.Tags {
+		keys = append(keys, GetRedisKey(KeyTagPF+tag)) // 把帖子id加到标签的set
+		args = append(args, tag)
+	}
+	return createPostScript.Run(ctx, client, keys, args...).Err()
+}
+
+// RemovePost 把帖子从时间、分数、社区、作者及标签的排序中移除并删除投票记录，用于删除帖子
+func RemovePost(p *models.Post) error {
+	cid := strconv.Itoa(int(p.CommunityID))
+	aKey := KeyAuthorPF + strconv.FormatInt(p.AuthorID, 10)
 	pipeline := client.TxPipeline()
-	// 帖子时间
-	client.ZAdd(ctx, GetRedisKey(KeyPostTime), &redis.Z{
-		Score:  float64(time.Now().Unix()),
-		Member: postID,
-	})
-	// 帖子分数
-	client.ZAdd(ctx, GetRedisKey(KeyPostScore), &redis.Z{
-		Score:  float64(time.Now().Unix()),
-		Member: postID,
-	})
-	// 把帖子id加到社区的set
-	cKey := GetRedisKey(KeyCommunityPF + strconv.Itoa(int(communityID)))
-	pipeline.SAdd(ctx, cKey, postID)
+	pipeline.ZRem(ctx, GetRedisKey(KeyPostTime), p.ID)
+	pipeline.ZRem(ctx, GetRedisKey(KeyPostScore), p.ID)
+	pipeline.ZRem(ctx, communityScoreKey(p.CommunityID), p.ID)
+	pipeline.SRem(ctx, GetRedisKey(KeyCommunityPF+cid), p.ID)
+	pipeline.SRem(ctx, GetRedisKey(aKey), p.ID)
+	// 投票期内删除的帖子不会再被归档，投票记录直接删除
+	pipeline.Del(ctx, GetRedisKey(KeyPostVotedPF+strconv.FormatInt(p.ID, 10)))
+	// 社区、作者及标签帖子列表的zinterstore缓存也要一起清掉
+	caches := make([]string, 0, 4+4*len(p.Tags))
+	for _, orderKey := range []string{GetRedisKey(KeyPostTime), GetRedisKey(KeyPostScore)} {
+		caches = append(caches, orderKey+cid, orderKey+":"+aKey)
+		for _, tag := range p.Tags {
+			caches = append(caches, tagCacheKey(orderKey, 0, tag), tagCacheKey(orderKey, p.CommunityID, tag))
+		}
+	}
+	for _, tag := range p.Tags {
+		pipeline.SRem(ctx, GetRedisKey(KeyTagPF+tag), p.ID)
+	}
+	pipeline.Del(ctx, caches...)
 	_, err := pipeline.Exec(ctx)
 	return err
 }
 
-// VoteForPost 为帖子投票的函数
-func VoteForPost(userID, postID string, value float64) error {
-	// 1. 判断投票限制
-	// 去redis取帖子发布时间
-	ctx := context.Background()
-	postTime := client.ZScore(ctx, GetRedisKey(KeyPostTime), postID).Val()
-	if float64(time.Now().Unix())-postTime > oneWeekInSeconds {
+// VoteForPost 为帖子投票的函数，r是帖子所在社区communityID使用的排序算法
+// 判断投票限制、更新分数及记录投票在lua脚本中原子执行，同一用户并发投票不会重复计算
+func VoteForPost(userID, postID string, communityID int64, value float64, r rank.Ranker) error {
+	keys := []string{
+		GetRedisKey(KeyPostTime),
+		GetRedisKey(KeyPostScore),
+		communityScoreKey(communityID),
+		GetRedisKey(KeyPostVotedPF + postID),
+	}
+	def := rank.Default()
+	code, err := voteScript.Run(ctx, client, keys,
+		postID, userID, value, time.Now().Unix(), oneWeekInSeconds,
+		def.Name(), def.Param(), r.Name(), r.Param()).Int()
+	if err != nil {
+		return err
+	}
+	switch code {
+	case voteExpired:
 		return ErrVoteTimeExpired
+	case voteRepeated:
+		return ErrVoteRepested
+	case votePostMissing:
+		return ErrVotePostNotExist
 	}
-	// 2和3需要放在一个pipeline事务中操作
+	return nil
+}
 
-	// 2. 更新帖子分数
-	// 先查当前用户给当前帖子的投票记录
-	ov := client.ZScore(ctx, GetRedisKey(KeyPostVotedPF+postID), userID).Val()
-	// 如果这一次投票的值和之前保存的值一致，就提示不允许重复投票
-	if value == ov {
-		return ErrVoteRepested
+// RecomputeScores 用默认算法及社区的排序算法r重新计算社区下仍在投票期内的帖子的分数
+// 投票期结束的帖子票数不再变化，分数保持最后一次计算的结果
+// 社区的分数中缺少的帖子(如升级前发的帖子)用post:score中的分数补上
+func RecomputeScores(communityID int64, r rank.Ranker) error {
+	cKey := GetRedisKey(KeyCommunityPF + strconv.FormatInt(communityID, 10))
+	now := time.Now()
+	var cursor uint64
+	for {
+		ids, next, err := client.SScan(ctx, cKey, cursor, "", recomputeBatch).Result()
+		if err != nil {
+			return err
+		}
+		if err = recomputeBatchScores(ids, communityID, r, now); err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func recomputeBatchScores(ids []string, communityID int64, r rank.Ranker, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
 	}
-	var op float64
-	if value > ov {
-		op = 1
-	} else {
-		op = -1
+	pipeline := client.Pipeline()
+	timeCmds := make([]*redis.FloatCmd, 0, len(ids))
+	scoreCmds := make([]*redis.FloatCmd, 0, len(ids))
+	upCmds := make([]*redis.IntCmd, 0, len(ids))
+	downCmds := make([]*redis.IntCmd, 0, len(ids))
+	for _, id := range ids {
+		votedKey := GetRedisKey(KeyPostVotedPF + id)
+		timeCmds = append(timeCmds, pipeline.ZScore(ctx, GetRedisKey(KeyPostTime), id))
+		scoreCmds = append(scoreCmds, pipeline.ZScore(ctx, GetRedisKey(KeyPostScore), id))
+		upCmds = append(upCmds, pipeline.ZCount(ctx, votedKey, "1", "1"))
+		downCmds = append(downCmds, pipeline.ZCount(ctx, votedKey, "-1", "-1"))
 	}
-	diff := math.Abs(ov - value)
-	pipeline := client.TxPipeline()                                                         // 计算两次投票的差值
-	pipeline.ZIncrBy(ctx, GetRedisKey(KeyPostScore), op*diff*scorePerVote, postID).Result() // 更新分数
-	// 3. 记录用户为该帖子投票的数据
-	if value == 0 {
-		pipeline.ZRem(ctx, GetRedisKey(KeyPostVotedPF+postID), userID)
-	} else {
-		pipeline.ZAdd(ctx, GetRedisKey(KeyPostVotedPF+postID), &redis.Z{
-			Score:  value, // 赞成票还是反对票
-			Member: userID,
-		})
+	// 已删除的帖子ZSCORE返回redis.Nil，下面单独判断
+	if _, err := pipeline.Exec(ctx); err != nil && err != redis.Nil {
+		return err
 	}
-	_, err := pipeline.Exec(ctx)
-	return err
+	def := rank.Default()
+	args := make([]interface{}, 0, 3*len(ids))
+	for i, id := range ids {
+		postTime, err := timeCmds[i].Result()
+		if err != nil {
+			continue
+		}
+		if float64(now.Unix())-postTime > oneWeekInSeconds {
+			if score, err := scoreCmds[i].Result(); err == nil {
+				args = append(args, id, "", score)
+			}
+			continue
+		}
+		ups, downs, created := upCmds[i].Val(), downCmds[i].Val(), time.Unix(int64(postTime), 0)
+		args = append(args, id, def.Score(ups, downs, created, now), r.Score(ups, downs, created, now))
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	keys := []string{GetRedisKey(KeyPostTime), GetRedisKey(KeyPostScore), communityScoreKey(communityID)}
+	return rescoreScript.Run(ctx, client, keys, args...).Err()
 }
@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/snowflake"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
+)
+
+// CreateComment 发表评论，ParentID不为0时作为对该评论的回复
+func CreateComment(userID, postID int64, p *models.ParamCreateComment) (data *models.ApiComment, err error) {
+	// 帖子必须存在
+	if _, err = postStore.GetPostByID(postID); err != nil {
+		return nil, err
+	}
+	comment := &models.Comment{
+		PostID:   postID,
+		ParentID: p.ParentID,
+		AuthorID: userID,
+		Content:  p.Content,
+	}
+	if p.ParentID != 0 {
+		parent, err := commentStore.GetCommentByID(p.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		// 不能回复其他帖子下的评论
+		if parent.PostID != postID {
+			return nil, models.ErrorCommentNotExist
+		}
+		comment.RootID = parent.RootID
+	}
+	comment.ID = snowflake.GenID()
+	comment.CreateTime = time.Now()
+	if comment.RootID == 0 {
+		comment.RootID = comment.ID
+	}
+	if err = commentStore.CreateComment(comment); err != nil {
+		return nil, err
+	}
+	user, err := userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApiComment{AuthorName: user.Username, Comment: comment}, nil
+}
+
+// GetCommentList 按游标分页获取帖子的评论
+// flat视图按时间顺序返回所有评论；tree视图按顶层评论分页，每条顶层评论带上它下面的全部回复
+func GetCommentList(postID int64, p *models.ParamCommentList) (data *models.ApiCommentList, err error) {
+	if _, err = postStore.GetPostByID(postID); err != nil {
+		return nil, err
+	}
+	if p.Size <= 0 {
+		p.Size = defaultCommentPageSize
+	}
+	if p.Size > maxCommentPageSize {
+		p.Size = maxCommentPageSize
+	}
+	var page, all []*models.Comment
+	if p.View == models.CommentViewTree {
+		page, err = commentStore.GetRootCommentList(postID, p.Cursor, p.Size)
+		if err != nil {
+			return nil, err
+		}
+		rootIDs := make([]int64, 0, len(page))
+		for _, c := range page {
+			rootIDs = append(rootIDs, c.ID)
+		}
+		replies, err := commentStore.GetCommentsByRootIDs(rootIDs)
+		if err != nil {
+			return nil, err
+		}
+		all = append(append(all, page...), replies...)
+	} else {
+		page, err = commentStore.GetCommentList(postID, p.Cursor, p.Size)
+		if err != nil {
+			return nil, err
+		}
+		all = page
+	}
+
+	// 批量填充评论作者
+	uids := make([]int64, 0, len(all))
+	for _, c := range all {
+		uids = append(uids, c.AuthorID)
+	}
+	l := newLoader()
+	if err = l.loadUsers(uids); err != nil {
+		return nil, err
+	}
+	nodes := make(map[int64]*models.ApiComment, len(all))
+	for _, c := range all {
+		node := &models.ApiComment{Comment: c}
+		if user, ok := l.users[c.AuthorID]; ok {
+			node.AuthorName = user.Username
+		}
+		nodes[c.ID] = node
+	}
+
+	data = &models.ApiCommentList{Comments: make([]*models.ApiComment, 0, len(page))}
+	if p.View == models.CommentViewTree {
+		// 回复按id顺序挂到父评论下，父评论一定比回复先创建
+		for _, c := range all {
+			if c.ParentID == 0 {
+				continue
+			}
+			if parent, ok := nodes[c.ParentID]; ok {
+				parent.Children = append(parent.Children, nodes[c.ID])
+			}
+		}
+	}
+	for _, c := range page {
+		data.Comments = append(data.Comments, nodes[c.ID])
+	}
+	if int64(len(page)) == p.Size {
+		data.NextCursor = strconv.FormatInt(page[len(page)-1].ID, 10)
+	}
+	return
+}
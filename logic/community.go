@@ -1,16 +1,141 @@
 package logic
 
 import (
-	"bell_best/dao/mysql"
 	"bell_best/models"
+	"bell_best/pkg/rbac"
+	"database/sql"
+	"errors"
+
+	"go.uber.org/zap"
 )
 
+var ErrorCommunityArchived = errors.New("社区已归档")
+
 func GetCommunityList() ([]*models.Community, error) {
 	// 查数据库 查找到所有的community 并返回
-	return mysql.GetCommunityList()
+	return communityStore.GetCommunityList()
 }
 
 // detail 细节
 func GetCommunityDetail(id int64) (*models.CommunityDetail, error) {
-	return mysql.GetCommunityDetailByID(id)
+	return communityStore.GetCommunityDetailByID(id)
+}
+
+// CreateCommunity 创建社区，userID为操作的管理员
+func CreateCommunity(userID int64, p *models.ParamCommunity) (*models.CommunityDetail, error) {
+	c := &models.CommunityDetail{Name: p.Name, Introduction: p.Introduction}
+	if err := communityStore.CreateCommunity(c); err != nil {
+		return nil, err
+	}
+	zap.L().Info("audit: community created", zap.Int64("user_id", userID), zap.Int64("community_id", c.ID))
+	return c, nil
+}
+
+// UpdateCommunity 编辑社区的名称及简介
+func UpdateCommunity(userID, id int64, p *models.ParamCommunity) (*models.CommunityDetail, error) {
+	c, err := communityStore.GetCommunityDetailByID(id)
+	if err != nil {
+		return nil, err
+	}
+	c.Name = p.Name
+	c.Introduction = p.Introduction
+	if err := communityStore.UpdateCommunity(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetCommunityArchived 归档或恢复社区，归档后不能再发帖
+func SetCommunityArchived(userID, id int64, archived bool) error {
+	c, err := communityStore.GetCommunityDetailByID(id)
+	if err != nil {
+		return err
+	}
+	if c.Archived == archived {
+		return nil
+	}
+	c.Archived = archived
+	if err := communityStore.UpdateCommunity(c); err != nil {
+		return err
+	}
+	zap.L().Info("audit: community archived changed",
+		zap.Int64("user_id", userID), zap.Int64("community_id", id), zap.Bool("archived", archived))
+	return nil
+}
+
+// GetCommunityModerators 查询社区的版主，按任命顺序返回
+func GetCommunityModerators(id int64) ([]*models.ApiModerator, error) {
+	if _, err := communityStore.GetCommunityDetailByID(id); err != nil {
+		return nil, err
+	}
+	uids, err := communityStore.GetCommunityModerators(id)
+	if err != nil {
+		return nil, err
+	}
+	users, err := userStore.GetUsersByIDs(uids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(users))
+	for _, u := range users {
+		names[u.UserID] = u.Username
+	}
+	data := make([]*models.ApiModerator, 0, len(uids))
+	for _, uid := range uids {
+		// 已经不存在的用户不返回
+		if name, ok := names[uid]; ok {
+			data = append(data, &models.ApiModerator{UserID: uid, Username: name})
+		}
+	}
+	return data, nil
+}
+
+// AddCommunityModerator 任命社区版主
+func AddCommunityModerator(userID, id, moderatorID int64) error {
+	if _, err := communityStore.GetCommunityDetailByID(id); err != nil {
+		return err
+	}
+	if _, err := userStore.GetUserByID(moderatorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrorUserNotExist
+		}
+		return err
+	}
+	if err := communityStore.AddCommunityModerator(id, moderatorID); err != nil {
+		return err
+	}
+	zap.L().Info("audit: moderator added",
+		zap.Int64("user_id", userID), zap.Int64("community_id", id), zap.Int64("moderator_id", moderatorID))
+	return nil
+}
+
+// RemoveCommunityModerator 撤销社区版主
+func RemoveCommunityModerator(userID, id, moderatorID int64) error {
+	if err := communityStore.RemoveCommunityModerator(id, moderatorID); err != nil {
+		return err
+	}
+	zap.L().Info("audit: moderator removed",
+		zap.Int64("user_id", userID), zap.Int64("community_id", id), zap.Int64("moderator_id", moderatorID))
+	return nil
+}
+
+// SubscribeCommunity 订阅或取消订阅社区，归档的社区也可以订阅
+func SubscribeCommunity(userID, id int64, subscribe bool) error {
+	if _, err := communityStore.GetCommunityDetailByID(id); err != nil {
+		return err
+	}
+	if subscribe {
+		_, err := communityStore.SubscribeCommunity(id, userID)
+		return err
+	}
+	_, err := communityStore.UnsubscribeCommunity(id, userID)
+	return err
+}
+
+// canModerate 判断用户能不能管理社区下的帖子，拥有删除帖子权限的角色及社区版主可以
+func canModerate(userID int64, role string, communityID int64) (bool, error) {
+	if rbac.Can(role, rbac.PermPostRemove) {
+		return true, nil
+	}
+	return communityStore.IsCommunityModerator(communityID, userID)
 }
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"bell_best/models"
+	"encoding/base64"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrorInvalidCursor = errors.New("无效的游标")
+
+// 帖子游标对客户端是不透明的字符串，内容是 base64url("分数:帖子id")
+
+func encodePostCursor(c *models.PostCursor) string {
+	if c == nil {
+		return ""
+	}
+	raw := strconv.FormatFloat(c.Score, 'f', -1, 64) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePostCursor 解析游标，空字符串表示从第一页开始
+func decodePostCursor(s string) (*models.PostCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrorInvalidCursor
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	// NaN和Inf不是有效的分数
+	if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+		return nil, ErrorInvalidCursor
+	}
+	if _, err := strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	return &models.PostCursor{Score: score, ID: parts[1]}, nil
+}
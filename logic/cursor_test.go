@@ -0,0 +1,29 @@
+package logic
+
+import (
+	"bell_best/models"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestDecodePostCursor(t *testing.T) {
+	for _, c := range []*models.PostCursor{{Score: 1700000000, ID: "42"}, {Score: -0.125, ID: "7"}, {Score: 1e-20, ID: "1"}} {
+		got, err := decodePostCursor(encodePostCursor(c))
+		if err != nil || *got != *c {
+			t.Errorf("round trip %v = %v, %v", c, got, err)
+		}
+	}
+	if got, err := decodePostCursor(""); got != nil || err != nil {
+		t.Errorf("empty cursor = %v, %v", got, err)
+	}
+	for _, raw := range []string{"NaN:1", "Inf:1", "-Inf:1", "+Inf:1", "1:", "1:abc", "abc:1", "1"} {
+		s := base64.RawURLEncoding.EncodeToString([]byte(raw))
+		if _, err := decodePostCursor(s); !errors.Is(err, ErrorInvalidCursor) {
+			t.Errorf("decodePostCursor(%q) err = %v, want ErrorInvalidCursor", raw, err)
+		}
+	}
+	if _, err := decodePostCursor("!!"); !errors.Is(err, ErrorInvalidCursor) {
+		t.Errorf("invalid base64: err = %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package logic
+
+import (
+	"bell_best/models"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 定时发布任务每次最多发布的帖子数
+const publishBatchSize = 100
+
+// GetDraftList 获取用户自己的草稿及定时发布的帖子
+func GetDraftList(userID, page, size int64) ([]*models.ApiPostDetail, error) {
+	posts, err := postStore.GetDraftList(userID, page, size)
+	if err != nil {
+		return nil, err
+	}
+	return buildPostDetails(userID, posts)
+}
+
+// PublishPost 发布草稿，publishAt为空或已过去时立即发布，否则改为定时发布
+// 定时发布的帖子也可以修改发布时间或立即发布
+func PublishPost(userID, pid int64, publishAt *time.Time) error {
+	post, err := postStore.GetDraftByID(pid)
+	if err != nil {
+		return err
+	}
+	if post.AuthorID != userID {
+		return ErrorNotPostAuthor
+	}
+	community, err := communityStore.GetCommunityDetailByID(post.CommunityID)
+	if err != nil {
+		return err
+	}
+	if community.Archived {
+		return ErrorCommunityArchived
+	}
+	if publishAt != nil && publishAt.After(time.Now()) {
+		return postStore.SchedulePost(pid, *publishAt)
+	}
+	return publishDraft(post)
+}
+
+// publishDraft 把草稿或定时发布的帖子改为正常并加入索引和排序
+// 只有把状态改成功的实例才会加入索引，加入失败时移除搜索索引，并恢复原来的状态、发布时间及发帖时间，等待下次重试
+func publishDraft(post *models.Post) error {
+	now := time.Now()
+	ok, err := postStore.PublishPost(post.ID, post.Status, now)
+	if err != nil || !ok {
+		return err
+	}
+	orig := *post
+	post.Status, post.PublishAt, post.CreateTime, post.UpdateTime = models.PostStatusNormal, nil, now, now
+	loadPostTags([]*models.Post{post})
+	if err = goLive(post); err != nil {
+		unindexPost(post.ID)
+		if e := postStore.UnpublishPost(&orig); e != nil {
+			zap.L().Error("postStore.UnpublishPost failed", zap.Int64("post_id", post.ID), zap.Error(e))
+		}
+		return err
+	}
+	return nil
+}
+
+// PublishDuePosts 发布发布时间已到的定时帖子
+func PublishDuePosts() error {
+	for {
+		posts, err := postStore.GetDuePosts(time.Now(), publishBatchSize)
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, post := range posts {
+			if err := publishDraft(post); err != nil {
+				zap.L().Error("publish scheduled post failed", zap.Int64("post_id", post.ID), zap.Error(err))
+				failed++
+				continue
+			}
+			zap.L().Info("scheduled post published", zap.Int64("post_id", post.ID))
+		}
+		// 有发布失败的帖子时等下次再重试，避免一直重试同一批帖子
+		if len(posts) < publishBatchSize || failed > 0 {
+			return nil
+		}
+	}
+}
+
+// RunScheduledPublish 后台定期发布定时帖子，多个实例部署时通过锁只让一个实例执行
+// 即使锁过期后多个实例同时执行，帖子状态的修改也保证了每个帖子只发布一次
+func RunScheduledPublish(ctx context.Context, interval time.Duration) {
+	runJob(ctx, "post:publish", interval, PublishDuePosts)
+}
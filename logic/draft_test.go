@@ -0,0 +1,60 @@
+package logic
+
+import (
+	"bell_best/dao/memory"
+	"bell_best/models"
+	"bell_best/pkg/rank"
+	"bell_best/pkg/search"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingVoteStore 加入排序时总是失败的VoteStore，用于测试发布失败时的回滚
+type failingVoteStore struct {
+	*memory.VoteStore
+}
+
+func (failingVoteStore) CreatePost(*models.Post, rank.Ranker) error {
+	return errors.New("redis unavailable")
+}
+
+func TestPublishDraftRollback(t *testing.T) {
+	posts := memory.NewPostStore()
+	index := search.NewIndex()
+	votes := memory.NewVoteStore()
+	Init(&Stores{Post: posts, Search: index, Vote: failingVoteStore{votes}, Timeline: votes, Follow: memory.NewFollowStore()})
+
+	publishAt := time.Now().Add(-time.Minute)
+	if err := posts.CreatePost(&models.Post{ID: 1, AuthorID: 1, CommunityID: 1, Title: "scheduled", Content: "scheduled post",
+		Status: models.PostStatusScheduled, PublishAt: &publishAt}); err != nil {
+		t.Fatal(err)
+	}
+	due, err := posts.GetDuePosts(time.Now(), publishBatchSize)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("GetDuePosts = %v, %v", due, err)
+	}
+	before := *due[0]
+
+	if err := publishDraft(due[0]); err == nil {
+		t.Fatal("publishDraft succeeded, want error")
+	}
+
+	// 回滚后帖子仍是定时发布，下次还能被发布任务查到
+	due, err = posts.GetDuePosts(time.Now(), publishBatchSize)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("after rollback GetDuePosts = %v, %v", due, err)
+	}
+	got := due[0]
+	if got.Status != models.PostStatusScheduled || got.PublishAt == nil || !got.PublishAt.Equal(publishAt) {
+		t.Errorf("status = %d, publish_at = %v, want %d, %v", got.Status, got.PublishAt, models.PostStatusScheduled, publishAt)
+	}
+	if !got.CreateTime.Equal(before.CreateTime) {
+		t.Errorf("create_time = %v, want %v", got.CreateTime, before.CreateTime)
+	}
+	// 搜索索引也要移除
+	ids, total, err := index.SearchPosts(&models.ParamSearch{Q: "scheduled", Page: 1, Size: 10})
+	if err != nil || total != 0 || len(ids) != 0 {
+		t.Errorf("SearchPosts = %v, %d, %v, want no result", ids, total, err)
+	}
+}
@@ -0,0 +1,163 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/setting"
+	"errors"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTimelineSize = 800
+	defaultFanOutLimit  = 1000
+)
+
+var ErrorFollowSelf = errors.New("不能关注自己")
+
+// timelineSize 每条时间线最多保留的帖子数
+func timelineSize() int64 {
+	if cfg := setting.Conf.FollowConfig; cfg != nil && cfg.TimelineSize > 0 {
+		return cfg.TimelineSize
+	}
+	return defaultTimelineSize
+}
+
+// fanOutLimit 粉丝数不超过该值的作者发帖时推送到粉丝的时间线
+func fanOutLimit() int64 {
+	if cfg := setting.Conf.FollowConfig; cfg != nil && cfg.FanOutLimit > 0 {
+		return cfg.FanOutLimit
+	}
+	return defaultFanOutLimit
+}
+
+// FollowUser 关注用户，粉丝不多的作者把已有的帖子合并到当前用户的时间线
+func FollowUser(userID, uid int64) error {
+	if userID == uid {
+		return ErrorFollowSelf
+	}
+	if _, err := userStore.GetUserProfile(uid); err != nil {
+		return err
+	}
+	ok, err := followStore.Follow(userID, uid)
+	if err != nil || !ok {
+		return err
+	}
+	count, err := followStore.GetFollowCount(uid)
+	if err != nil {
+		return err
+	}
+	// 粉丝多的作者在读取时间线时合并，不需要写入
+	if count.Followers > fanOutLimit() {
+		// 刚超过上限时作者改为读取时合并，之前推送的帖子要从粉丝的时间线中移除，否则删帖时不会再清理
+		if count.Followers == fanOutLimit()+1 {
+			switchToPull(uid)
+		}
+		return nil
+	}
+	// 时间线只是缓存，写入失败时只记录日志
+	if err := timelineStore.AddAuthorToTimeline(userID, uid, timelineSize()); err != nil {
+		zap.L().Error("timelineStore.AddAuthorToTimeline failed",
+			zap.Int64("user_id", userID), zap.Int64("author_id", uid), zap.Error(err))
+	}
+	return nil
+}
+
+// UnfollowUser 取消关注，并把作者的帖子从时间线中移除
+func UnfollowUser(userID, uid int64) error {
+	ok, err := followStore.Unfollow(userID, uid)
+	if err != nil || !ok {
+		return err
+	}
+	if err := timelineStore.RemoveAuthorFromTimeline(userID, uid); err != nil {
+		zap.L().Error("timelineStore.RemoveAuthorFromTimeline failed",
+			zap.Int64("user_id", userID), zap.Int64("author_id", uid), zap.Error(err))
+	}
+	count, err := followStore.GetFollowCount(uid)
+	if err != nil {
+		return err
+	}
+	// 刚降到上限时作者改为发帖时推送，读取时不再合并，要把作者的帖子补到粉丝的时间线中
+	if count.Followers == fanOutLimit() {
+		switchToPush(uid)
+	}
+	return nil
+}
+
+// switchToPull 作者改为读取时合并，把作者的帖子从所有粉丝的时间线中移除，失败时只记录日志
+func switchToPull(authorID int64) {
+	ids, err := followStore.GetFollowerIDs(authorID)
+	if err != nil {
+		zap.L().Error("followStore.GetFollowerIDs failed", zap.Int64("author_id", authorID), zap.Error(err))
+		return
+	}
+	for _, id := range ids {
+		if err := timelineStore.RemoveAuthorFromTimeline(id, authorID); err != nil {
+			zap.L().Error("timelineStore.RemoveAuthorFromTimeline failed",
+				zap.Int64("user_id", id), zap.Int64("author_id", authorID), zap.Error(err))
+		}
+	}
+}
+
+// switchToPush 作者改为发帖时推送，把作者的帖子合并到所有粉丝的时间线，失败时只记录日志
+func switchToPush(authorID int64) {
+	ids, err := followStore.GetFollowerIDs(authorID)
+	if err != nil {
+		zap.L().Error("followStore.GetFollowerIDs failed", zap.Int64("author_id", authorID), zap.Error(err))
+		return
+	}
+	for _, id := range ids {
+		if err := timelineStore.AddAuthorToTimeline(id, authorID, timelineSize()); err != nil {
+			zap.L().Error("timelineStore.AddAuthorToTimeline failed",
+				zap.Int64("user_id", id), zap.Int64("author_id", authorID), zap.Error(err))
+		}
+	}
+}
+
+// GetTimeline 按游标分页查询当前用户关注的作者的帖子，按发帖时间倒序
+// 时间线中是发帖时推送的帖子，粉丝多的作者的帖子在这里合并
+func GetTimeline(userID int64, p *models.ParamPostList) (*models.ApiPostList, error) {
+	cursor, err := decodePostCursor(p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	pullIDs, err := followStore.GetFollowingIDs(userID, fanOutLimit()+1)
+	if err != nil {
+		return nil, err
+	}
+	ids, next, err := timelineStore.GetTimelineIDsByCursor(userID, pullIDs, cursor, p.Size)
+	if err != nil {
+		return nil, err
+	}
+	return buildPostList(userID, ids, next)
+}
+
+// getFanOutFollowers 发帖时需要推送的粉丝，粉丝多的作者返回nil
+func getFanOutFollowers(authorID int64) ([]int64, error) {
+	count, err := followStore.GetFollowCount(authorID)
+	if err != nil || count.Followers == 0 || count.Followers > fanOutLimit() {
+		return nil, err
+	}
+	return followStore.GetFollowerIDs(authorID)
+}
+
+// fanOutPost 把刚发布的帖子推送到作者粉丝的时间线，失败时只记录日志
+func fanOutPost(p *models.Post) {
+	ids, err := getFanOutFollowers(p.AuthorID)
+	if err == nil {
+		err = timelineStore.PushTimeline(ids, p, timelineSize())
+	}
+	if err != nil {
+		zap.L().Error("fan out post failed", zap.Int64("post_id", p.ID), zap.Error(err))
+	}
+}
+
+// unfanOutPost 把删除的帖子从作者粉丝的时间线中移除，失败时只记录日志
+func unfanOutPost(p *models.Post) {
+	ids, err := getFanOutFollowers(p.AuthorID)
+	if err == nil {
+		err = timelineStore.RemoveFromTimelines(ids, p.ID)
+	}
+	if err != nil {
+		zap.L().Error("remove post from timelines failed", zap.Int64("post_id", p.ID), zap.Error(err))
+	}
+}
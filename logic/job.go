@@ -0,0 +1,67 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runJob 每隔interval执行一次后台任务fn，直到ctx结束
+// 多实例部署时通过锁保证同一时间只有一个实例执行
+func runJob(ctx context.Context, name string, interval time.Duration, fn func() error) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		runLocked(name, interval, fn)
+	}
+}
+
+// runLocked 获取锁后执行一次fn，执行期间一直持有锁，执行完后释放
+// 锁的有效期为interval，实例在执行中途退出时锁最多保留一个周期
+func runLocked(name string, interval time.Duration, fn func() error) {
+	token, err := lockToken()
+	if err != nil {
+		zap.L().Error("lockToken failed", zap.String("job", name), zap.Error(err))
+		return
+	}
+	ok, err := lockStore.TryLock(name, token, interval)
+	if err != nil {
+		zap.L().Error("lockStore.TryLock failed", zap.String("job", name), zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		// 只删除自己持有的锁，执行超过interval时锁可能已经被其他实例获取
+		if err := lockStore.Unlock(name, token); err != nil {
+			zap.L().Error("lockStore.Unlock failed", zap.String("job", name), zap.Error(err))
+		}
+	}()
+	start := time.Now()
+	if err := fn(); err != nil {
+		zap.L().Error("run job failed", zap.String("job", name), zap.Error(err))
+		return
+	}
+	zap.L().Debug("run job", zap.String("job", name), zap.Duration("cost", time.Since(start)))
+}
+
+// lockToken 生成随机的锁持有者标识
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
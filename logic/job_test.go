@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"bell_best/dao/memory"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunLocked(t *testing.T) {
+	locks := memory.NewLockStore()
+	Init(&Stores{Lock: locks})
+
+	// 执行期间一直持有锁，有效期不短于interval
+	runs := 0
+	runLocked("job", time.Hour, func() error {
+		runs++
+		if ok, _ := locks.TryLock("job", "other", time.Hour); ok {
+			t.Error("lock not held while the job is running")
+		}
+		return nil
+	})
+	if runs != 1 {
+		t.Fatalf("runs = %d, want 1", runs)
+	}
+	// 执行结束后释放锁，执行失败也一样
+	runLocked("job", time.Hour, func() error {
+		runs++
+		return errors.New("failed")
+	})
+	if runs != 2 {
+		t.Fatalf("runs = %d, want 2", runs)
+	}
+	if ok, _ := locks.TryLock("job", "other", time.Hour); !ok {
+		t.Fatal("lock not released after the job")
+	}
+
+	// 锁被其他实例持有时不执行，也不能释放别人的锁
+	runLocked("job", time.Hour, func() error {
+		runs++
+		return nil
+	})
+	if runs != 2 {
+		t.Errorf("job ran while another instance held the lock")
+	}
+	if ok, _ := locks.TryLock("job", "third", time.Hour); ok {
+		t.Error("lock of another instance was released")
+	}
+
+	// 执行超过有效期后锁被其他实例获取，结束时不能删除新的锁
+	runLocked("slow", time.Millisecond, func() error {
+		time.Sleep(5 * time.Millisecond)
+		if ok, _ := locks.TryLock("slow", "other", time.Hour); !ok {
+			t.Error("expired lock was not taken over")
+		}
+		return nil
+	})
+	if ok, _ := locks.TryLock("slow", "third", time.Hour); ok {
+		t.Error("lock taken over by another instance was released")
+	}
+}
@@ -0,0 +1,71 @@
+package logic
+
+import "bell_best/models"
+
+// loader 在一次请求内批量加载用户和社区
+// 先收集需要的id，去重后一次查询，已经加载过的id不会重复查询
+type loader struct {
+	users       map[int64]*models.User
+	communities map[int64]*models.CommunityDetail
+}
+
+func newLoader() *loader {
+	return &loader{
+		users:       make(map[int64]*models.User),
+		communities: make(map[int64]*models.CommunityDetail),
+	}
+}
+
+// loadUsers 批量加载还没有加载过的用户
+func (l *loader) loadUsers(uids []int64) error {
+	missing := make([]int64, 0, len(uids))
+	seen := make(map[int64]struct{}, len(uids))
+	for _, uid := range uids {
+		if _, ok := l.users[uid]; ok {
+			continue
+		}
+		if _, ok := seen[uid]; ok {
+			continue
+		}
+		seen[uid] = struct{}{}
+		missing = append(missing, uid)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	users, err := userStore.GetUsersByIDs(missing)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		l.users[u.UserID] = u
+	}
+	return nil
+}
+
+// loadCommunities 批量加载还没有加载过的社区
+func (l *loader) loadCommunities(ids []int64) error {
+	missing := make([]int64, 0, len(ids))
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := l.communities[id]; ok {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	communities, err := communityStore.GetCommunitiesByIDs(missing)
+	if err != nil {
+		return err
+	}
+	for _, c := range communities {
+		l.communities[c.ID] = c
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package logic
+
+import (
+	"bell_best/setting"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 登录防暴力破解
+// 按用户名和ip分别统计窗口期内的失败次数，超过免费次数后按指数退避暂时禁止登录，
+// 达到锁定阈值时锁定较长时间。用户名登录成功后清零，ip的失败次数不清零，
+// 避免攻击者用自己的账号登录来重置计数
+
+var ErrorLoginLocked = errors.New("登录失败次数过多")
+
+// LoginLockedError 暂时禁止登录，RetryAfter之后才能再次尝试
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s，请%s后再试", ErrorLoginLocked.Error(), e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginLockedError) Is(target error) bool { return target == ErrorLoginLocked }
+
+type loginSubject struct {
+	key   string
+	rule  *setting.LoginGuardRule
+	reset bool // 登录成功后是否清零
+}
+
+// loginSubjects 需要统计失败次数的用户名和ip，没有开启时返回空
+func loginSubjects(username, ip string) []loginSubject {
+	cfg := setting.Conf.LoginGuardConfig
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	subjects := make([]loginSubject, 0, 2)
+	if cfg.Username != nil {
+		subjects = append(subjects, loginSubject{key: "user:" + username, rule: cfg.Username, reset: true})
+	}
+	if cfg.IP != nil && ip != "" {
+		subjects = append(subjects, loginSubject{key: "ip:" + ip, rule: cfg.IP})
+	}
+	return subjects
+}
+
+// checkLoginLocked 用户名或ip被禁止登录时返回LoginLockedError
+func checkLoginLocked(subjects []loginSubject) error {
+	if len(subjects) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		keys = append(keys, s.key)
+	}
+	locks, err := loginStore.GetLoginLocks(keys)
+	if err != nil {
+		return err
+	}
+	var wait time.Duration
+	for _, d := range locks {
+		if d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次登录失败，需要禁止登录时设置禁止的时间
+func recordLoginFailure(subjects []loginSubject, username, ip string) {
+	for _, s := range subjects {
+		failures, err := loginStore.IncrLoginFailures(s.key, s.rule.Window)
+		if err != nil {
+			zap.L().Error("loginStore.IncrLoginFailures failed", zap.String("key", s.key), zap.Error(err))
+			continue
+		}
+		d, lockout := loginDelay(s.rule, failures)
+		if d <= 0 {
+			continue
+		}
+		if err := loginStore.LockLogin(s.key, d); err != nil {
+			zap.L().Error("loginStore.LockLogin failed", zap.String("key", s.key), zap.Error(err))
+			continue
+		}
+		fields := []zap.Field{
+			zap.String("key", s.key),
+			zap.String("username", username),
+			zap.String("ip", ip),
+			zap.Int64("failures", failures),
+			zap.Duration("duration", d),
+		}
+		if lockout {
+			zap.L().Warn("audit: login locked out", fields...)
+		} else {
+			zap.L().Info("audit: login delayed", fields...)
+		}
+	}
+}
+
+// loginDelay 失败failures次之后需要禁止登录多久，lockout表示达到了锁定阈值
+func loginDelay(rule *setting.LoginGuardRule, failures int64) (d time.Duration, lockout bool) {
+	if rule.LockoutThreshold > 0 && failures >= rule.LockoutThreshold {
+		return rule.LockoutDuration, true
+	}
+	n := failures - rule.FreeAttempts
+	if n <= 0 || rule.BaseDelay <= 0 || rule.MaxDelay <= 0 {
+		return 0, false
+	}
+	// 超过免费次数后的第n次失败禁止base_delay*2^(n-1)
+	d = rule.BaseDelay
+	for i := int64(1); i < n && d < rule.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > rule.MaxDelay {
+		d = rule.MaxDelay
+	}
+	return d, false
+}
+
+// resetLoginFailures 登录成功后清除用户名的失败次数
+func resetLoginFailures(subjects []loginSubject) {
+	for _, s := range subjects {
+		if !s.reset {
+			continue
+		}
+		if err := loginStore.ResetLoginFailures(s.key); err != nil {
+			zap.L().Error("loginStore.ResetLoginFailures failed", zap.String("key", s.key), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/markdown"
+	"bell_best/setting"
+	"sync"
+)
+
+const (
+	defaultRenderCacheSize = 1024
+	defaultExcerptRunes    = 140
+)
+
+var (
+	renderCacheOnce sync.Once
+	renderCache     *markdown.Cache
+)
+
+// getRenderCache 按配置创建正文渲染结果的缓存
+func getRenderCache() *markdown.Cache {
+	renderCacheOnce.Do(func() {
+		size, excerpt := defaultRenderCacheSize, defaultExcerptRunes
+		if cfg := setting.Conf.MarkdownConfig; cfg != nil {
+			if cfg.CacheSize > 0 {
+				size = cfg.CacheSize
+			}
+			if cfg.ExcerptLength > 0 {
+				excerpt = cfg.ExcerptLength
+			}
+		}
+		renderCache = markdown.NewCache(size, excerpt)
+	})
+	return renderCache
+}
+
+// renderPostContent 填充帖子正文渲染后的HTML及摘要，按帖子id和正文哈希缓存
+func renderPostContent(detail *models.ApiPostDetail) {
+	r := getRenderCache().Render(detail.Post.ID, detail.Content)
+	detail.ContentHTML = r.HTML
+	detail.Excerpt = r.Excerpt
+}
@@ -1,192 +1,392 @@
 package logic
 
 import (
-	"bell_best/dao/mysql"
-	"bell_best/dao/redis"
 	"bell_best/models"
+	"bell_best/pkg/rank"
 	"bell_best/pkg/snowflake"
+	"errors"
 	"go.uber.org/zap"
+	"strconv"
+	"time"
 )
 
-func CreatePost(p *models.Post) (err error) {
+var ErrorNotPostAuthor = errors.New("不是帖子作者")
+
+// CreatePost 发帖，draft为true时保存为草稿，publish_at为将来的时间时定时发布
+// 草稿和定时发布的帖子只保存到数据库，发布时才加入搜索索引和redis中的各个排序
+func CreatePost(p *models.Post, draft bool) (err error) {
+	// 归档的社区不能再发帖
+	community, err := communityStore.GetCommunityDetailByID(p.CommunityID)
+	if err != nil {
+		return err
+	}
+	if community.Archived {
+		return ErrorCommunityArchived
+	}
+	if p.Tags, err = normalizeTags(p.Tags); err != nil {
+		return err
+	}
 	// 1.生成post id
 	p.ID = snowflake.GenID()
+	p.CreateTime = time.Now()
+	p.UpdateTime = p.CreateTime
+	switch {
+	case draft:
+		p.Status, p.PublishAt = models.PostStatusDraft, nil
+	case p.PublishAt != nil && p.PublishAt.After(p.CreateTime):
+		p.Status = models.PostStatusScheduled
+	default:
+		p.Status, p.PublishAt = models.PostStatusNormal, nil
+	}
 	// 2.保存到数据库
-	err = mysql.CreatePost(p)
+	err = postStore.CreatePost(p)
 	if err != nil {
 		return err
 	}
-	err = redis.CreatePost(p.ID, p.CommunityID)
-	return
+	linkPostUploads(p)
+	if p.Status != models.PostStatusNormal {
+		return nil
+	}
+	return goLive(p)
 	//3.返回
 }
 
-// GetPostByID 根据帖子id查询帖子详情数据
-func GetPostByID(pid int64) (data *models.ApiPostDetail, err error) {
+// goLive 把刚发布的帖子加入搜索索引及redis中的时间、分数、社区、作者和标签排序，帖子从这时起出现在列表中
+// 同时推送到作者粉丝的时间线
+func goLive(p *models.Post) error {
+	indexPost(p)
+	if err := voteStore.CreatePost(p, rank.For(p.CommunityID)); err != nil {
+		return err
+	}
+	fanOutPost(p)
+	return nil
+}
+
+// GetPostByID 根据帖子id查询帖子详情数据，userID为当前登录的用户，未登录时为0
+func GetPostByID(userID, pid int64) (data *models.ApiPostDetail, err error) {
 	// 查询并组合我们接口想要的数据
-	post, err := mysql.GetPostByID(pid)
+	post, err := postStore.GetPostByID(pid)
 	if err != nil {
-		zap.L().Error("mysql.GetPostByID(pid) fail ed", zap.Error(err))
+		zap.L().Error("postStore.GetPostByID(pid) failed", zap.Error(err))
 		return
 	}
-	// 根据作者id查询作者信息
-	user, err := mysql.GetUserByID(post.AuthorID)
+	list, err := buildPostDetails(userID, []*models.Post{post})
 	if err != nil {
-		zap.L().Error("mysql.GetUserByID(pid) failed", zap.Int64("user_id", post.AuthorID))
-		return
+		return nil, err
+	}
+	// 作者或社区不存在
+	if len(list) == 0 {
+		return nil, models.ErrorInvalidID
 	}
-	// 根据社区id查询社区详情信息
-	community, err := mysql.GetCommunityDetailByID(post.CommunityID)
+	return list[0], nil
+}
+
+// getCommentNum 查询每篇帖子的评论数，查询失败时只记录日志，不影响帖子数据的返回
+func getCommentNum(posts []*models.Post) map[int64]int64 {
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	data, err := commentStore.GetCommentNumByPostIDs(ids)
 	if err != nil {
-		zap.L().Error("mysql.GetCommunityDetailByID(pid) failed",
-			zap.Int64("community_id", post.CommunityID),
-			zap.Error(err))
-		return
+		zap.L().Error("commentStore.GetCommentNumByPostIDs(ids) failed", zap.Error(err))
+		return map[int64]int64{}
+	}
+	return data
+}
+
+// UpdatePost 编辑帖子，只有作者本人可以编辑，草稿和定时发布的帖子也可以编辑
+func UpdatePost(userID, pid int64, p *models.ParamUpdatePost) (err error) {
+	post, err := getOwnPost(userID, pid)
+	if err != nil {
+		return err
 	}
-	data = &models.ApiPostDetail{
-		AuthorName:      user.Username,
-		Post:            post,
-		CommunityDetail: community,
+	if post.AuthorID != userID {
+		return ErrorNotPostAuthor
 	}
-	return
+	// 标题和内容都没有变化时不保存新版本
+	if post.Title == p.Title && post.Content == p.Content {
+		return nil
+	}
+	post.Title = p.Title
+	post.Content = p.Content
+	return savePost(userID, post)
 }
 
-// GetPostList 获取帖子列表
-func GetPostList(page, size int64) (data []*models.ApiPostDetail, err error) {
-	posts, err := mysql.GetPostList(page, size)
+// savePost 保存修改后的标题和内容，保存为userID修改的新版本
+func savePost(userID int64, post *models.Post) error {
+	post.UpdateTime = time.Now()
+	if err := postStore.UpdatePost(post, userID); err != nil {
+		return err
+	}
+	linkPostUploads(post)
+	if post.Status == models.PostStatusNormal {
+		indexPost(post)
+	}
+	return nil
+}
+
+// getOwnPost 查询已发布的帖子，不存在时再查询用户自己的草稿，别人的草稿视为不存在
+func getOwnPost(userID, pid int64) (*models.Post, error) {
+	post, err := postStore.GetPostByID(pid)
+	if !errors.Is(err, models.ErrorPostNotExist) {
+		return post, err
+	}
+	draft, err := postStore.GetDraftByID(pid)
 	if err != nil {
 		return nil, err
 	}
-	data = make([]*models.ApiPostDetail, 0, len(posts))
-	for _, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserByID(post.AuthorID)
+	if draft.AuthorID != userID {
+		return nil, models.ErrorPostNotExist
+	}
+	return draft, nil
+}
+
+// DeletePost 软删除帖子，作者本人、全站版主、管理员及社区版主可以删除，role为当前用户的角色
+func DeletePost(userID int64, role string, pid int64) (err error) {
+	post, err := getOwnPost(userID, pid)
+	if err != nil {
+		return err
+	}
+	if post.AuthorID != userID {
+		ok, err := canModerate(userID, role, post.CommunityID)
 		if err != nil {
-			zap.L().Error("mysql.GetUserByID(pid) failed", zap.Int64("user_id", post.AuthorID))
-			continue
+			return err
 		}
-		// 根据社区id查询社区详情信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetailByID(pid) failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
+		if !ok {
+			return ErrorNotPostAuthor
 		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
+		zap.L().Info("audit: post removed by moderator",
+			zap.Int64("user_id", userID), zap.Int64("post_id", pid), zap.Int64("community_id", post.CommunityID))
 	}
-	return
+	if err = postStore.DeletePost(pid); err != nil {
+		return err
+	}
+	unlinkPostUploads(post)
+	// 草稿还没有加入索引和排序
+	if post.Status != models.PostStatusNormal {
+		return nil
+	}
+	unindexPost(pid)
+	unfanOutPost(post)
+	// 从redis的时间、分数、社区、作者及标签排序中移除，列表中就不会再出现该帖子
+	loadPostTags([]*models.Post{post})
+	return voteStore.RemovePost(post)
+}
+
+// GetPostList 获取帖子列表
+func GetPostList(userID, page, size int64) (data []*models.ApiPostDetail, err error) {
+	posts, err := postStore.GetPostList(page, size)
+	if err != nil {
+		return nil, err
+	}
+	return buildPostDetails(userID, posts)
 }
 
-func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+func GetPostList2(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
 	// 2. 去redis查询id列表
-	ids, err := redis.GetPostIDsInOrder(p)
+	ids, err := voteStore.GetPostIDsInOrder(p)
 	if err != nil {
 		return
 	}
 	if len(ids) == 0 {
-		zap.L().Warn("redis.GetPostIDsInOrder(p) return 0 data")
+		zap.L().Warn("voteStore.GetPostIDsInOrder(p) return 0 data")
 		return
 	}
-	// 3. 根据id去数据库查询帖子详细信息
-	// 返回的数据还要按照我给定的id顺序返回
-	posts, err := mysql.GetPostListByIDs(ids)
+	return getPostDetailsByIDs(userID, ids)
+}
+
+// GetTagPostList 按标签获取帖子列表，可同时按社区过滤
+func GetTagPostList(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	ids, err := voteStore.GetTagPostIDsInOrder(p)
 	if err != nil {
 		return
 	}
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
+	if len(ids) == 0 {
+		return
+	}
+	return getPostDetailsByIDs(userID, ids)
+}
+
+func GetCommunityPostList(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	// 2. 去redis查询id列表
+	ids, err := voteStore.GetCommunityPostIDsInOrder(p)
 	if err != nil {
 		return
 	}
-	// 将帖子的作者及分区信息查询出来填充到帖子中
-	for idx, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID(pid) failed", zap.Int64("user_id", post.AuthorID))
-			continue
-		}
-		// 根据社区id查询社区详情信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetailByID(pid) failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			VoteNum:         voteData[idx],
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
+	if len(ids) == 0 {
+		zap.L().Warn("voteStore.GetPostIDsInOrder(p) return 0 data")
+		return
 	}
-	return
+	return getPostDetailsByIDs(userID, ids)
 }
 
-func GetCommunityPostList(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 2. 去redis查询id列表
-	ids, err := redis.GetCommunityPostIDsInOrder(p)
+// GetSubscribedPostList 按时间或分数分页查询用户订阅的社区的帖子
+func GetSubscribedPostList(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	cids, err := communityStore.GetSubscribedCommunityIDs(userID)
+	if err != nil || len(cids) == 0 {
+		return
+	}
+	ids, err := voteStore.GetCommunitiesPostIDsInOrder(p, cids)
 	if err != nil {
 		return
 	}
 	if len(ids) == 0 {
-		zap.L().Warn("redis.GetPostIDsInOrder(p) return 0 data")
 		return
 	}
+	return getPostDetailsByIDs(userID, ids)
+}
+
+// getSubscribedPostIDsByCursor 按游标查询用户订阅的社区的帖子id，没有订阅社区时返回空
+func getSubscribedPostIDsByCursor(userID int64, p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error) {
+	cids, err := communityStore.GetSubscribedCommunityIDs(userID)
+	if err != nil || len(cids) == 0 {
+		return nil, nil, err
+	}
+	return voteStore.GetCommunitiesPostIDsByCursor(p, cids, cursor)
+}
+
+// getPostDetailsByIDs 按给定的id顺序查询帖子详情
+func getPostDetailsByIDs(userID int64, ids []string) (data []*models.ApiPostDetail, err error) {
 	// 3. 根据id去数据库查询帖子详细信息
 	// 返回的数据还要按照我给定的id顺序返回
-	posts, err := mysql.GetPostListByIDs(ids)
+	posts, err := postStore.GetPostListByIDs(ids)
 	if err != nil {
 		return
 	}
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
-	if err != nil {
+	return buildPostDetails(userID, posts)
+}
+
+// buildPostDetails 填充帖子的作者、社区、标签、投票及评论数据，所有帖子列表和详情都走这里
+// 作者和社区按去重后的id批量查询，一页不论多少帖子，查询次数都是固定的
+func buildPostDetails(userID int64, posts []*models.Post) (data []*models.ApiPostDetail, err error) {
+	data = make([]*models.ApiPostDetail, 0, len(posts))
+	if len(posts) == 0 {
 		return
 	}
-	// 将帖子的作者及分区信息查询出来填充到帖子中
+	ids := make([]string, 0, len(posts))
+	uids := make([]int64, 0, len(posts))
+	cids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, strconv.FormatInt(post.ID, 10))
+		uids = append(uids, post.AuthorID)
+		cids = append(cids, post.CommunityID)
+	}
+	l := newLoader()
+	if err = l.loadUsers(uids); err != nil {
+		return nil, err
+	}
+	if err = l.loadCommunities(cids); err != nil {
+		return nil, err
+	}
+	// 提前查询好每篇帖子的投票数据
+	voteData, err := getPostVoteData(userID, posts, ids)
+	if err != nil {
+		return nil, err
+	}
+	commentData := getCommentNum(posts)
+	loadPostTags(posts)
+	// 将帖子的作者及分区信息填充到帖子中
 	for idx, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID(pid) failed", zap.Int64("user_id", post.AuthorID))
+		user, ok := l.users[post.AuthorID]
+		if !ok {
+			zap.L().Error("author of post not found", zap.Int64("post_id", post.ID), zap.Int64("user_id", post.AuthorID))
 			continue
 		}
-		// 根据社区id查询社区详情信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetailByID(pid) failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
+		community, ok := l.communities[post.CommunityID]
+		if !ok {
+			zap.L().Error("community of post not found", zap.Int64("post_id", post.ID), zap.Int64("community_id", post.CommunityID))
 			continue
 		}
-		postDetail := &models.ApiPostDetail{
+		vote := voteData[idx]
+		detail := &models.ApiPostDetail{
 			AuthorName:      user.Username,
-			VoteNum:         voteData[idx],
+			VoteNum:         vote.UpVotes,
+			UpVotes:         vote.UpVotes,
+			DownVotes:       vote.DownVotes,
+			Score:           vote.UpVotes - vote.DownVotes,
+			CommentNum:      commentData[post.ID],
 			Post:            post,
 			CommunityDetail: community,
 		}
-		data = append(data, postDetail)
+		renderPostContent(detail)
+		if userID != 0 {
+			detail.MyVote = &vote.MyVote
+		}
+		data = append(data, detail)
 	}
 	return
 }
 
+// GetPostListByCursor 按游标分页获取帖子列表，可按社区及标签过滤
+// 游标记录上一页最后一个帖子的分数和id，翻页期间有新帖子或新投票也不会重复或遗漏
+func GetPostListByCursor(userID int64, p *models.ParamPostList) (data *models.ApiPostList, err error) {
+	cursor, err := decodePostCursor(p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if p.Tag != "" {
+		if p.Tag, err = normalizeTag(p.Tag); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		ids  []string
+		next *models.PostCursor
+	)
+	switch {
+	case p.Subscribed:
+		ids, next, err = getSubscribedPostIDsByCursor(userID, p, cursor)
+	case p.Tag != "":
+		ids, next, err = voteStore.GetTagPostIDsByCursor(p, cursor)
+	case p.CommunityID == 0:
+		ids, next, err = voteStore.GetPostIDsByCursor(p, cursor)
+	default:
+		ids, next, err = voteStore.GetCommunityPostIDsByCursor(p, cursor)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buildPostList(userID, ids, next)
+}
+
+// buildPostList 根据帖子id及下一页的游标构造按游标分页的帖子列表
+func buildPostList(userID int64, ids []string, next *models.PostCursor) (data *models.ApiPostList, err error) {
+	data = &models.ApiPostList{
+		Posts:      make([]*models.ApiPostDetail, 0, len(ids)),
+		NextCursor: encodePostCursor(next),
+	}
+	if len(ids) == 0 {
+		return
+	}
+	posts, err := getPostDetailsByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	data.Posts = append(data.Posts, posts...)
+	return
+}
+
 // GetPostListNew 将两个查询逻辑合二为一的函数
-func GetPostListNew(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	if p.CommunityID == 0 {
+func GetPostListNew(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	if p.Tag != "" {
+		if p.Tag, err = normalizeTag(p.Tag); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case p.Subscribed:
+		// 订阅的社区
+		data, err = GetSubscribedPostList(userID, p)
+	case p.Tag != "":
+		// 根据标签查询，可同时按社区过滤
+		data, err = GetTagPostList(userID, p)
+	case p.CommunityID == 0:
 		// 查所有
-		data, err = GetPostList2(p)
-	} else {
+		data, err = GetPostList2(userID, p)
+	default:
 		// 根据社区id查询
-		data, err = GetCommunityPostList(p)
+		data, err = GetCommunityPostList(userID, p)
 	}
 	if err != nil {
 		zap.L().Error("GetPostListNew failed", zap.Error(err))
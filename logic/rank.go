@@ -0,0 +1,26 @@
+package logic
+
+import (
+	"bell_best/pkg/rank"
+	"context"
+	"time"
+)
+
+// RecomputePostScores 按每个社区配置的排序算法重新计算投票期内帖子的分数
+func RecomputePostScores() error {
+	communities, err := communityStore.GetCommunityList()
+	if err != nil {
+		return err
+	}
+	for _, c := range communities {
+		if err := voteStore.RecomputeScores(c.ID, rank.For(c.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunScoreRecompute 每隔interval重新计算一次帖子的分数，直到ctx结束
+func RunScoreRecompute(ctx context.Context, interval time.Duration) {
+	runJob(ctx, "rank:recompute", interval, RecomputePostScores)
+}
@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"bell_best/dao/memory"
+	"bell_best/models"
+	"bell_best/pkg/rank"
+	"bell_best/setting"
+	"reflect"
+	"testing"
+)
+
+func TestCommunityRankers(t *testing.T) {
+	// 默认使用simple算法，社区2使用wilson算法，两种算法的分数量级相差很大
+	err := rank.Init(&setting.RankConfig{
+		Default:     &setting.RankerConfig{Algorithm: rank.AlgSimple},
+		Communities: []*setting.RankerConfig{{CommunityID: 2, Algorithm: rank.AlgWilson}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rank.Init(nil) })
+	votes := memory.NewVoteStore()
+	Init(&Stores{
+		Vote:      votes,
+		Community: memory.NewCommunityStore(&models.CommunityDetail{ID: 1, Name: "a"}, &models.CommunityDetail{ID: 2, Name: "b"}),
+	})
+	for _, p := range []*models.Post{{ID: 1, CommunityID: 1}, {ID: 2, CommunityID: 2}, {ID: 3, CommunityID: 2}} {
+		if err := votes.CreatePost(p, rank.For(p.CommunityID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := votes.VoteForPost("100", "2", 2, 1, rank.For(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := votes.VoteForPost("101", "3", 2, -1, rank.For(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() {
+		t.Helper()
+		// 全站按默认算法的分数排序，社区2的帖子不会因为wilson的分数小于1而排到最后
+		ids, err := votes.GetPostIDsInOrder(&models.ParamPostList{Page: 1, Size: 10, Order: models.OrderScore})
+		if err != nil || !reflect.DeepEqual(ids, []string{"2", "1", "3"}) {
+			t.Errorf("site order = %v, %v, want [2 1 3]", ids, err)
+		}
+		ids, err = votes.GetCommunitiesPostIDsInOrder(&models.ParamPostList{Page: 1, Size: 10, Order: models.OrderScore}, []int64{1, 2})
+		if err != nil || !reflect.DeepEqual(ids, []string{"2", "1", "3"}) {
+			t.Errorf("communities order = %v, %v, want [2 1 3]", ids, err)
+		}
+		// 社区内按社区的算法排序
+		ids, err = votes.GetCommunityPostIDsInOrder(&models.ParamPostList{Page: 1, Size: 10, Order: models.OrderScore, CommunityID: 2})
+		if err != nil || !reflect.DeepEqual(ids, []string{"2", "3"}) {
+			t.Errorf("community order = %v, %v, want [2 3]", ids, err)
+		}
+	}
+	check()
+	if err := RecomputePostScores(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
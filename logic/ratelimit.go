@@ -0,0 +1,16 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/setting"
+)
+
+// AllowRequest 按限流规则从key对应的令牌桶中取一个令牌
+func AllowRequest(key string, rule *setting.RateLimitRule) (*models.RateLimitResult, error) {
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Limit
+	}
+	rate := float64(rule.Limit) / rule.Period.Seconds()
+	return rateLimitStore.TakeToken(key, rate, burst)
+}
@@ -0,0 +1,113 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/diff"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+)
+
+// diff中每处修改前后保留的行数
+const revisionDiffContext = 3
+
+var ErrorNotModerator = errors.New("没有管理权限")
+
+// GetPostRevisions 按版本号倒序分页查询已发布帖子的历史版本
+func GetPostRevisions(pid, page, size int64) ([]*models.PostRevision, error) {
+	if _, err := postStore.GetPostByID(pid); err != nil {
+		return nil, err
+	}
+	revs, err := postStore.GetPostRevisions(pid, page, size)
+	if err != nil {
+		return nil, err
+	}
+	if err = loadEditorNames(revs...); err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+// GetPostRevision 查询帖子的第rev个版本及与上一个版本的diff，第1个版本与空内容比较
+func GetPostRevision(pid, rev int64) (*models.ApiPostRevision, error) {
+	if _, err := postStore.GetPostByID(pid); err != nil {
+		return nil, err
+	}
+	r, err := postStore.GetPostRevision(pid, rev)
+	if err != nil {
+		return nil, err
+	}
+	oldName, oldText := "/dev/null", ""
+	if rev > 1 {
+		prev, err := postStore.GetPostRevision(pid, rev-1)
+		if err != nil {
+			return nil, err
+		}
+		oldName, oldText = revisionName(prev), revisionText(prev)
+	}
+	if err = loadEditorNames(r); err != nil {
+		return nil, err
+	}
+	return &models.ApiPostRevision{
+		PostRevision: r,
+		Diff:         diff.Unified(oldName, revisionName(r), oldText, revisionText(r), revisionDiffContext),
+	}, nil
+}
+
+// RollbackPost 把帖子的标题和内容恢复为第rev个版本，全站版主、管理员及社区版主可以操作
+// 恢复不会删除之后的版本，而是把恢复后的内容保存为一个新版本
+func RollbackPost(userID int64, role string, pid, rev int64) error {
+	post, err := postStore.GetPostByID(pid)
+	if err != nil {
+		return err
+	}
+	ok, err := canModerate(userID, role, post.CommunityID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorNotModerator
+	}
+	r, err := postStore.GetPostRevision(pid, rev)
+	if err != nil {
+		return err
+	}
+	if post.Title == r.Title && post.Content == r.Content {
+		return nil
+	}
+	post.Title = r.Title
+	post.Content = r.Content
+	if err = savePost(userID, post); err != nil {
+		return err
+	}
+	zap.L().Info("audit: post rolled back by moderator",
+		zap.Int64("user_id", userID), zap.Int64("post_id", pid), zap.Int64("revision", rev))
+	return nil
+}
+
+// revisionText 比较版本时第一行是标题，空一行后是正文
+func revisionText(r *models.PostRevision) string {
+	return r.Title + "\n\n" + r.Content
+}
+
+func revisionName(r *models.PostRevision) string {
+	return fmt.Sprintf("revision %d", r.Revision)
+}
+
+// loadEditorNames 填充版本修改者的用户名
+func loadEditorNames(revs ...*models.PostRevision) error {
+	uids := make([]int64, 0, len(revs))
+	for _, r := range revs {
+		uids = append(uids, r.EditorID)
+	}
+	l := newLoader()
+	if err := l.loadUsers(uids); err != nil {
+		return err
+	}
+	for _, r := range revs {
+		if user, ok := l.users[r.EditorID]; ok {
+			r.EditorName = user.Username
+		}
+	}
+	return nil
+}
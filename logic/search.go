@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/markdown"
+	"bell_best/pkg/search"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// 正文摘要的最大字符数
+const snippetRunes = 120
+
+var ErrorEmptyQuery = errors.New("搜索词为空")
+
+// SearchPosts 全文搜索帖子，返回命中的帖子及高亮的标题、正文摘要
+func SearchPosts(userID int64, p *models.ParamSearch) (*models.ApiSearchResult, error) {
+	terms := search.QueryTerms(p.Q)
+	if len(terms) == 0 {
+		return nil, ErrorEmptyQuery
+	}
+	ids, total, err := searchStore.SearchPosts(p)
+	if err != nil {
+		return nil, err
+	}
+	data := &models.ApiSearchResult{
+		Total: total,
+		Hits:  make([]*models.ApiSearchHit, 0, len(ids)),
+	}
+	if len(ids) == 0 {
+		return data, nil
+	}
+	posts, err := getPostDetailsByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		data.Hits = append(data.Hits, &models.ApiSearchHit{
+			ApiPostDetail:    post,
+			TitleHighlight:   search.Highlight(post.Title, terms),
+			ContentHighlight: search.Snippet(markdown.PlainText(post.Content), terms, snippetRunes),
+		})
+	}
+	return data, nil
+}
+
+// RebuildSearchIndex 从数据库重建搜索索引，用于启动时加载进程内的倒排索引
+func RebuildSearchIndex() (n int, err error) {
+	const size = 500
+	for page := int64(1); ; page++ {
+		posts, err := postStore.GetPostList(page, size)
+		if err != nil {
+			return n, err
+		}
+		for _, p := range posts {
+			if err := searchStore.IndexPost(p); err != nil {
+				return n, err
+			}
+		}
+		n += len(posts)
+		if len(posts) < size {
+			return n, nil
+		}
+	}
+}
+
+// indexPost 更新帖子的搜索索引，失败时只记录日志，不影响帖子的保存
+func indexPost(p *models.Post) {
+	if err := searchStore.IndexPost(p); err != nil {
+		zap.L().Error("searchStore.IndexPost failed", zap.Int64("post_id", p.ID), zap.Error(err))
+	}
+}
+
+// unindexPost 删除帖子的搜索索引，失败时只记录日志
+func unindexPost(pid int64) {
+	if err := searchStore.RemovePost(pid); err != nil {
+		zap.L().Error("searchStore.RemovePost failed", zap.Int64("post_id", pid), zap.Error(err))
+	}
+}
@@ -0,0 +1,238 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/rank"
+	"time"
+)
+
+// logic层不直接依赖dao/mysql和dao/redis的包级函数，
+// 而是通过下面几个存储接口访问数据，启动时由main注入具体实现
+// 线上使用mysql/redis，测试时可以注入dao/memory中的内存实现
+
+// PostStore 帖子数据的存储
+type PostStore interface {
+	CreatePost(p *models.Post) error
+	GetPostByID(pid int64) (*models.Post, error)
+	GetPostList(page, size int64) ([]*models.Post, error)
+	GetPostListByIDs(ids []string) ([]*models.Post, error)
+	GetPostTags(pids []int64) (map[int64][]string, error)
+	// UpdatePost 修改帖子的标题和内容，同时保存为editorID修改的新版本
+	UpdatePost(p *models.Post, editorID int64) error
+	DeletePost(pid int64) error
+	// 草稿及定时发布
+	GetDraftByID(pid int64) (*models.Post, error)
+	GetDraftList(authorID, page, size int64) ([]*models.Post, error)
+	GetDuePosts(now time.Time, size int64) ([]*models.Post, error)
+	SchedulePost(pid int64, publishAt time.Time) error
+	// PublishPost 把状态为from的帖子改为正常，发帖时间改为t，返回是否修改
+	PublishPost(pid int64, from int32, t time.Time) (bool, error)
+	// UnpublishPost 把正常的帖子改回发布前p的状态、定时发布时间及发帖时间，用于发布失败时回滚
+	UnpublishPost(p *models.Post) error
+	// 历史版本，版本号从1开始，创建帖子时保存第1个版本
+	GetPostRevisions(pid, page, size int64) ([]*models.PostRevision, error)
+	GetPostRevision(pid, rev int64) (*models.PostRevision, error)
+}
+
+// UserStore 用户数据的存储
+type UserStore interface {
+	CheckUserExist(username string) error
+	InsertUser(user *models.User) error
+	Login(user *models.User) error
+	GetUserByID(uid int64) (*models.User, error)
+	GetUsersByIDs(uids []int64) ([]*models.User, error)
+	GetUserByUsername(username string) (*models.User, error)
+	UpdateUserRole(uid int64, role string) error
+	GetUserProfile(uid int64) (*models.User, error)
+	UpdateUserProfile(user *models.User) error
+}
+
+// CommunityStore 社区数据的存储
+type CommunityStore interface {
+	GetCommunityList() ([]*models.Community, error)
+	GetCommunityDetailByID(id int64) (*models.CommunityDetail, error)
+	GetCommunitiesByIDs(ids []int64) ([]*models.CommunityDetail, error)
+	CreateCommunity(c *models.CommunityDetail) error
+	UpdateCommunity(c *models.CommunityDetail) error
+	GetCommunityModerators(communityID int64) ([]int64, error)
+	IsCommunityModerator(communityID, uid int64) (bool, error)
+	AddCommunityModerator(communityID, uid int64) error
+	RemoveCommunityModerator(communityID, uid int64) error
+	// SubscribeCommunity 订阅社区并增加订阅人数，已经订阅时返回false
+	SubscribeCommunity(communityID, uid int64) (bool, error)
+	// UnsubscribeCommunity 取消订阅并减少订阅人数，没有订阅时返回false
+	UnsubscribeCommunity(communityID, uid int64) (bool, error)
+	GetSubscribedCommunityIDs(uid int64) ([]int64, error)
+}
+
+// CommentStore 评论数据的存储
+type CommentStore interface {
+	CreateComment(c *models.Comment) error
+	GetCommentByID(cid int64) (*models.Comment, error)
+	GetCommentList(postID, cursor, size int64) ([]*models.Comment, error)
+	GetRootCommentList(postID, cursor, size int64) ([]*models.Comment, error)
+	GetCommentsByRootIDs(rootIDs []int64) ([]*models.Comment, error)
+	GetCommentNumByPostIDs(postIDs []int64) (map[int64]int64, error)
+}
+
+// VoteStore 帖子投票及按时间/分数排序的存储
+type VoteStore interface {
+	CreatePost(p *models.Post, r rank.Ranker) error
+	RemovePost(p *models.Post) error
+	VoteForPost(userID, postID string, communityID int64, value float64, r rank.Ranker) error
+	RecomputeScores(communityID int64, r rank.Ranker) error
+	GetPostIDsInOrder(p *models.ParamPostList) ([]string, error)
+	GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error)
+	GetPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error)
+	GetCommunityPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error)
+	// 多个社区的帖子合并后按时间或分数排序，用于订阅的社区的帖子列表
+	GetCommunitiesPostIDsInOrder(p *models.ParamPostList, communityIDs []int64) ([]string, error)
+	GetCommunitiesPostIDsByCursor(p *models.ParamPostList, communityIDs []int64, cursor *models.PostCursor) ([]string, *models.PostCursor, error)
+	GetTagPostIDsInOrder(p *models.ParamPostList) ([]string, error)
+	GetTagPostIDsByCursor(p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error)
+	GetTrendingTags(window time.Duration, size int64) ([]*models.TagCount, error)
+	GetAuthorPostIDsByCursor(authorID int64, p *models.ParamPostList, cursor *models.PostCursor) ([]string, *models.PostCursor, error)
+	GetPostVoteData(ids []string, userID string) ([]*models.PostVoteData, error)
+	GetVoteExpiredPostIDs(limit int64) ([]string, float64, error)
+	GetPostVotes(ids []string) ([]*models.PostVotes, error)
+	ArchivePostVotes(ids []string, until float64) error
+}
+
+// FollowStore 用户之间的关注关系
+type FollowStore interface {
+	// Follow 关注用户，已经关注时返回false
+	Follow(followerID, followeeID int64) (bool, error)
+	// Unfollow 取消关注，没有关注时返回false
+	Unfollow(followerID, followeeID int64) (bool, error)
+	GetFollowerIDs(uid int64) ([]int64, error)
+	// GetFollowingIDs 查询uid关注的、粉丝数不少于minFollowers的用户
+	GetFollowingIDs(uid, minFollowers int64) ([]int64, error)
+	GetFollowCount(uid int64) (*models.FollowCount, error)
+}
+
+// TimelineStore 用户关注的作者的帖子组成的时间线
+type TimelineStore interface {
+	PushTimeline(userIDs []int64, p *models.Post, size int64) error
+	RemoveFromTimelines(userIDs []int64, pid int64) error
+	AddAuthorToTimeline(userID, authorID, size int64) error
+	RemoveAuthorFromTimeline(userID, authorID int64) error
+	// GetTimelineIDsByCursor 按游标查询时间线，pullAuthorIDs中作者的帖子在读取时合并
+	GetTimelineIDsByCursor(userID int64, pullAuthorIDs []int64, cursor *models.PostCursor, size int64) ([]string, *models.PostCursor, error)
+}
+
+// SearchStore 帖子全文搜索的索引
+type SearchStore interface {
+	IndexPost(p *models.Post) error
+	RemovePost(pid int64) error
+	SearchPosts(p *models.ParamSearch) (ids []string, total int64, err error)
+}
+
+// UploadStore 上传图片的记录，用于关联帖子和清理没有被引用的图片
+type UploadStore interface {
+	CreateUpload(u *models.Upload) error
+	// SetPostUploads 把作者上传的ids关联到帖子，帖子之前关联但不在ids中的图片取消关联
+	SetPostUploads(userID, postID int64, ids []int64) error
+	// GetOrphanUploads 查询没有帖子引用且before之前就没有变化的图片
+	GetOrphanUploads(before time.Time, size int64) ([]*models.Upload, error)
+	// DeleteOrphanUpload 删除仍然没有帖子引用的图片记录，返回是否删除
+	DeleteOrphanUpload(id int64) (bool, error)
+}
+
+// BlobStore 上传文件的存储，key为相对路径
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// VoteArchiveStore 投票期结束后帖子票数的归档存储
+type VoteArchiveStore interface {
+	SavePostVotes(votes []*models.PostVotes) error
+	GetPostVotesByIDs(postIDs []int64) (map[int64]*models.PostVotes, error)
+}
+
+// TokenStore refresh token及access token黑名单的存储
+type TokenStore interface {
+	SaveAccessToken(userID int64, tokenID string, expire time.Duration) error
+	SaveRefreshToken(token string, s *models.RefreshSession, expire time.Duration) error
+	TakeRefreshToken(token string) (*models.RefreshSession, error)
+	RemoveRefreshToken(userID int64, token string) error
+	DenyToken(tokenID string, expire time.Duration) error
+	IsTokenDenied(tokenID string) (bool, error)
+	RevokeUserTokens(userID int64, expire time.Duration) error
+}
+
+// LockStore 多实例部署时后台任务使用的锁
+type LockStore interface {
+	TryLock(name, token string, ttl time.Duration) (bool, error)
+	Unlock(name, token string) error
+}
+
+// RateLimitStore 限流的令牌桶存储
+type RateLimitStore interface {
+	TakeToken(key string, rate float64, burst int64) (*models.RateLimitResult, error)
+}
+
+// LoginGuardStore 登录失败次数及禁止登录的存储
+type LoginGuardStore interface {
+	GetLoginLocks(keys []string) ([]time.Duration, error)
+	IncrLoginFailures(key string, window time.Duration) (int64, error)
+	LockLogin(key string, d time.Duration) error
+	ResetLoginFailures(key string) error
+}
+
+// Stores logic层依赖的全部存储
+type Stores struct {
+	Post        PostStore
+	User        UserStore
+	Community   CommunityStore
+	Comment     CommentStore
+	Vote        VoteStore
+	VoteArchive VoteArchiveStore
+	Token       TokenStore
+	Lock        LockStore
+	RateLimit   RateLimitStore
+	LoginGuard  LoginGuardStore
+	Search      SearchStore
+	Upload      UploadStore
+	Blob        BlobStore
+	Follow      FollowStore
+	Timeline    TimelineStore
+}
+
+var (
+	postStore      PostStore
+	userStore      UserStore
+	communityStore CommunityStore
+	commentStore   CommentStore
+	voteStore      VoteStore
+	archiveStore   VoteArchiveStore
+	tokenStore     TokenStore
+	lockStore      LockStore
+	rateLimitStore RateLimitStore
+	loginStore     LoginGuardStore
+	searchStore    SearchStore
+	uploadStore    UploadStore
+	blobStore      BlobStore
+	followStore    FollowStore
+	timelineStore  TimelineStore
+)
+
+// Init 注入logic层使用的存储实现
+func Init(s *Stores) {
+	postStore = s.Post
+	userStore = s.User
+	communityStore = s.Community
+	commentStore = s.Comment
+	voteStore = s.Vote
+	archiveStore = s.VoteArchive
+	tokenStore = s.Token
+	lockStore = s.Lock
+	rateLimitStore = s.RateLimit
+	loginStore = s.LoginGuard
+	searchStore = s.Search
+	uploadStore = s.Upload
+	blobStore = s.Blob
+	followStore = s.Follow
+	timelineStore = s.Timeline
+}
@@ -0,0 +1,91 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/setting"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxTags        = 5
+	defaultTrendingWindow = 24 * time.Hour
+	maxTagRunes           = 32
+)
+
+var ErrorInvalidTag = errors.New("无效的标签")
+
+// maxTags 每个帖子最多的标签数
+func maxTags() int {
+	if cfg := setting.Conf.TagConfig; cfg != nil && cfg.MaxPerPost > 0 {
+		return cfg.MaxPerPost
+	}
+	return defaultMaxTags
+}
+
+// normalizeTag 去掉首尾空白并转成小写，标签只能包含文字、数字及-_+#.
+// 标签会作为redis key的一部分，不允许出现冒号等分隔符
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagRunes {
+		return "", ErrorInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_+#.", r) {
+			return "", ErrorInvalidTag
+		}
+	}
+	return tag, nil
+}
+
+// normalizeTags 规范化并去重帖子的标签，超过数量限制时返回错误
+func normalizeTags(tags []string) ([]string, error) {
+	data := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		data = append(data, tag)
+	}
+	if n := maxTags(); len(data) > n {
+		return nil, fmt.Errorf("%w: 最多%d个标签", ErrorInvalidTag, n)
+	}
+	return data, nil
+}
+
+// loadPostTags 批量查询并填充帖子的标签，查询失败时只记录日志，不影响帖子数据的返回
+func loadPostTags(posts []*models.Post) {
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	tags, err := postStore.GetPostTags(ids)
+	if err != nil {
+		zap.L().Error("postStore.GetPostTags(ids) failed", zap.Error(err))
+		return
+	}
+	for _, post := range posts {
+		post.Tags = tags[post.ID]
+	}
+}
+
+// GetTrendingTags 热门标签，按最近一段时间内新帖子使用的次数排序
+func GetTrendingTags(size int64) ([]*models.TagCount, error) {
+	window := defaultTrendingWindow
+	if cfg := setting.Conf.TagConfig; cfg != nil && cfg.TrendingWindow > 0 {
+		window = cfg.TrendingWindow
+	}
+	return voteStore.GetTrendingTags(window, size)
+}
@@ -0,0 +1,73 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/jwt"
+	"bell_best/pkg/rbac"
+	"database/sql"
+	"errors"
+)
+
+// issueTokens 签发一对access token和refresh token，用户的角色写入access token
+func issueTokens(user *models.User) (token, refreshToken string, err error) {
+	token, tokenID, err := jwt.GenToken(user.UserID, user.Username, rbac.Normalize(user.Role))
+	if err != nil {
+		return "", "", err
+	}
+	if err = tokenStore.SaveAccessToken(user.UserID, tokenID, jwt.AccessTokenExpire()); err != nil {
+		return "", "", err
+	}
+	refreshToken, err = jwt.GenRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	err = tokenStore.SaveRefreshToken(refreshToken, &models.RefreshSession{
+		UserID:   user.UserID,
+		Username: user.Username,
+		TokenID:  tokenID,
+	}, jwt.RefreshTokenExpire())
+	return
+}
+
+// RefreshToken 用refresh token换取新的一对token，旧的refresh token随即作废
+func RefreshToken(p *models.ParamRefreshToken) (user *models.User, err error) {
+	s, err := tokenStore.TakeRefreshToken(p.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	// 重新查询用户，角色修改后刷新token就能生效
+	user, err = userStore.GetUserByID(s.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrorUserNotExist
+		}
+		return nil, err
+	}
+	user.Token, user.RefreshToken, err = issueTokens(user)
+	return
+}
+
+// Logout 退出登录，拉黑当前的access token并作废refresh token
+// p.All为true时退出该用户的所有会话
+func Logout(userID int64, tokenID string, p *models.ParamLogout) (err error) {
+	if err = tokenStore.DenyToken(tokenID, jwt.AccessTokenExpire()); err != nil {
+		return err
+	}
+	if p.All {
+		return RevokeUserSessions(userID)
+	}
+	if p.RefreshToken != "" {
+		return tokenStore.RemoveRefreshToken(userID, p.RefreshToken)
+	}
+	return
+}
+
+// RevokeUserSessions 作废用户所有的会话，用于账号被盗等需要强制下线的场景
+func RevokeUserSessions(userID int64) error {
+	return tokenStore.RevokeUserTokens(userID, jwt.AccessTokenExpire())
+}
+
+// IsTokenRevoked 判断access token是否已被注销
+func IsTokenRevoked(tokenID string) (bool, error) {
+	return tokenStore.IsTokenDenied(tokenID)
+}
@@ -0,0 +1,192 @@
+package logic
+
+import (
+	"bell_best/models"
+	"bell_best/pkg/imaging"
+	"bell_best/pkg/snowflake"
+	"bell_best/setting"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxUploadSize  = 5 << 20
+	defaultMaxPixels      = 40_000_000
+	defaultThumbnailSize  = 320
+	defaultOrphanTTL      = 24 * time.Hour
+	orphanCleanupPageSize = 100
+)
+
+var (
+	ErrorFileTooLarge        = errors.New("文件过大")
+	ErrorUnsupportedFileType = errors.New("不支持的文件类型")
+)
+
+// uploadConfig 返回上传配置，未配置的项使用默认值
+func uploadConfig() setting.UploadConfig {
+	var cfg setting.UploadConfig
+	if setting.Conf.UploadConfig != nil {
+		cfg = *setting.Conf.UploadConfig
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultMaxUploadSize
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = defaultMaxPixels
+	}
+	if cfg.ThumbnailSize <= 0 {
+		cfg.ThumbnailSize = defaultThumbnailSize
+	}
+	if cfg.OrphanTTL <= 0 {
+		cfg.OrphanTTL = defaultOrphanTTL
+	}
+	return cfg
+}
+
+// MaxUploadSize 单个上传文件的最大字节数
+func MaxUploadSize() int64 {
+	return uploadConfig().MaxSize
+}
+
+// Upload 保存用户上传的图片，去掉EXIF等元数据并生成缩略图
+// 图片在被帖子引用之前是孤立的，超过orphan_ttl仍没有帖子引用会被清理
+func Upload(userID int64, r io.Reader) (*models.ApiUpload, error) {
+	cfg := uploadConfig()
+	data, err := io.ReadAll(io.LimitReader(r, cfg.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > cfg.MaxSize {
+		return nil, ErrorFileTooLarge
+	}
+	img, err := imaging.Process(data, imaging.Options{MaxPixels: cfg.MaxPixels, ThumbnailSize: cfg.ThumbnailSize})
+	switch {
+	case errors.Is(err, imaging.ErrTooManyPixels), errors.Is(err, imaging.ErrTooManyFrames):
+		return nil, ErrorFileTooLarge
+	case errors.Is(err, imaging.ErrUnsupportedType), errors.Is(err, imaging.ErrInvalidImage):
+		return nil, fmt.Errorf("%w: %v", ErrorUnsupportedFileType, err)
+	case err != nil:
+		return nil, err
+	}
+
+	// 按月分目录，避免单个目录下文件过多
+	id := snowflake.GenID()
+	dir := time.Now().Format("2006/01/")
+	u := &models.Upload{
+		ID:          id,
+		UserID:      userID,
+		Key:         dir + strconv.FormatInt(id, 10) + img.Ext,
+		ThumbKey:    dir + strconv.FormatInt(id, 10) + "_thumb" + img.ThumbExt,
+		ContentType: img.ContentType,
+		Size:        int64(len(img.Data)),
+		Width:       img.Width,
+		Height:      img.Height,
+		CreateTime:  time.Now(),
+	}
+	if err = blobStore.Put(u.Key, img.Data, img.ContentType); err != nil {
+		return nil, err
+	}
+	if err = blobStore.Put(u.ThumbKey, img.Thumb, img.ThumbContentType); err != nil {
+		deleteBlobs(u)
+		return nil, err
+	}
+	if err = uploadStore.CreateUpload(u); err != nil {
+		deleteBlobs(u)
+		return nil, err
+	}
+	return &models.ApiUpload{
+		Upload:       u,
+		URL:          blobStore.URL(u.Key),
+		ThumbnailURL: blobStore.URL(u.ThumbKey),
+	}, nil
+}
+
+// deleteBlobs 删除图片的原图和缩略图，返回是否全部删除成功
+func deleteBlobs(u *models.Upload) bool {
+	ok := true
+	for _, key := range []string{u.Key, u.ThumbKey} {
+		if err := blobStore.Delete(key); err != nil {
+			zap.L().Error("blobStore.Delete failed", zap.String("key", key), zap.Error(err))
+			ok = false
+		}
+	}
+	return ok
+}
+
+var (
+	uploadRefOnce sync.Once
+	uploadRefRe   *regexp.Regexp
+)
+
+// uploadIDs 找出帖子正文中引用的上传图片（原图或缩略图地址）
+func uploadIDs(content string) []int64 {
+	uploadRefOnce.Do(func() {
+		uploadRefRe = regexp.MustCompile(regexp.QuoteMeta(blobStore.URL("")) + `\d{4}/\d{2}/(\d+)(?:_thumb)?\.(?:jpg|png|gif)`)
+	})
+	var ids []int64
+	seen := make(map[int64]struct{})
+	for _, m := range uploadRefRe.FindAllStringSubmatch(content, -1) {
+		id, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// linkPostUploads 按帖子正文更新帖子引用的图片，只能引用作者自己上传的图片
+// 失败时只记录日志，没有关联上的图片最多被当成孤立图片清理
+func linkPostUploads(p *models.Post) {
+	if err := uploadStore.SetPostUploads(p.AuthorID, p.ID, uploadIDs(p.Content)); err != nil {
+		zap.L().Error("uploadStore.SetPostUploads failed", zap.Int64("post_id", p.ID), zap.Error(err))
+	}
+}
+
+// unlinkPostUploads 帖子删除后取消关联，图片之后会被当成孤立图片清理
+func unlinkPostUploads(p *models.Post) {
+	if err := uploadStore.SetPostUploads(p.AuthorID, p.ID, nil); err != nil {
+		zap.L().Error("uploadStore.SetPostUploads failed", zap.Int64("post_id", p.ID), zap.Error(err))
+	}
+}
+
+// CleanupOrphanUploads 删除超过orphan_ttl仍没有帖子引用的图片
+func CleanupOrphanUploads() error {
+	before := time.Now().Add(-uploadConfig().OrphanTTL)
+	for {
+		uploads, err := uploadStore.GetOrphanUploads(before, orphanCleanupPageSize)
+		if err != nil {
+			return err
+		}
+		for _, u := range uploads {
+			// 先删除记录，清理期间被帖子引用的图片不会被删除
+			ok, err := uploadStore.DeleteOrphanUpload(u.ID)
+			if err != nil {
+				return err
+			}
+			if ok {
+				deleteBlobs(u)
+			}
+		}
+		if len(uploads) < orphanCleanupPageSize {
+			return nil
+		}
+	}
+}
+
+// RunUploadCleanup 后台定期清理没有帖子引用的图片，多个实例部署时只有一个实例执行
+func RunUploadCleanup(ctx context.Context, interval time.Duration) {
+	runJob(ctx, "upload:cleanup", interval, CleanupOrphanUploads)
+}
@@ -1,17 +1,26 @@
 package logic
 
 import (
-	"bell_best/dao/mysql"
 	"bell_best/models"
-	"bell_best/pkg/jwt"
+	"bell_best/pkg/rbac"
 	"bell_best/pkg/snowflake"
+	"bell_best/setting"
+	"database/sql"
+	"errors"
+
+	"go.uber.org/zap"
 )
 
 // 存放业务逻辑的代码
 
+var (
+	ErrorInvalidRole       = errors.New("无效的角色")
+	ErrorSeedAdminPassword = errors.New("创建管理员需要配置密码")
+)
+
 func SignUp(p *models.ParamSignUp) (err error) {
 	// 判断注册用户存不存在
-	if err := mysql.CheckUserExist(p.Username); err != nil {
+	if err := userStore.CheckUserExist(p.Username); err != nil {
 		return err
 	}
 	// 生成UID
@@ -21,22 +30,160 @@ func SignUp(p *models.ParamSignUp) (err error) {
 		UserID:   userID,
 		Username: p.Username,
 		Password: p.Password,
+		Role:     rbac.RoleUser,
 	}
 	// 保存进数据库
-	return mysql.InsertUser(user)
+	return userStore.InsertUser(user)
 }
 
-func Login(p *models.ParamLogin) (user *models.User, err error) {
+// Login 登录，ip用于统计登录失败次数
+func Login(p *models.ParamLogin, ip string) (user *models.User, err error) {
+	// 用户名或ip失败次数过多时暂时禁止登录
+	subjects := loginSubjects(p.Username, ip)
+	if err := checkLoginLocked(subjects); err != nil {
+		if errors.Is(err, ErrorLoginLocked) {
+			zap.L().Info("audit: login rejected while locked", zap.String("username", p.Username), zap.String("ip", ip))
+		}
+		return nil, err
+	}
 	user = &models.User{
 		Username: p.Username,
 		Password: p.Password,
 	}
 	// 传递的是指针，就能拿到userID
-	if err := mysql.Login(user); err != nil {
+	if err := userStore.Login(user); err != nil {
+		// 用户名不存在也计入失败次数，避免借此不受限制地探测用户名
+		if errors.Is(err, models.ErrorInvalidPassword) || errors.Is(err, models.ErrorUserNotExist) {
+			recordLoginFailure(subjects, p.Username, ip)
+		}
 		return nil, err
 	}
+	resetLoginFailures(subjects)
 	// 生产JWT
-	token, err := jwt.GenToken(user.UserID, user.Username)
-	user.Token = token
+	user.Token, user.RefreshToken, err = issueTokens(user)
 	return
 }
+
+// SetUserRole 修改用户的角色，并作废该用户所有的会话，重新登录后新的角色生效
+func SetUserRole(operatorID, uid int64, role string) error {
+	if !rbac.Valid(role) {
+		return ErrorInvalidRole
+	}
+	if err := userStore.UpdateUserRole(uid, role); err != nil {
+		return err
+	}
+	zap.L().Info("audit: user role changed",
+		zap.Int64("operator_id", operatorID), zap.Int64("user_id", uid), zap.String("role", role))
+	return RevokeUserSessions(uid)
+}
+
+// RevokeUser 管理员强制用户下线，作废该用户所有的会话及未过期的access token
+func RevokeUser(operatorID, uid int64) error {
+	if _, err := userStore.GetUserByID(uid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrorUserNotExist
+		}
+		return err
+	}
+	if err := RevokeUserSessions(uid); err != nil {
+		return err
+	}
+	zap.L().Info("audit: user sessions revoked",
+		zap.Int64("operator_id", operatorID), zap.Int64("user_id", uid))
+	return nil
+}
+
+// SeedAdmin 按配置创建管理员账号，用户名已存在时只把角色改为管理员
+func SeedAdmin(cfg *setting.SeedAdminConfig) error {
+	if cfg == nil || cfg.Username == "" {
+		return nil
+	}
+	user, err := userStore.GetUserByUsername(cfg.Username)
+	if errors.Is(err, models.ErrorUserNotExist) {
+		if cfg.Password == "" {
+			return ErrorSeedAdminPassword
+		}
+		err = userStore.InsertUser(&models.User{
+			UserID:   snowflake.GenID(),
+			Username: cfg.Username,
+			Password: cfg.Password,
+			Role:     rbac.RoleAdmin,
+		})
+		if err == nil {
+			zap.L().Info("audit: seed admin created", zap.String("username", cfg.Username))
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role == rbac.RoleAdmin {
+		return nil
+	}
+	if err := userStore.UpdateUserRole(user.UserID, rbac.RoleAdmin); err != nil {
+		return err
+	}
+	zap.L().Info("audit: seed admin promoted", zap.String("username", cfg.Username))
+	return nil
+}
+
+// GetUserProfile 查询用户的公开资料及粉丝数、关注数
+func GetUserProfile(uid int64) (*models.ApiUserProfile, error) {
+	user, err := userStore.GetUserProfile(uid)
+	if err != nil {
+		return nil, err
+	}
+	return toUserProfile(user)
+}
+
+// UpdateProfile 修改当前用户的资料，返回修改后的资料
+func UpdateProfile(uid int64, p *models.ParamUpdateProfile) (*models.ApiUserProfile, error) {
+	user, err := userStore.GetUserProfile(uid)
+	if err != nil {
+		return nil, err
+	}
+	user.DisplayName = p.DisplayName
+	user.Bio = p.Bio
+	user.AvatarURL = p.AvatarURL
+	if err := userStore.UpdateUserProfile(user); err != nil {
+		return nil, err
+	}
+	return toUserProfile(user)
+}
+
+// GetAuthorPage 作者主页，返回作者的资料及按时间或分数排序的帖子，viewerID为当前登录的用户
+func GetAuthorPage(viewerID, authorID int64, p *models.ParamPostList) (*models.ApiAuthorPage, error) {
+	cursor, err := decodePostCursor(p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := GetUserProfile(authorID)
+	if err != nil {
+		return nil, err
+	}
+	ids, next, err := voteStore.GetAuthorPostIDsByCursor(authorID, p, cursor)
+	if err != nil {
+		return nil, err
+	}
+	list, err := buildPostList(viewerID, ids, next)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApiAuthorPage{Profile: profile, ApiPostList: list}, nil
+}
+
+func toUserProfile(user *models.User) (*models.ApiUserProfile, error) {
+	count, err := followStore.GetFollowCount(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApiUserProfile{
+		UserID:      user.UserID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+		JoinTime:    user.CreateTime,
+		FollowCount: count,
+	}, nil
+}
@@ -1,36 +1,104 @@
 package logic
 
 import (
-	"bell_best/dao/redis"
 	"bell_best/models"
+	"bell_best/pkg/rank"
+	"context"
 	"go.uber.org/zap"
 
 	"strconv"
+	"time"
 )
 
-// 基于用户投票的相关算法:http://www.ruanyifeng.com/blog/algorithm/
-// 本项目使用简化版的投票分数
-// 投一票就加432分	86400/200 -> 200张赞成票可以给你的帖子续一天
-
-/* 投票的几种情况
-direction=1时，有两种情况：
-	1. 之前没有投过票，现在投赞成票--> 更新分数和投票记录	差值绝对值是1	+432
-	2. 之前投反对票，现在改赞成票--> 更新分数和投票记录	差值绝对值是2	+432*2
-direction=0时，有两种情况：
-	1. 之前投反对票，现在取消投票--> 更新分数和投票记录	差值绝对值是1	+432
-	2. 之前投赞成票，现在取消投票--> 更新分数和投票记录	差值绝对值是1	-432
-direction=-1时，有两种情况：
-	1. 之前没有投过票，现在投反对票--> 更新分数和投票记录	差值绝对值是1	-432
-	2. 之前投赞成票，现在改反对票--> 更新分数和投票记录		差值绝对值是2	-432*2
-
-投票的限制：
-每个帖子自发帖之日起只在一个星期内允许投票
-	1.到期之后将redis中保存的赞成票数及反对票数存储到mysql表中
-	2.到期之后删除那个 KeyPostVotedPF
-*/
+// 投票的限制：
+// 每个帖子自发帖之日起只在一个星期内允许投票
+// 	1.到期之后将redis中保存的赞成票数及反对票数存储到mysql表中
+// 	2.到期之后删除那个 KeyPostVotedPF
+// 帖子的分数由帖子所在社区配置的排序算法计算，见pkg/rank
+
+const (
+	voteExpire   = 7 * 24 * time.Hour // 与dao/redis中的投票期一致
+	archiveBatch = 500                // 每批归档的帖子数
+)
 
 // VoteForPost 为帖子投票的函数
 func VoteForPost(userID int64, p *models.ParamVoteData) (err error) {
 	zap.L().Debug("VoteForPost", zap.Int64("user_id", userID), zap.String("post_id", p.PostID), zap.Int8("direction", p.Direction))
-	return redis.VoteForPost(strconv.Itoa(int(userID)), p.PostID, float64(p.Direction))
+	pid, err := strconv.ParseInt(p.PostID, 10, 64)
+	if err != nil {
+		return models.ErrorPostNotExist
+	}
+	// 查询帖子所在的社区，使用社区的排序算法
+	post, err := postStore.GetPostByID(pid)
+	if err != nil {
+		return err
+	}
+	return voteStore.VoteForPost(strconv.Itoa(int(userID)), p.PostID, post.CommunityID, float64(p.Direction), rank.For(post.CommunityID))
+}
+
+// getPostVoteData 一次查询每篇帖子的赞成票、反对票及当前用户的投票
+// 投票期已结束并归档的帖子使用mysql中的票数，投票记录已删除，当前用户的投票为0
+func getPostVoteData(userID int64, posts []*models.Post, ids []string) ([]*models.PostVoteData, error) {
+	var uid string
+	if userID != 0 {
+		uid = strconv.FormatInt(userID, 10)
+	}
+	data, err := voteStore.GetPostVoteData(ids, uid)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(-voteExpire)
+	expired := make([]int64, 0)
+	for _, post := range posts {
+		if post.CreateTime.Before(deadline) {
+			expired = append(expired, post.ID)
+		}
+	}
+	if len(expired) == 0 {
+		return data, nil
+	}
+	archived, err := archiveStore.GetPostVotesByIDs(expired)
+	if err != nil {
+		return nil, err
+	}
+	for idx, post := range posts {
+		if v, ok := archived[post.ID]; ok {
+			data[idx].UpVotes, data[idx].DownVotes = v.UpVotes, v.DownVotes
+		}
+	}
+	return data, nil
+}
+
+// ArchiveExpiredVotes 把投票期已结束的帖子的票数归档到mysql，并删除redis中的投票记录
+func ArchiveExpiredVotes() error {
+	for {
+		ids, until, err := voteStore.GetVoteExpiredPostIDs(archiveBatch)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		votes, err := voteStore.GetPostVotes(ids)
+		if err != nil {
+			return err
+		}
+		// 没有人投过票的帖子不用保存
+		nonzero := make([]*models.PostVotes, 0, len(votes))
+		for _, v := range votes {
+			if v.UpVotes > 0 || v.DownVotes > 0 {
+				nonzero = append(nonzero, v)
+			}
+		}
+		// 先写mysql再删redis，中途失败时下一次会重新归档这一批
+		if err = archiveStore.SavePostVotes(nonzero); err != nil {
+			return err
+		}
+		if err = voteStore.ArchivePostVotes(ids, until); err != nil {
+			return err
+		}
+		zap.L().Debug("archive post votes", zap.Int("posts", len(ids)), zap.Int("voted", len(nonzero)))
+	}
+}
+
+// RunVoteArchive 每隔interval归档一次投票期已结束的帖子的票数，直到ctx结束
+func RunVoteArchive(ctx context.Context, interval time.Duration) {
+	runJob(ctx, "vote:archive", interval, ArchiveExpiredVotes)
 }
@@ -2,10 +2,17 @@ package main
 
 import (
 	"bell_best/controller"
+	"bell_best/dao/localfs"
 	"bell_best/dao/mysql"
 	"bell_best/dao/redis"
+	"bell_best/dao/s3"
 	_ "bell_best/docs" // 如果你生成了 docs 目录，记得导入
 	"bell_best/logger"
+	"bell_best/logic"
+	"bell_best/pkg/jwt"
+	"bell_best/pkg/password"
+	"bell_best/pkg/rank"
+	"bell_best/pkg/search"
 	"bell_best/pkg/snowflake"
 	"bell_best/router"
 	"bell_best/setting"
@@ -69,11 +76,96 @@ func main() {
 	}
 	defer redis.Close()
 
+	// 全文搜索默认使用MySQL的FULLTEXT索引
+	var searchStore logic.SearchStore = mysql.SearchStore{}
+	if cfg := setting.Conf.SearchConfig; cfg != nil {
+		switch cfg.Engine {
+		case "", "mysql":
+		case "inverted":
+			searchStore = search.NewIndex()
+		default:
+			fmt.Printf("unknown search engine %q\n", cfg.Engine)
+			return
+		}
+	}
+
+	// 上传的图片默认保存在本地目录
+	var (
+		blobStore logic.BlobStore
+		err       error
+	)
+	uploadCfg := setting.Conf.UploadConfig
+	if uploadCfg == nil {
+		uploadCfg = new(setting.UploadConfig)
+	}
+	switch uploadCfg.Storage {
+	case "", "local":
+		blobStore, err = localfs.New(uploadCfg.Local)
+	case "s3":
+		blobStore, err = s3.New(uploadCfg.S3)
+	default:
+		err = fmt.Errorf("unknown upload storage %q", uploadCfg.Storage)
+	}
+	if err != nil {
+		fmt.Printf("init blob store failed,err:%v\n", err)
+		return
+	}
+
+	// 注入logic层使用的存储实现
+	logic.Init(&logic.Stores{
+		Post:        mysql.PostStore{},
+		User:        mysql.UserStore{},
+		Community:   mysql.CommunityStore{},
+		Comment:     mysql.CommentStore{},
+		Vote:        redis.VoteStore{},
+		VoteArchive: mysql.VoteArchiveStore{},
+		Token:       redis.TokenStore{},
+		Lock:        redis.LockStore{},
+		RateLimit:   redis.RateLimitStore{},
+		LoginGuard:  redis.LoginGuardStore{},
+		Search:      searchStore,
+		Upload:      mysql.UploadStore{},
+		Blob:        blobStore,
+		Follow:      mysql.FollowStore{},
+		Timeline:    redis.TimelineStore{},
+	})
+
+	if err := password.Init(setting.Conf.AuthConfig); err != nil {
+		fmt.Printf("init password hasher failed,err:%v\n", err)
+		return
+	}
+
+	if err := jwt.Init(setting.Conf.AuthConfig); err != nil {
+		fmt.Printf("init jwt failed,err:%v\n", err)
+		return
+	}
+
+	if err := rank.Init(setting.Conf.RankConfig); err != nil {
+		fmt.Printf("init ranker failed,err:%v\n", err)
+		return
+	}
+
 	if err := snowflake.Init(setting.Conf.StartTime, setting.Conf.MachineID); err != nil {
 		fmt.Printf("init snowflake failed,err:%v\n", err)
 		return
 	}
 
+	// 进程内的倒排索引需要从数据库重建
+	if _, ok := searchStore.(*search.Index); ok {
+		n, err := logic.RebuildSearchIndex()
+		if err != nil {
+			fmt.Printf("rebuild search index failed,err:%v\n", err)
+			return
+		}
+		zap.L().Info("search index rebuilt", zap.Int("posts", n))
+	}
+
+	// 按配置创建管理员账号
+	if err := logic.SeedAdmin(setting.Conf.SeedAdminConfig); err != nil {
+		fmt.Printf("seed admin failed,err:%v\n", err)
+		return
+	}
+
 	// 初始化gin框架内置的校验器使用的翻译器
 	if err := controller.InitTrans("zh"); err != nil {
 		fmt.Printf("init validator trans failed,err:%v\n", err)
@@ -96,6 +188,20 @@ func main() {
 		Handler: r,
 	}
 
+	// 后台定期重新计算帖子分数、归档投票期已结束的帖子票数、发布定时帖子、清理没有帖子引用的图片
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if setting.Conf.RankConfig != nil {
+		go logic.RunScoreRecompute(jobCtx, setting.Conf.RankConfig.RecomputeInterval)
+	}
+	if setting.Conf.VoteConfig != nil {
+		go logic.RunVoteArchive(jobCtx, setting.Conf.VoteConfig.ArchiveInterval)
+	}
+	if setting.Conf.PublishConfig != nil {
+		go logic.RunScheduledPublish(jobCtx, setting.Conf.PublishConfig.ScheduleInterval)
+	}
+	go logic.RunUploadCleanup(jobCtx, uploadCfg.CleanupInterval)
+
 	go func() {
 		// 开启一个goroutine启动服务
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
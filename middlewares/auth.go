@@ -2,8 +2,10 @@ package middlewares
 
 import (
 	"bell_best/controller"
+	"bell_best/logic"
 	"bell_best/pkg/jwt"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"strings"
 )
 
@@ -21,24 +23,51 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
-
-		// 校验格式
-		// 按空格分割
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			controller.ResponseError(c, controller.CodeInvalidToken)
-			c.Abort()
-			return
-		}
-		// parts[1]是获取到的tokenString，我们使用之前定义好的解析JWT的函数来解析它
-		mc, err := jwt.ParseToken(parts[1])
-		if err != nil {
-			controller.ResponseError(c, controller.CodeInvalidToken)
+		if code := authenticate(c, authHeader); code != controller.CodeSuccess {
+			controller.ResponseError(c, code)
 			c.Abort()
 			return
 		}
-		// 将当前请求的userid信息保存到请求的上下文c上
-		c.Set(controller.CtxUserIDKey, mc.UserID)
 		c.Next() // 后续的处理函数可以用过c.Get(CtxUserIDKey)来获取当前请求的用户信息
 	}
 }
+
+// JWTOptionalAuthMiddleware 可选的JWT认证中间件，用于不要求登录的接口
+// 携带有效token时和JWTAuthMiddleware一样保存当前用户，没有token或token无效时按未登录处理
+func JWTOptionalAuthMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if authHeader := c.Request.Header.Get("Authorization"); authHeader != "" {
+			authenticate(c, authHeader)
+		}
+		c.Next()
+	}
+}
+
+// authenticate 校验Authorization请求头中的token，通过后把用户信息保存到请求的上下文c上
+func authenticate(c *gin.Context, authHeader string) controller.ResCode {
+	// 校验格式
+	// 按空格分割
+	parts := strings.SplitN(authHeader, " ", 2)
+	if !(len(parts) == 2 && parts[0] == "Bearer") {
+		return controller.CodeInvalidToken
+	}
+	// parts[1]是获取到的tokenString，我们使用之前定义好的解析JWT的函数来解析它
+	mc, err := jwt.ParseToken(parts[1])
+	if err != nil || mc.ID == "" {
+		return controller.CodeInvalidToken
+	}
+	// 检查token是否已经注销
+	revoked, err := logic.IsTokenRevoked(mc.ID)
+	if err != nil {
+		zap.L().Error("logic.IsTokenRevoked failed", zap.Error(err))
+		return controller.CodeServerBusy
+	}
+	if revoked {
+		return controller.CodeInvalidToken
+	}
+	// 将当前请求的userid信息保存到请求的上下文c上
+	c.Set(controller.CtxUserIDKey, mc.UserID)
+	c.Set(controller.CtxTokenIDKey, mc.ID)
+	c.Set(controller.CtxRoleKey, mc.Role)
+	return controller.CodeSuccess
+}
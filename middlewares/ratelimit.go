@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"bell_best/controller"
+	"bell_best/logic"
+	"bell_best/setting"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitMiddleware 按路由分组限流的中间件，group对应配置中rate_limit.groups下的分组名
+// 登录的用户按用户id限流，未登录的按ip限流，需要按用户限流的路由要放在JWT认证中间件之后
+func RateLimitMiddleware(group string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		// 每次请求都读取配置，修改配置文件后立即生效
+		cfg := setting.Conf.RateLimitConfig
+		if cfg == nil || !cfg.Enabled {
+			c.Next()
+			return
+		}
+		rule, ok := cfg.Groups[group]
+		if !ok || rule.Limit <= 0 || rule.Period <= 0 {
+			c.Next()
+			return
+		}
+		key := group + ":ip:" + c.ClientIP()
+		if userID, err := controller.GetCurrentUserID(c); err == nil {
+			key = group + ":user:" + strconv.FormatInt(userID, 10)
+		}
+		res, err := logic.AllowRequest(key, rule)
+		if err != nil {
+			// redis不可用时不限流，避免影响正常请求
+			zap.L().Error("logic.AllowRequest failed", zap.String("key", key), zap.Error(err))
+			c.Next()
+			return
+		}
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+		h.Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+		h.Set("X-RateLimit-Reset", ceilSeconds(res.Reset))
+		if !res.Allowed {
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			controller.ResponseErrorWithStatus(c, http.StatusTooManyRequests, controller.CodeTooManyRequests)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds 向上取整的秒数
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
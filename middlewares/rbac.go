@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"bell_best/controller"
+	"bell_best/pkg/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 要求当前用户的角色不低于role，需要放在JWTAuthMiddleware之后
+func RequireRole(role string) func(c *gin.Context) {
+	return requireRole(func(r string) bool { return rbac.AtLeast(r, role) })
+}
+
+// RequirePermission 要求当前用户的角色拥有权限perm，需要放在JWTAuthMiddleware之后
+func RequirePermission(perm rbac.Permission) func(c *gin.Context) {
+	return requireRole(func(r string) bool { return rbac.Can(r, perm) })
+}
+
+func requireRole(allow func(role string) bool) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if _, err := controller.GetCurrentUserID(c); err != nil {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+		if !allow(controller.GetCurrentRole(c)) {
+			controller.ResponseError(c, controller.CodeNoPermission)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// 评论展示方式
+const (
+	CommentViewFlat = "flat" // 按时间平铺
+	CommentViewTree = "tree" // 按父子关系嵌套
+)
+
+// Comment 帖子评论
+// ParentID为0表示直接评论帖子，RootID记录所属的顶层评论，顶层评论的RootID是它自己
+type Comment struct {
+	ID         int64     `json:"id,string" db:"comment_id"`
+	PostID     int64     `json:"post_id,string" db:"post_id"`
+	ParentID   int64     `json:"parent_id,string" db:"parent_id"`
+	RootID     int64     `json:"root_id,string" db:"root_id"`
+	AuthorID   int64     `json:"author_id,string" db:"author_id"`
+	Content    string    `json:"content" db:"content"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiComment 评论接口返回的数据
+type ApiComment struct {
+	AuthorName string `json:"author_name"`
+	*Comment
+	Children []*ApiComment `json:"children,omitempty"` // 只在tree视图中使用
+}
+
+// ApiCommentList 评论列表接口返回的数据
+type ApiCommentList struct {
+	Comments   []*ApiComment `json:"comments"`
+	NextCursor string        `json:"next_cursor"` // 为空表示没有更多数据
+}
@@ -11,5 +11,13 @@ type CommunityDetail struct {
 	ID           int64     `json:"id" db:"community_id"`
 	Name         string    `json:"name" db:"community_name"`
 	Introduction string    `json:"introduction,omitempty" db:"introduction"`
+	Archived     bool      `json:"archived" db:"archived"` // 归档后不能再发帖
+	Subscribers  int64     `json:"subscriber_count" db:"subscriber_count"`
 	CreateTime   time.Time `json:"create_time" db:"create_time"`
 }
+
+// ApiModerator 社区的版主
+type ApiModerator struct {
+	UserID   int64  `json:"user_id,string"`
+	Username string `json:"username"`
+}
@@ -1,9 +1,26 @@
+-- password保存argon2id/bcrypt哈希，已有的库需要执行:
+-- ALTER TABLE `user` MODIFY `password` varchar(255) COLLATE utf8mb4_general_ci NOT NULL;
+-- 个人资料字段，已有的库需要执行:
+-- ALTER TABLE `user` ADD `display_name` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' AFTER `password`,
+--     ADD `bio` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' AFTER `display_name`,
+--     ADD `avatar_url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' AFTER `bio`;
+-- 用户角色，已有的库需要执行:
+-- ALTER TABLE `user` ADD `role` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'user' AFTER `password`;
+-- 粉丝数及关注数，已有的库需要执行:
+-- ALTER TABLE `user` ADD `follower_count` bigint(20) NOT NULL DEFAULT '0' AFTER `avatar_url`,
+--     ADD `following_count` bigint(20) NOT NULL DEFAULT '0' AFTER `follower_count`;
 DROP TABLE IF EXISTS `user`;
 CREATE TABLE 'user' (
     'id' bigint(20) NOT NULL AUTO_INCREMENT,
     'user_id' bigint(20) NOT NULL,
     'username' varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
-    'password' varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
+    'password' varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
+    'role' varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'user',
+    'display_name' varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+    'bio' varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+    'avatar_url' varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+    'follower_count' bigint(20) NOT NULL DEFAULT '0',
+    'following_count' bigint(20) NOT NULL DEFAULT '0',
     'email' varchar(64) COLLATE utf8mb4_general_ci,
     'gender' tinyint(4) NOT NULL DEFAULT '0',
     'create_time' timestamp NULL DEFAULT CURRENT_TIMESTAMP,
@@ -14,23 +31,67 @@ CREATE TABLE 'user' (
     UNIQUE KEY 'idx_user_id' ('user_id') USING BTREE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
+-- 社区归档标记，已有的库需要执行:
+-- ALTER TABLE `community` ADD `archived` tinyint(4) NOT NULL DEFAULT '0' AFTER `introduction`;
+-- 订阅人数，已有的库需要执行:
+-- ALTER TABLE `community` ADD `subscriber_count` bigint(20) NOT NULL DEFAULT '0' AFTER `archived`;
 DROP TABLE IF EXISTS `community`;
 CREATE TABLE `community` (
                              `id` int(11) NOT NULL AUTO_INCREMENT,
                              `community_id` int(10) unsigned NOT NULL,
                              `community_name` varchar(128) COLLATE utf8mb4_general_ci NOT NULL,
                              `introduction` varchar(256) COLLATE utf8mb4_general_ci NOT NULL,
+                             `archived` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否归档',
+                             `subscriber_count` bigint(20) NOT NULL DEFAULT '0' COMMENT '订阅人数',
                              `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
                              `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
                              PRIMARY KEY (`id`),
                              UNIQUE KEY `idx_community_id` (`community_id`),
                              UNIQUE KEY `idx_community_name` (`community_name`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
-INSERT INTO `community` VALUES ('1', '1', 'Go', 'Golang', '2016-11-01 08:10:10', '2016-11-01 08:10:10');
-INSERT INTO `community` VALUES ('2', '2', 'leetcode', '刷题刷题刷题', '2020-01-01 08:00:00', '2020-01-01 08:00:00');
-INSERT INTO `community` VALUES ('3', '3', 'PUBG', '大吉大利，今晚吃鸡。', '2018-08-07 08:30:00', '2018-08-07 08:30:00');
-INSERT INTO `community` VALUES ('4', '4', 'LOL', '欢迎来到英雄联盟!', '2016-01-01 08:00:00', '2016-01-01 08:00:00');
+INSERT INTO `community` VALUES ('1', '1', 'Go', 'Golang', '0', '0', '2016-11-01 08:10:10', '2016-11-01 08:10:10');
+INSERT INTO `community` VALUES ('2', '2', 'leetcode', '刷题刷题刷题', '0', '0', '2020-01-01 08:00:00', '2020-01-01 08:00:00');
+INSERT INTO `community` VALUES ('3', '3', 'PUBG', '大吉大利，今晚吃鸡。', '0', '0', '2018-08-07 08:30:00', '2018-08-07 08:30:00');
+INSERT INTO `community` VALUES ('4', '4', 'LOL', '欢迎来到英雄联盟!', '0', '0', '2016-01-01 08:00:00', '2016-01-01 08:00:00');
 
+DROP TABLE IF EXISTS `community_moderator`;
+CREATE TABLE `community_moderator` (
+                                       `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                                       `community_id` bigint(20) NOT NULL COMMENT '社区id',
+                                       `user_id` bigint(20) NOT NULL COMMENT '版主的用户id',
+                                       `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '任命时间',
+                                       PRIMARY KEY (`id`),
+                                       UNIQUE KEY `idx_community_user` (`community_id`, `user_id`),
+                                       KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `follow`;
+CREATE TABLE `follow` (
+                          `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                          `follower_id` bigint(20) NOT NULL COMMENT '粉丝的用户id',
+                          `followee_id` bigint(20) NOT NULL COMMENT '被关注的用户id',
+                          `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '关注时间',
+                          PRIMARY KEY (`id`),
+                          UNIQUE KEY `idx_follower_followee` (`follower_id`, `followee_id`),
+                          KEY `idx_followee_id` (`followee_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_subscriber`;
+CREATE TABLE `community_subscriber` (
+                                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                                        `community_id` bigint(20) NOT NULL COMMENT '社区id',
+                                        `user_id` bigint(20) NOT NULL COMMENT '订阅者的用户id',
+                                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '订阅时间',
+                                        PRIMARY KEY (`id`),
+                                        UNIQUE KEY `idx_user_community` (`user_id`, `community_id`),
+                                        KEY `idx_community_id` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- 草稿及定时发布，status: 0已删除 1正常 2草稿 3定时发布，已有的库需要执行:
+-- ALTER TABLE `post` ADD `publish_at` timestamp NULL DEFAULT NULL COMMENT '定时发布的时间' AFTER `status`,
+--     DROP KEY `idx_author_id`, ADD KEY `idx_author_status` (`author_id`, `status`), ADD KEY `idx_status_publish_at` (`status`, `publish_at`);
+-- 帖子全文索引，已有的库需要执行:
+-- ALTER TABLE `post` ADD FULLTEXT KEY `idx_fulltext` (`title`, `content`) WITH PARSER ngram;
 DROP TABLE IF EXISTS `post`;
 CREATE TABLE `post` (
                         `id` bigint(20) NOT NULL AUTO_INCREMENT,
@@ -40,10 +101,84 @@ CREATE TABLE `post` (
                         `author_id` bigint(20) NOT NULL COMMENT '作者的用户id',
                         `community_id` bigint(20) NOT NULL COMMENT '所属社区',
                         `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态',
+                        `publish_at` timestamp NULL DEFAULT NULL COMMENT '定时发布的时间',
                         `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
                         `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
                         PRIMARY KEY (`id`),
                         UNIQUE KEY `idx_post_id` (`post_id`),
-                        KEY `idx_author_id` (`author_id`),
-                        KEY `idx_community_id` (`community_id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
\ No newline at end of file
+                        KEY `idx_author_status` (`author_id`, `status`),
+                        KEY `idx_community_id` (`community_id`),
+                        KEY `idx_status_publish_at` (`status`, `publish_at`),
+                        FULLTEXT KEY `idx_fulltext` (`title`, `content`) WITH PARSER ngram
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_tag`;
+CREATE TABLE `post_tag` (
+                            `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                            `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                            `tag` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标签',
+                            `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                            PRIMARY KEY (`id`),
+                            UNIQUE KEY `idx_post_tag` (`post_id`, `tag`),
+                            KEY `idx_tag` (`tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_revision`;
+CREATE TABLE `post_revision` (
+                                 `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                                 `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                                 `revision` bigint(20) NOT NULL COMMENT '版本号，从1开始',
+                                 `editor_id` bigint(20) NOT NULL COMMENT '修改者的用户id',
+                                 `title` varchar(128) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标题',
+                                 `content` varchar(8192) COLLATE utf8mb4_general_ci NOT NULL COMMENT '内容',
+                                 `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '修改时间',
+                                 PRIMARY KEY (`id`),
+                                 UNIQUE KEY `idx_post_revision` (`post_id`, `revision`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `comment`;
+CREATE TABLE `comment` (
+                           `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                           `comment_id` bigint(20) NOT NULL COMMENT '评论id',
+                           `post_id` bigint(20) NOT NULL COMMENT '所属帖子',
+                           `parent_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '回复的评论id，0表示直接评论帖子',
+                           `root_id` bigint(20) NOT NULL COMMENT '所属顶层评论id',
+                           `author_id` bigint(20) NOT NULL COMMENT '评论者的用户id',
+                           `content` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL COMMENT '内容',
+                           `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                           PRIMARY KEY (`id`),
+                           UNIQUE KEY `idx_comment_id` (`comment_id`),
+                           KEY `idx_post_parent` (`post_id`, `parent_id`),
+                           KEY `idx_root_id` (`root_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_vote`;
+CREATE TABLE `post_vote` (
+                             `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                             `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                             `up_votes` bigint(20) NOT NULL DEFAULT '0' COMMENT '赞成票数',
+                             `down_votes` bigint(20) NOT NULL DEFAULT '0' COMMENT '反对票数',
+                             `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '归档时间',
+                             PRIMARY KEY (`id`),
+                             UNIQUE KEY `idx_post_id` (`post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `upload`;
+CREATE TABLE `upload` (
+                          `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                          `upload_id` bigint(20) NOT NULL COMMENT '图片id',
+                          `user_id` bigint(20) NOT NULL COMMENT '上传者的用户id',
+                          `post_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '引用该图片的帖子，0表示没有帖子引用',
+                          `blob_key` varchar(255) COLLATE utf8mb4_general_ci NOT NULL COMMENT '原图的存储路径',
+                          `thumb_key` varchar(255) COLLATE utf8mb4_general_ci NOT NULL COMMENT '缩略图的存储路径',
+                          `content_type` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+                          `size` bigint(20) NOT NULL COMMENT '原图字节数',
+                          `width` int(11) NOT NULL,
+                          `height` int(11) NOT NULL,
+                          `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '上传时间',
+                          `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+                          PRIMARY KEY (`id`),
+                          UNIQUE KEY `idx_upload_id` (`upload_id`),
+                          KEY `idx_user_id` (`user_id`),
+                          KEY `idx_post_update` (`post_id`, `update_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,15 @@
+package models
+
+import "errors"
+
+// 各层共用的业务错误，由dao返回，logic及controller据此判断
+var (
+	ErrorUserExist        = errors.New("用户已存在")
+	ErrorUserNotExist     = errors.New("用户不存在")
+	ErrorInvalidPassword  = errors.New("密码错误")
+	ErrorInvalidID        = errors.New("无效的ID")
+	ErrorPostNotExist     = errors.New("帖子不存在")
+	ErrorCommentNotExist  = errors.New("评论不存在")
+	ErrorCommunityExist   = errors.New("社区已存在")
+	ErrorRevisionNotExist = errors.New("版本不存在")
+)
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // 定义请求的参数结构体
 
 const (
@@ -19,16 +21,93 @@ type ParamLogin struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ParamRefreshToken 刷新token的参数
+type ParamRefreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ParamLogout 退出登录的参数
+type ParamLogout struct {
+	RefreshToken string `json:"refresh_token"` // 同时作废的refresh token
+	All          bool   `json:"all"`           // 是否退出该用户的所有会话
+}
+
+// ParamUpdateProfile 编辑个人资料的参数
+type ParamUpdateProfile struct {
+	DisplayName string `json:"display_name" binding:"max=64"`
+	Bio         string `json:"bio" binding:"max=512"`
+	AvatarURL   string `json:"avatar_url" binding:"omitempty,url,max=512"`
+}
+
+// ParamUserRole 修改用户角色的参数
+type ParamUserRole struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
+// ParamCommunity 创建、编辑社区的参数
+type ParamCommunity struct {
+	Name         string `json:"name" binding:"required,max=128"`
+	Introduction string `json:"introduction" binding:"required,max=256"`
+}
+
+// ParamModerator 添加社区版主的参数
+type ParamModerator struct {
+	UserID int64 `json:"user_id,string" binding:"required"`
+}
+
 // ParamVoteData 投票数据
 type ParamVoteData struct {
-	PostID    string `json:"post_id,string" binding:"required"`       // 帖子id
+	PostID    string `json:"post_id" binding:"required"`              // 帖子id
 	Direction int8   `json:"direction,string" binding:"oneof=1 0 -1"` // 赞成票(1)还是反对票(-1)取消投票(0)
 }
 
+// ParamCreatePost 发帖的参数，draft为true时保存为草稿，publish_at为将来的时间时定时发布
+type ParamCreatePost struct {
+	Post
+	Draft bool `json:"draft"`
+}
+
+// ParamPublishPost 发布草稿的参数，publish_at为空或已过去时立即发布
+type ParamPublishPost struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// ParamUpdatePost 编辑帖子的参数
+type ParamUpdatePost struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// ParamCreateComment 发表评论的参数
+type ParamCreateComment struct {
+	ParentID int64  `json:"parent_id,string"`                    // 回复的评论id，为空表示直接评论帖子
+	Content  string `json:"content" binding:"required,max=2048"` // 评论内容，按字符计算长度
+}
+
+// ParamCommentList 获取评论列表query string参数
+type ParamCommentList struct {
+	Cursor int64  `json:"cursor" form:"cursor"`                                                // 上一页最后一条评论的id，为空从头开始
+	Size   int64  `json:"size" form:"size"`                                                    // 每页数据量
+	View   string `json:"view" form:"view" binding:"omitempty,oneof=flat tree" example:"tree"` // 展示方式
+}
+
 // ParamPostList 获取帖子列表query string参数
 type ParamPostList struct {
-	CommunityID int64  `json:"community_id" form:"community_id"`   // 可以为空
-	Page        int64  `json:"page" form:"page"`                   // 页码
-	Size        int64  `json:"size" form:"size"`                   // 每页数据量
-	Order       string `json:"order" form:"order" example:"score"` // 排序依据
+	CommunityID int64  `json:"community_id" form:"community_id"`         // 可以为空
+	Tag         string `json:"tag" form:"tag"`                           // 可以为空
+	Page        int64  `json:"page" form:"page"`                         // 页码
+	Size        int64  `json:"size" form:"size" binding:"min=1,max=100"` // 每页数据量，默认10
+	Order       string `json:"order" form:"order" example:"score"`       // 排序依据
+	Cursor      string `json:"cursor" form:"cursor"`                     // 游标，传入时按游标分页并忽略page
+	Subscribed  bool   `json:"subscribed" form:"subscribed"`             // 只查询当前用户订阅的社区，需要登录，不能与community_id及tag同时使用
+}
+
+// ParamSearch 搜索帖子的query string参数
+type ParamSearch struct {
+	Q           string `json:"q" form:"q" binding:"required,max=100"` // 搜索词
+	CommunityID int64  `json:"community_id" form:"community_id"`      // 可以为空
+	Since       int64  `json:"since" form:"since"`                    // 发帖时间不早于，unix秒，可以为空
+	Until       int64  `json:"until" form:"until"`                    // 发帖时间早于，unix秒，可以为空
+	Page        int64  `json:"page" form:"page"`                      // 页码
+	Size        int64  `json:"size" form:"size"`                      // 每页数据量
 }
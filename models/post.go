@@ -4,19 +4,55 @@ import "time"
 
 // 内存对齐概念
 
+// 帖子状态
+const (
+	PostStatusDeleted   int32 = 0 // 已删除
+	PostStatusNormal    int32 = 1 // 正常
+	PostStatusDraft     int32 = 2 // 草稿，只有作者可见
+	PostStatusScheduled int32 = 3 // 定时发布，到publish_at时由后台任务发布
+)
+
 type Post struct {
-	ID          int64     `json:"id" db:"post_id"`
-	AuthorID    int64     `json:"author_id" db:"author_id"`
-	CommunityID int64     `json:"community_id" db:"community_id" binding:"required"`
-	Status      int32     `json:"status" db:"status"`
-	Title       string    `json:"title" db:"title" binding:"required"`
-	Content     string    `json:"content" db:"content" binding:"required"`
-	CreateTime  time.Time `json:"create_time" db:"create_time"`
+	ID          int64      `json:"id" db:"post_id"`
+	AuthorID    int64      `json:"author_id" db:"author_id"`
+	CommunityID int64      `json:"community_id" db:"community_id" binding:"required"`
+	Status      int32      `json:"status" db:"status"`
+	Title       string     `json:"title" db:"title" binding:"required"`
+	Content     string     `json:"content" db:"content" binding:"required"`
+	Tags        []string   `json:"tags" db:"-"`                          // 标签，单独保存在post_tag表中
+	PublishAt   *time.Time `json:"publish_at,omitempty" db:"publish_at"` // 定时发布的时间，只对定时发布的帖子有效
+	CreateTime  time.Time  `json:"create_time" db:"create_time"`
+	UpdateTime  time.Time  `json:"update_time" db:"update_time"`
+}
+
+// TagCount 标签及最近使用的次数
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// PostCursor 帖子列表的游标，记录上一页最后一个帖子的分数和id
+type PostCursor struct {
+	Score float64
+	ID    string
+}
+
+// ApiPostList 按游标分页的帖子列表
+type ApiPostList struct {
+	Posts      []*ApiPostDetail `json:"posts"`
+	NextCursor string           `json:"next_cursor"` // 为空表示没有更多数据
 }
 
 type ApiPostDetail struct {
 	AuthorName       string             `json:"author_name"`
-	VoteNum          int64              `json:"vote_num"`
+	VoteNum          int64              `json:"vote_num"` // 赞成票数，与up_votes相同，保留给旧客户端
+	UpVotes          int64              `json:"up_votes"`
+	DownVotes        int64              `json:"down_votes"`
+	Score            int64              `json:"score"`             // 净票数 = 赞成票 - 反对票
+	MyVote           *int8              `json:"my_vote,omitempty"` // 当前用户的投票 1/0/-1，未登录时不返回
+	CommentNum       int64              `json:"comment_num"`
+	ContentHTML      string             `json:"content_html"` // 正文Markdown渲染后的HTML，已过滤不安全的内容
+	Excerpt          string             `json:"excerpt"`      // 正文开头的纯文本摘要，用于列表展示
 	*Post                               // 嵌入帖子结构体
 	*CommunityDetail `json:"community"` // 嵌入社区信息
 }
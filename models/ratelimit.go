@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateLimitResult 一次限流检查的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int64         // 令牌桶的容量
+	Remaining  int64         // 剩余的令牌数
+	RetryAfter time.Duration // 被限流时需要等待多久才有新的令牌
+	Reset      time.Duration // 多久之后令牌桶会重新装满
+}
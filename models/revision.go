@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PostRevision 帖子的历史版本，每次修改标题或内容都会保存一个新版本
+type PostRevision struct {
+	PostID     int64     `json:"post_id" db:"post_id"`
+	Revision   int64     `json:"revision" db:"revision"`
+	EditorID   int64     `json:"editor_id" db:"editor_id"`
+	EditorName string    `json:"editor_name" db:"-"`
+	Title      string    `json:"title" db:"title"`
+	Content    string    `json:"content,omitempty" db:"content"` // 版本列表不返回内容
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiPostRevision 历史版本及与上一个版本的unified diff
+type ApiPostRevision struct {
+	*PostRevision
+	Diff string `json:"diff"`
+}
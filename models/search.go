@@ -0,0 +1,14 @@
+package models
+
+// ApiSearchHit 搜索结果中的一个帖子，高亮的文本已做HTML转义，命中的词用<em>包起来
+type ApiSearchHit struct {
+	*ApiPostDetail
+	TitleHighlight   string `json:"title_highlight"`
+	ContentHighlight string `json:"content_highlight"` // 正文命中位置附近的摘要
+}
+
+// ApiSearchResult 搜索结果
+type ApiSearchResult struct {
+	Total int64           `json:"total"` // 命中的帖子总数
+	Hits  []*ApiSearchHit `json:"hits"`
+}
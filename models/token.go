@@ -0,0 +1,8 @@
+package models
+
+// RefreshSession refresh token对应的会话信息
+type RefreshSession struct {
+	UserID   int64
+	Username string
+	TokenID  string // 与refresh token一起签发的access token的jti
+}
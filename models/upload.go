@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Upload 用户上传的图片，原图和缩略图保存在BlobStore中
+type Upload struct {
+	ID          int64     `json:"id" db:"upload_id"`
+	UserID      int64     `json:"user_id" db:"user_id"`
+	PostID      int64     `json:"post_id" db:"post_id"` // 引用该图片的帖子，0表示还没有帖子引用
+	Key         string    `json:"-" db:"blob_key"`
+	ThumbKey    string    `json:"-" db:"thumb_key"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	Width       int       `json:"width" db:"width"`
+	Height      int       `json:"height" db:"height"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+	UpdateTime  time.Time `json:"-" db:"update_time"`
+}
+
+// ApiUpload 上传接口返回的图片地址，在帖子正文中引用这些地址
+type ApiUpload struct {
+	*Upload
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
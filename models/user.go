@@ -1,8 +1,39 @@
 package models
 
+import "time"
+
 type User struct {
-	UserID   int64  `db:"user_id"`
-	Username string `db:"username"`
-	Password string `db:"password"`
-	Token    string
+	UserID       int64     `db:"user_id"`
+	Username     string    `db:"username"`
+	Password     string    `db:"password"`
+	Role         string    `db:"role"` // 见pkg/rbac
+	DisplayName  string    `db:"display_name"`
+	Bio          string    `db:"bio"`
+	AvatarURL    string    `db:"avatar_url"`
+	CreateTime   time.Time `db:"create_time"`
+	Token        string
+	RefreshToken string
+}
+
+// ApiUserProfile 用户的公开资料
+type ApiUserProfile struct {
+	UserID      int64     `json:"user_id,string"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	JoinTime    time.Time `json:"join_time"`
+	*FollowCount
+}
+
+// FollowCount 用户的粉丝数及关注数
+type FollowCount struct {
+	Followers int64 `json:"follower_count" db:"follower_count"`
+	Following int64 `json:"following_count" db:"following_count"`
+}
+
+// ApiAuthorPage 作者主页，资料及按时间或分数排序的帖子
+type ApiAuthorPage struct {
+	Profile      *ApiUserProfile `json:"profile"`
+	*ApiPostList                 // 作者的帖子及下一页的游标
 }
@@ -0,0 +1,16 @@
+package models
+
+// PostVotes 帖子的赞成票及反对票数
+// 投票期内保存在redis中，投票期结束后归档到mysql的post_vote表
+type PostVotes struct {
+	PostID    int64 `json:"post_id,string" db:"post_id"`
+	UpVotes   int64 `json:"up_votes" db:"up_votes"`
+	DownVotes int64 `json:"down_votes" db:"down_votes"`
+}
+
+// PostVoteData 帖子列表及详情中展示的投票数据
+type PostVoteData struct {
+	UpVotes   int64
+	DownVotes int64
+	MyVote    int8 // 当前用户的投票，没有指定用户时为0
+}
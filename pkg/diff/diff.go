@@ -0,0 +1,226 @@
+// Package diff 按行比较两段文本，输出unified diff格式
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// noNewline 最后一行没有换行符时的提示，与diff命令一致
+const noNewline = `\ No newline at end of file`
+
+// 编辑距离超过这个值时不再求最短编辑，直接当作整段替换，避免内容差异很大时占用过多内存
+const maxEditDistance = 2000
+
+// Kind 行的变化类型
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Edit 一行的变化
+type Edit struct {
+	Kind Kind
+	Line string
+}
+
+// Lines 用Myers算法求a变成b的最短编辑序列
+func Lines(a, b []string) []Edit {
+	// 去掉相同的前缀和后缀，只对中间不同的部分求编辑序列
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a[:pre] {
+		edits = append(edits, Edit{Equal, line})
+	}
+	edits = append(edits, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, line := range a[len(a)-suf:] {
+		edits = append(edits, Edit{Equal, line})
+	}
+	return edits
+}
+
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	limit := min(n+m, maxEditDistance)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d]保存第d步开始前v中[-d-1, d+1]的部分，用于回溯
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string) []Edit {
+	x, y := len(a), len(b)
+	var rev []Edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, Edit{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			rev = append(rev, Edit{Insert, b[y-1]})
+			y--
+		} else {
+			rev = append(rev, Edit{Delete, a[x-1]})
+			x--
+		}
+	}
+	edits := make([]Edit, len(rev))
+	for i, e := range rev {
+		edits[len(rev)-1-i] = e
+	}
+	return edits
+}
+
+func replaceAll(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, Edit{Delete, line})
+	}
+	for _, line := range b {
+		edits = append(edits, Edit{Insert, line})
+	}
+	return edits
+}
+
+// Unified 输出a变成b的unified diff，每处修改前后保留context行上下文，没有变化时返回空字符串
+func Unified(oldName, newName, a, b string, context int) string {
+	edits := Lines(splitLines(a), splitLines(b))
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// 找到下一处修改
+		for start < len(edits) && edits[start].Kind == Equal {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		// 向后合并间隔不超过2*context行的修改
+		end := start
+		for i := start; i < len(edits); i++ {
+			if edits[i].Kind != Equal {
+				end = i + 1
+			} else if i-end >= 2*context {
+				break
+			}
+		}
+		from, to := max(start-context, 0), min(end+context, len(edits))
+		if out.Len() == 0 {
+			out.WriteString("--- " + oldName + "\n+++ " + newName + "\n")
+		}
+		writeHunk(&out, edits, from, to)
+		start = to
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []Edit, from, to int) {
+	// 计算hunk之前的行数
+	oldLine, newLine := 0, 0
+	for _, e := range edits[:from] {
+		if e.Kind != Insert {
+			oldLine++
+		}
+		if e.Kind != Delete {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	var body strings.Builder
+	for _, e := range edits[from:to] {
+		switch e.Kind {
+		case Equal:
+			writeLine(&body, " ", e.Line)
+			oldCount++
+			newCount++
+		case Delete:
+			writeLine(&body, "-", e.Line)
+			oldCount++
+		case Insert:
+			writeLine(&body, "+", e.Line)
+			newCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+	out.WriteString(body.String())
+}
+
+// hunkRange 行号从1开始，行数为0时起始行为hunk前一行
+func hunkRange(before, count int) string {
+	start := before + 1
+	if count == 0 {
+		start = before
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// writeLine 输出一行，最后一行没有换行符时和diff命令一样加上提示
+func writeLine(out *strings.Builder, prefix, line string) {
+	out.WriteString(prefix + line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n" + noNewline + "\n")
+	}
+}
+
+// splitLines 按行切分，每行保留结尾的换行符，最后一行有没有换行符算作不同的内容
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	seq := func(lines ...string) string { return strings.Join(lines, "\n") + "\n" }
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"both empty", "", "", 3, ""},
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{"identical without trailing newline", "a\nb", "a\nb", 3, ""},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", 3, ""},
+		{"from empty", "", "a\n", 3, "@@ -0,0 +1 @@\n+a\n"},
+		{"to empty", "a\n", "", 3, "@@ -1 +0,0 @@\n-a\n"},
+		{"trailing newline removed", "a\nb\n", "a\nb", 3,
+			"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n"},
+		{"trailing newline added", "a\nb", "a\nb\n", 3,
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+		{"both without trailing newline", "x", "y", 3,
+			"@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+y\n\\ No newline at end of file\n"},
+		{"separate hunks", seq("1", "2", "3", "4", "5", "6", "7", "8", "9", "10"), seq("1", "two", "3", "4", "5", "six", "7", "8", "9", "10"), 1,
+			"@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n@@ -5,3 +5,3 @@\n 5\n-6\n+six\n 7\n"},
+		{"merged hunks", seq("1", "2", "3", "4", "5", "6", "7", "8", "9", "10"), seq("1", "two", "3", "4", "5", "six", "7", "8", "9", "10"), 2,
+			"@@ -1,8 +1,8 @@\n 1\n-2\n+two\n 3\n 4\n 5\n-6\n+six\n 7\n 8\n"},
+		{"gap of twice the context merges", seq("1", "2", "3", "4", "5", "6", "7", "8", "9", "10"), seq("1", "two", "3", "4", "five", "6", "7", "8", "9", "10"), 1,
+			"@@ -1,6 +1,6 @@\n 1\n-2\n+two\n 3\n 4\n-5\n+five\n 6\n"},
+		{"zero context", seq("a", "b", "c"), seq("a", "B", "c"), 0, "@@ -2 +2 @@\n-b\n+B\n"},
+		{"insert in middle", seq("a", "c"), seq("a", "b", "c"), 3, "@@ -1,2 +1,3 @@\n a\n+b\n c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = "--- old\n+++ new\n" + want
+			}
+			if got := Unified("old", "new", tt.a, tt.b, tt.context); got != want {
+				t.Errorf("Unified(%q, %q, %d)\n got %q\nwant %q", tt.a, tt.b, tt.context, got, want)
+			}
+		})
+	}
+}
+
+// apply 用编辑序列还原出旧文本和新文本
+func apply(edits []Edit) (a, b []string) {
+	for _, e := range edits {
+		if e.Kind != Insert {
+			a = append(a, e.Line)
+		}
+		if e.Kind != Delete {
+			b = append(b, e.Line)
+		}
+	}
+	return
+}
+
+// lcs 动态规划求最长公共子序列的长度，用于检查编辑序列是否最短
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLinesShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randLines := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = strconv.Itoa(r.Intn(4))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := randLines(), randLines()
+		edits := Lines(a, b)
+		gotA, gotB := apply(edits)
+		if !equal(gotA, a) || !equal(gotB, b) {
+			t.Fatalf("Lines(%q, %q) = %v does not reproduce the inputs", a, b, edits)
+		}
+		changes := 0
+		for _, e := range edits {
+			if e.Kind != Equal {
+				changes++
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); changes != want {
+			t.Fatalf("Lines(%q, %q) has %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+func TestLinesLargeDistance(t *testing.T) {
+	// 编辑距离超过上限时整段替换，结果仍然正确
+	a := make([]string, maxEditDistance)
+	b := make([]string, maxEditDistance)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+	a = append([]string{"same"}, append(a, "tail")...)
+	b = append([]string{"same"}, append(b, "tail")...)
+	edits := Lines(a, b)
+	gotA, gotB := apply(edits)
+	if !equal(gotA, a) || !equal(gotB, b) {
+		t.Fatal("edits do not reproduce the inputs")
+	}
+	if edits[0] != (Edit{Equal, "same"}) || edits[len(edits)-1] != (Edit{Equal, "tail"}) {
+		t.Errorf("common prefix or suffix not kept: first %v, last %v", edits[0], edits[len(edits)-1])
+	}
+}
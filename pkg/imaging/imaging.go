@@ -0,0 +1,237 @@
+// Package imaging 处理用户上传的图片：校验格式和尺寸、按EXIF方向摆正、重新编码去掉元数据并生成缩略图
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	jpegQuality = 90
+	// 动图最多的帧数
+	maxGIFFrames = 1000
+	// 动图所有帧的像素总数不超过MaxPixels的这个倍数，gif每个像素解码后占1字节，与一张同样像素上限的NRGBA图片相当
+	gifPixelsFactor = 4
+)
+
+var (
+	ErrUnsupportedType = errors.New("不支持的图片格式")
+	ErrTooManyPixels   = errors.New("图片尺寸过大")
+	ErrInvalidImage    = errors.New("无法解析的图片")
+	ErrTooManyFrames   = errors.New("动图帧数过多")
+)
+
+// Options 图片处理的参数
+type Options struct {
+	MaxPixels     int // 宽×高的上限，防止解码时占用过多内存
+	ThumbnailSize int // 缩略图最长边的像素数
+}
+
+// Result 处理后的图片及缩略图，都不含EXIF等元数据
+type Result struct {
+	ContentType      string
+	Ext              string
+	Data             []byte
+	Width            int
+	Height           int
+	Thumb            []byte
+	ThumbContentType string
+	ThumbExt         string
+}
+
+// Process 按文件内容识别格式（只支持jpeg、png、gif），解码后重新编码
+// 重新编码只保留像素数据，EXIF、GPS等元数据都会被去掉，jpeg会先按EXIF中的方向旋转
+func Process(data []byte, opt Options) (*Result, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if opt.MaxPixels > 0 && cfg.Width*cfg.Height > opt.MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	if contentType == "image/gif" {
+		return processGIF(data, opt)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	src := toNRGBA(img)
+	if contentType == "image/jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+	res := &Result{
+		ContentType: contentType,
+		Width:       src.Rect.Dx(),
+		Height:      src.Rect.Dy(),
+	}
+	thumb := Thumbnail(src, opt.ThumbnailSize)
+	if contentType == "image/jpeg" {
+		res.Ext, res.ThumbExt, res.ThumbContentType = ".jpg", ".jpg", "image/jpeg"
+		if res.Data, err = encodeJPEG(src); err != nil {
+			return nil, err
+		}
+		res.Thumb, err = encodeJPEG(thumb)
+	} else {
+		res.Ext, res.ThumbExt, res.ThumbContentType = ".png", ".png", "image/png"
+		if res.Data, err = encodePNG(src); err != nil {
+			return nil, err
+		}
+		res.Thumb, err = encodePNG(thumb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// processGIF 保留动画的所有帧，缩略图取第一帧
+func processGIF(data []byte, opt Options) (*Result, error) {
+	// DecodeAll会一次解码所有帧，先扫描帧的个数及尺寸，防止压缩率很高的大量帧解码后占用过多内存
+	frames, pixels, err := scanGIF(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if frames > maxGIFFrames {
+		return nil, ErrTooManyFrames
+	}
+	if opt.MaxPixels > 0 && pixels > opt.MaxPixels*gifPixelsFactor {
+		return nil, ErrTooManyPixels
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if len(g.Image) == 0 {
+		return nil, ErrInvalidImage
+	}
+	// EncodeAll只写入帧、延时、处置方式及循环次数，注释和XMP等扩展块会被去掉
+	var buf bytes.Buffer
+	err = gif.EncodeAll(&buf, &gif.GIF{
+		Image:           g.Image,
+		Delay:           g.Delay,
+		Disposal:        g.Disposal,
+		LoopCount:       g.LoopCount,
+		Config:          g.Config,
+		BackgroundIndex: g.BackgroundIndex,
+	})
+	if err != nil {
+		return nil, err
+	}
+	first := image.NewNRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	draw.Draw(first, g.Image[0].Bounds(), g.Image[0], g.Image[0].Bounds().Min, draw.Over)
+	thumb, err := encodePNG(Thumbnail(first, opt.ThumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		ContentType:      "image/gif",
+		Ext:              ".gif",
+		Data:             buf.Bytes(),
+		Width:            g.Config.Width,
+		Height:           g.Config.Height,
+		Thumb:            thumb,
+		ThumbContentType: "image/png",
+		ThumbExt:         ".png",
+	}, nil
+}
+
+// scanGIF 不解码像素，只按块结构遍历gif，返回帧数及所有帧的像素总数
+func scanGIF(data []byte) (frames, pixels int, err error) {
+	errTruncated := errors.New("gif: truncated")
+	if len(data) < 13 {
+		return 0, 0, errTruncated
+	}
+	i := 13 // 文件头及逻辑屏幕描述符
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1) // 全局颜色表
+	}
+	// skipSubBlocks 跳过以长度为0的块结束的数据子块
+	skipSubBlocks := func() error {
+		for {
+			if i >= len(data) {
+				return errTruncated
+			}
+			n := int(data[i])
+			i += 1 + n
+			if n == 0 {
+				return nil
+			}
+		}
+	}
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // 扩展块
+			i += 2
+			if err = skipSubBlocks(); err != nil {
+				return
+			}
+		case 0x2C: // 图像描述符
+			if i+10 > len(data) {
+				return 0, 0, errTruncated
+			}
+			w, h := int(data[i+5])|int(data[i+6])<<8, int(data[i+7])|int(data[i+8])<<8
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1) // 局部颜色表
+			}
+			i++ // LZW最小码长
+			if err = skipSubBlocks(); err != nil {
+				return
+			}
+			frames++
+			pixels += w * h
+		case 0x3B: // 文件结束
+			return frames, pixels, nil
+		default:
+			return 0, 0, fmt.Errorf("gif: unknown block type %#x", data[i])
+		}
+	}
+	// 缺少结束标记时gif包也能解码，按已扫描到的帧计算
+	return frames, pixels, nil
+}
+
+// toNRGBA 转成坐标从(0,0)开始的NRGBA，方便直接操作像素
+func toNRGBA(img image.Image) *image.NRGBA {
+	if dst, ok := img.(*image.NRGBA); ok && dst.Rect.Min == (image.Point{}) {
+		return dst
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
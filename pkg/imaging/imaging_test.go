@@ -0,0 +1,312 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// grid 用宽w、高h的值生成图片，每个像素的红色通道保存它的值
+func grid(w, h int, values ...uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i, v := range values {
+		img.SetNRGBA(i%w, i/w, color.NRGBA{R: v, A: 255})
+	}
+	return img
+}
+
+func values(img *image.NRGBA) (w, h int, vs []uint8) {
+	w, h = img.Rect.Dx(), img.Rect.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			vs = append(vs, img.NRGBAAt(x, y).R)
+		}
+	}
+	return
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 正常方向显示的图片为
+	// 1 2 3
+	// 4 5 6
+	// stored是各个EXIF方向下相机实际保存的像素
+	tests := []struct {
+		orientation int
+		w, h        int
+		stored      []uint8
+	}{
+		{1, 3, 2, []uint8{1, 2, 3, 4, 5, 6}},
+		{2, 3, 2, []uint8{3, 2, 1, 6, 5, 4}},
+		{3, 3, 2, []uint8{6, 5, 4, 3, 2, 1}},
+		{4, 3, 2, []uint8{4, 5, 6, 1, 2, 3}},
+		{5, 2, 3, []uint8{1, 4, 2, 5, 3, 6}},
+		{6, 2, 3, []uint8{3, 6, 2, 5, 1, 4}},
+		{7, 2, 3, []uint8{6, 3, 5, 2, 4, 1}},
+		{8, 2, 3, []uint8{4, 1, 5, 2, 6, 3}},
+	}
+	want := []uint8{1, 2, 3, 4, 5, 6}
+	for _, tt := range tests {
+		got := applyOrientation(grid(tt.w, tt.h, tt.stored...), tt.orientation)
+		w, h, vs := values(got)
+		if w != 3 || h != 2 || !bytes.Equal(vs, want) {
+			t.Errorf("orientation %d: got %dx%d %v, want 3x2 %v", tt.orientation, w, h, vs, want)
+		}
+	}
+	// 不合法的方向原样返回
+	src := grid(3, 2, want...)
+	if got := applyOrientation(src, 9); got != src {
+		t.Error("orientation 9 should return the source image")
+	}
+}
+
+// exifSegment 生成只包含方向标签的APP1段
+func exifSegment(orientation int, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // IFD0的偏移
+	order.PutUint16(tiff[8:], 1) // 标签数
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1) // 个数
+	order.PutUint16(tiff[18:], uint16(orientation))
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// jpegWithExif 编码jpeg并在SOI之后插入EXIF段
+func jpegWithExif(t *testing.T, img image.Image, orientation int, order binary.ByteOrder) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(orientation, order)...)
+	return append(out, data[2:]...)
+}
+
+// hasSegment 判断jpeg在图像数据之前是否有marker段
+func hasSegment(data []byte, marker byte) bool {
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		if data[i+1] == marker {
+			return true
+		}
+		if data[i+1] == 0xDA {
+			return false
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	return false
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	img := grid(4, 4)
+	for o := 1; o <= 8; o++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if got := jpegOrientation(jpegWithExif(t, img, o, order)); got != o {
+				t.Errorf("orientation %d (%v): got %d", o, order, got)
+			}
+		}
+	}
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	broken := jpegWithExif(t, img, 6, binary.BigEndian)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no exif", plain.Bytes()},
+		{"not jpeg", []byte("GIF89a")},
+		{"truncated", broken[:20]},
+		{"invalid value", jpegWithExif(t, img, 9, binary.BigEndian)},
+		{"empty", nil},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != 1 {
+			t.Errorf("%s: got %d, want 1", tt.name, got)
+		}
+	}
+}
+
+func TestProcessStripsExifAndRotates(t *testing.T) {
+	// 左半边黑色、右半边白色的16x8图片，按方向6保存时相机写入的是逆时针旋转90度后的8x16图片
+	upright := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			v := uint8(0)
+			if x >= 8 {
+				v = 255
+			}
+			upright.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	stored := image.NewNRGBA(image.Rect(0, 0, 8, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 8; x++ {
+			stored.Set(x, y, upright.At(15-y, x))
+		}
+	}
+	data := jpegWithExif(t, stored, 6, binary.BigEndian)
+	if !hasSegment(data, 0xE1) {
+		t.Fatal("test input has no APP1 segment")
+	}
+
+	res, err := Process(data, Options{MaxPixels: 1000, ThumbnailSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ContentType != "image/jpeg" || res.Ext != ".jpg" || res.Width != 16 || res.Height != 8 {
+		t.Errorf("result = %s %s %dx%d, want image/jpeg .jpg 16x8", res.ContentType, res.Ext, res.Width, res.Height)
+	}
+	for name, out := range map[string][]byte{"image": res.Data, "thumbnail": res.Thumb} {
+		if hasSegment(out, 0xE1) || bytes.Contains(out, []byte("Exif")) {
+			t.Errorf("%s still has EXIF", name)
+		}
+	}
+	got, err := jpeg.Decode(bytes.NewReader(res.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := got.Bounds(); b.Dx() != 16 || b.Dy() != 8 {
+		t.Fatalf("decoded size = %v", b)
+	}
+	left, _, _, _ := got.At(2, 4).RGBA()
+	right, _, _, _ := got.At(13, 4).RGBA()
+	if left>>8 > 40 || right>>8 < 215 {
+		t.Errorf("image not rotated upright: left %d, right %d", left>>8, right>>8)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(res.Thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 4 || b.Dy() != 2 {
+		t.Errorf("thumbnail size = %v, want 4x2", b)
+	}
+}
+
+func TestProcessMaxPixels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, grid(100, 50)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Process(buf.Bytes(), Options{MaxPixels: 4999}); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("MaxPixels 4999: err = %v, want ErrTooManyPixels", err)
+	}
+	res, err := Process(buf.Bytes(), Options{MaxPixels: 5000})
+	if err != nil {
+		t.Fatalf("MaxPixels 5000: %v", err)
+	}
+	if res.ContentType != "image/png" || res.Width != 100 || res.Height != 50 {
+		t.Errorf("result = %s %dx%d", res.ContentType, res.Width, res.Height)
+	}
+}
+
+func TestProcessInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, grid(4, 4)); err != nil {
+		t.Fatal(err)
+	}
+	truncated := buf.Bytes()[:40]
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("hello world"), ErrUnsupportedType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrUnsupportedType},
+		{"truncated png", truncated, ErrInvalidImage},
+		{"jpeg header only", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 0}, ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		if _, err := Process(tt.data, Options{}); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{200, 100, 50, 50, 25},
+		{100, 200, 50, 25, 50},
+		{40, 30, 50, 40, 30}, // 不放大
+		{1000, 1, 10, 10, 1},
+		{100, 100, 0, 100, 100},
+	}
+	for _, tt := range tests {
+		got := Thumbnail(image.NewNRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.size)
+		if got.Rect.Dx() != tt.wantW || got.Rect.Dy() != tt.wantH {
+			t.Errorf("Thumbnail(%dx%d, %d) = %v, want %dx%d", tt.w, tt.h, tt.size, got.Rect, tt.wantW, tt.wantH)
+		}
+	}
+	// 透明像素的颜色不参与平均
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	src.SetNRGBA(1, 0, color.NRGBA{B: 255, A: 0})
+	if got := Thumbnail(src, 1).NRGBAAt(0, 0); got != (color.NRGBA{R: 255, A: 127}) {
+		t.Errorf("transparent pixel bled into thumbnail: %v", got)
+	}
+}
+
+// animatedGIF 编码frames帧w×h的单色动图，单色帧的压缩率很高
+func animatedGIF(t *testing.T, w, h, frames int) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	palette := color.Palette{color.Black, color.White}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, w, h), palette))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestScanGIF(t *testing.T) {
+	data := animatedGIF(t, 30, 20, 5)
+	frames, pixels, err := scanGIF(data)
+	if err != nil || frames != 5 || pixels != 5*30*20 {
+		t.Errorf("scanGIF = %d, %d, %v, want 5, 3000, nil", frames, pixels, err)
+	}
+	if _, _, err := scanGIF(data[:len(data)/2]); err == nil {
+		t.Error("scanGIF truncated: want error")
+	}
+}
+
+func TestProcessGIFLimits(t *testing.T) {
+	// 每帧100×100，MaxPixels为10000时最多解码4帧的像素
+	if _, err := Process(animatedGIF(t, 100, 100, 5), Options{MaxPixels: 10000}); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("5 frames: err = %v, want ErrTooManyPixels", err)
+	}
+	res, err := Process(animatedGIF(t, 100, 100, 4), Options{MaxPixels: 10000, ThumbnailSize: 10})
+	if err != nil {
+		t.Fatalf("4 frames: %v", err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(res.Data))
+	if err != nil || len(g.Image) != 4 {
+		t.Errorf("re-encoded gif: %v, %v", g, err)
+	}
+	if _, err := Process(animatedGIF(t, 1, 1, maxGIFFrames+1), Options{}); !errors.Is(err, ErrTooManyFrames) {
+		t.Errorf("too many frames: err = %v, want ErrTooManyFrames", err)
+	}
+}
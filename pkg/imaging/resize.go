@@ -0,0 +1,43 @@
+package imaging
+
+import "image"
+
+// Thumbnail 按比例缩小到最长边不超过size，不放大；使用区域平均，按透明度加权避免透明像素的颜色渗出
+func Thumbnail(src *image.NRGBA, size int) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if size <= 0 || (w <= size && h <= size) {
+		return src
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				p := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(p); i += 4 {
+					pa := uint64(p[i+3])
+					r += uint64(p[i]) * pa
+					g += uint64(p[i+1]) * pa
+					b += uint64(p[i+2]) * pa
+					a += pa
+					n++
+				}
+			}
+			o := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[o] = uint8(r / a)
+				dst.Pix[o+1] = uint8(g / a)
+				dst.Pix[o+2] = uint8(b / a)
+			}
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -1,14 +1,20 @@
 package jwt
 
 import (
+	"bell_best/setting"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/spf13/viper"
 	"time"
 )
 
-// CustomSecret 用于加盐的字符串（用于签名的字符串）
-var CustomSecret = []byte("夏天夏天悄悄过去")
+var (
+	// access token有效期较短，过期后用refresh token换取新的token
+	accessTokenExpire  = 15 * time.Minute
+	refreshTokenExpire = 30 * 24 * time.Hour
+)
 
 // CustomClaims 自定义声明类型 并内嵌jwt.RegisteredClaims
 // jwt包自带的jwt.RegisteredClaims只包含了官方字段
@@ -17,35 +23,72 @@ var CustomSecret = []byte("夏天夏天悄悄过去")
 type CustomClaims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"` // 用户的角色，见pkg/rbac
 	jwt.RegisteredClaims
 }
 
-// GenToken 生成JWT
-func GenToken(userID int64, username string) (string, error) {
+// Init 加载签名密钥并读取token有效期配置，有效期未配置时使用默认值
+func Init(cfg *setting.AuthConfig) (err error) {
+	if cfg == nil {
+		return ErrNoSigningKey
+	}
+	if err = loadKeys(cfg); err != nil {
+		return
+	}
+	if cfg.AccessTokenExpire > 0 {
+		accessTokenExpire = cfg.AccessTokenExpire
+	}
+	if cfg.RefreshTokenExpire > 0 {
+		refreshTokenExpire = cfg.RefreshTokenExpire
+	}
+	return
+}
+
+// AccessTokenExpire access token的有效期
+func AccessTokenExpire() time.Duration { return accessTokenExpire }
+
+// RefreshTokenExpire refresh token的有效期
+func RefreshTokenExpire() time.Duration { return refreshTokenExpire }
+
+// GenToken 生成JWT，同时返回token的唯一id(jti)，注销时根据jti拉黑token
+func GenToken(userID int64, username, role string) (token, tokenID string, err error) {
+	tokenID, err = randomString(16, hex.EncodeToString)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
 	// 创建一个我们自己的声明
 	claims := CustomClaims{
 		userID,
 		username, // 自定义字段
+		role,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(viper.GetInt("auth.jwt_expire")) * time.Hour)),
+			ID:        tokenID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenExpire)),
 			Issuer:    "bluebell", // 签发人
 		},
 	}
-	// 使用指定的签名方法创建签名对象
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// 使用指定的secret签名并获得完整的编码后的字符串token
-	return token.SignedString(CustomSecret)
+	// 使用当前签名密钥的算法创建签名对象，并在header中写入kid
+	t := jwt.NewWithClaims(signingKey.method, claims)
+	t.Header["kid"] = signingKey.kid
+	// 使用签名密钥签名并获得完整的编码后的字符串token
+	token, err = t.SignedString(signingKey.sign)
+	return token, tokenID, err
+}
+
+// GenRefreshToken 生成refresh token，refresh token只是一个随机字符串，会话信息保存在服务端
+func GenRefreshToken() (string, error) {
+	return randomString(32, base64.RawURLEncoding.EncodeToString)
 }
 
 // ParseToken 解析JWT
 func ParseToken(tokenString string) (*CustomClaims, error) {
 	// 解析token
 	// 如果是自定义Claim结构体则需要使用 ParseWithClaims 方法
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (i interface{}, err error) {
-		// 直接使用标准的Claim则可以直接使用Parse方法
-		//token, err := jwt.Parse(tokenString, func(token *jwt.Token) (i interface{}, err error) {
-		return CustomSecret, nil
-	})
+	// keyFunc 根据kid选择校验密钥并检查签名算法
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, keyFunc,
+		jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}))
 	if err != nil {
 		return nil, err
 	}
@@ -56,3 +99,11 @@ func ParseToken(tokenString string) (*CustomClaims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
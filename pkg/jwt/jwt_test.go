@@ -0,0 +1,277 @@
+package jwt
+
+import (
+	"bell_best/setting"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret  = "0123456789abcdef0123456789abcdef"
+	testSecret2 = "fedcba9876543210fedcba9876543210"
+)
+
+// testKeys 测试用的非对称密钥，PEM文件写在临时目录中
+type testKeys struct {
+	rsa       *rsa.PrivateKey
+	ed        ed25519.PrivateKey
+	rsaPriv   string
+	rsaPub    string
+	rsaPubPEM []byte
+	edPriv    string
+	edPub     string
+	dir       string
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	k := &testKeys{dir: t.TempDir()}
+	var err error
+	if k.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	_, k.ed, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, typ string, der []byte) (string, []byte) {
+		data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+		path := filepath.Join(k.dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path, data
+	}
+	marshal := func(der []byte, err error) []byte {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	k.rsaPriv, _ = write("rs.pem", "PRIVATE KEY", marshal(x509.MarshalPKCS8PrivateKey(k.rsa)))
+	k.rsaPub, k.rsaPubPEM = write("rs.pub.pem", "PUBLIC KEY", marshal(x509.MarshalPKIXPublicKey(&k.rsa.PublicKey)))
+	k.edPriv, _ = write("ed.pem", "PRIVATE KEY", marshal(x509.MarshalPKCS8PrivateKey(k.ed)))
+	k.edPub, _ = write("ed.pub.pem", "PUBLIC KEY", marshal(x509.MarshalPKIXPublicKey(k.ed.Public())))
+	return k
+}
+
+func mustInit(t *testing.T, cfg *setting.AuthConfig) {
+	t.Helper()
+	if err := Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInitValidation(t *testing.T) {
+	keys := newTestKeys(t)
+	hs := &setting.JWTKeyConfig{KID: "hs", Alg: "HS256", Secret: testSecret}
+	tests := []struct {
+		name string
+		cfg  *setting.AuthConfig
+		want error
+	}{
+		{"nil config", nil, ErrNoSigningKey},
+		{"no keys", &setting.AuthConfig{SigningKeyID: "hs"}, ErrNoSigningKey},
+		{"unknown signing kid", &setting.AuthConfig{SigningKeyID: "other", Keys: []*setting.JWTKeyConfig{hs}}, ErrNoSigningKey},
+		{"short HS256 secret", &setting.AuthConfig{SigningKeyID: "hs",
+			Keys: []*setting.JWTKeyConfig{{KID: "hs", Alg: "HS256", Secret: testSecret[:minHMACSecretLen-1]}}}, ErrHMACSecretTooShort},
+		{"unsupported alg", &setting.AuthConfig{SigningKeyID: "hs",
+			Keys: []*setting.JWTKeyConfig{{KID: "hs", Alg: "HS512", Secret: testSecret}}}, ErrUnsupportedAlg},
+		{"none alg", &setting.AuthConfig{SigningKeyID: "hs",
+			Keys: []*setting.JWTKeyConfig{{KID: "hs", Alg: "none"}}}, ErrUnsupportedAlg},
+		{"verify-only RSA key for signing", &setting.AuthConfig{SigningKeyID: "rs",
+			Keys: []*setting.JWTKeyConfig{{KID: "rs", Alg: "RS256", PublicKeyFile: keys.rsaPub}}}, ErrNoSigningKey},
+		{"verify-only EdDSA key for signing", &setting.AuthConfig{SigningKeyID: "ed",
+			Keys: []*setting.JWTKeyConfig{{KID: "ed", Alg: "EdDSA", PublicKeyFile: keys.edPub}}}, ErrNoSigningKey},
+	}
+	for _, tt := range tests {
+		if err := Init(tt.cfg); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// 没有kid、kid重复及读取不到PEM文件也不能通过
+	for name, cfg := range map[string]*setting.AuthConfig{
+		"missing kid":   {SigningKeyID: "hs", Keys: []*setting.JWTKeyConfig{{Alg: "HS256", Secret: testSecret}}},
+		"duplicate kid": {SigningKeyID: "hs", Keys: []*setting.JWTKeyConfig{hs, hs}},
+		"missing file":  {SigningKeyID: "rs", Keys: []*setting.JWTKeyConfig{{KID: "rs", Alg: "RS256", PrivateKeyFile: filepath.Join(keys.dir, "missing.pem")}}},
+	} {
+		if err := Init(cfg); err == nil {
+			t.Errorf("%s: Init succeeded", name)
+		}
+	}
+}
+
+func TestSignAndVerifyWithRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	hs := &setting.JWTKeyConfig{KID: "hs", Alg: "HS256", Secret: testSecret}
+	rs := &setting.JWTKeyConfig{KID: "rs", Alg: "RS256", PrivateKeyFile: keys.rsaPriv}
+	ed := &setting.JWTKeyConfig{KID: "ed", Alg: "EdDSA", PrivateKeyFile: keys.edPriv}
+
+	// 每种算法签发的token都能被自己校验，header中带有签发密钥的kid
+	tokens := make(map[string]string)
+	for _, kc := range []*setting.JWTKeyConfig{hs, rs, ed} {
+		mustInit(t, &setting.AuthConfig{SigningKeyID: kc.KID, Keys: []*setting.JWTKeyConfig{hs, rs, ed}})
+		token, tokenID, err := GenToken(1, "alice", "user")
+		if err != nil {
+			t.Fatalf("%s: GenToken: %v", kc.KID, err)
+		}
+		mc, err := ParseToken(token)
+		if err != nil {
+			t.Fatalf("%s: ParseToken: %v", kc.KID, err)
+		}
+		if mc.UserID != 1 || mc.Username != "alice" || mc.Role != "user" || mc.ID != tokenID {
+			t.Errorf("%s: claims = %+v", kc.KID, mc)
+		}
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &CustomClaims{})
+		if err != nil || parsed.Header["kid"] != kc.KID || parsed.Method.Alg() != kc.Alg {
+			t.Errorf("%s: header = %v, %v", kc.KID, parsed.Header, err)
+		}
+		tokens[kc.KID] = token
+	}
+
+	// 轮换：新密钥签发，旧密钥只用于校验，旧token在旧密钥删除前仍然有效
+	rsVerify := &setting.JWTKeyConfig{KID: "rs", Alg: "RS256", PublicKeyFile: keys.rsaPub}
+	hs2 := &setting.JWTKeyConfig{KID: "hs2", Alg: "HS256", Secret: testSecret2}
+	mustInit(t, &setting.AuthConfig{SigningKeyID: "hs2", Keys: []*setting.JWTKeyConfig{hs2, hs, rsVerify}})
+	for _, kid := range []string{"hs", "rs"} {
+		if _, err := ParseToken(tokens[kid]); err != nil {
+			t.Errorf("token signed by %s after rotation: %v", kid, err)
+		}
+	}
+	if _, err := ParseToken(tokens["ed"]); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token signed by removed key ed: err = %v, want ErrUnknownKey", err)
+	}
+	token, _, err := GenToken(2, "bob", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(token); err != nil {
+		t.Errorf("token signed by new key: %v", err)
+	}
+
+	// 旧密钥删除后它签发的token失效
+	mustInit(t, &setting.AuthConfig{SigningKeyID: "hs2", Keys: []*setting.JWTKeyConfig{hs2}})
+	if _, err := ParseToken(tokens["hs"]); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token signed by removed key hs: err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseTokenRejectsForgedTokens(t *testing.T) {
+	keys := newTestKeys(t)
+	mustInit(t, &setting.AuthConfig{SigningKeyID: "hs", Keys: []*setting.JWTKeyConfig{
+		{KID: "hs", Alg: "HS256", Secret: testSecret},
+		{KID: "rs", Alg: "RS256", PublicKeyFile: keys.rsaPub},
+	}})
+	claims := func(expire time.Duration) CustomClaims {
+		now := time.Now()
+		return CustomClaims{UserID: 1, Username: "alice", Role: "admin", RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expire)),
+		}}
+	}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, c CustomClaims) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := sign(jwt.SigningMethodHS256, "hs", []byte(testSecret), claims(time.Minute))
+	if _, err := ParseToken(valid); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	none := sign(jwt.SigningMethodNone, "hs", jwt.UnsafeAllowNoneSignatureType, claims(time.Minute))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error // 为nil时只要求返回错误
+	}{
+		// 用公开的RSA公钥作为HMAC密钥伪造token，alg与kid对应密钥的算法不一致
+		{"HS256 with RSA public key as secret", sign(jwt.SigningMethodHS256, "rs", keys.rsaPubPEM, claims(time.Minute)), ErrUnsupportedAlg},
+		{"RS256 with kid of HS256 key", sign(jwt.SigningMethodRS256, "hs", otherRSA, claims(time.Minute)), ErrUnsupportedAlg},
+		{"RS256 signed by another key", sign(jwt.SigningMethodRS256, "rs", otherRSA, claims(time.Minute)), jwt.ErrTokenSignatureInvalid},
+		{"wrong HMAC secret", sign(jwt.SigningMethodHS256, "hs", []byte(testSecret2), claims(time.Minute)), jwt.ErrTokenSignatureInvalid},
+		{"unknown kid", sign(jwt.SigningMethodHS256, "other", []byte(testSecret), claims(time.Minute)), ErrUnknownKey},
+		{"missing kid", sign(jwt.SigningMethodHS256, "", []byte(testSecret), claims(time.Minute)), ErrUnknownKey},
+		{"alg none", none, nil},
+		{"expired", sign(jwt.SigningMethodHS256, "hs", []byte(testSecret), claims(-time.Minute)), jwt.ErrTokenExpired},
+		{"malformed", "not.a.token", jwt.ErrTokenMalformed},
+	}
+	for _, tt := range tests {
+		mc, err := ParseToken(tt.token)
+		if err == nil || mc != nil {
+			t.Errorf("%s: ParseToken = %+v, %v, want error", tt.name, mc, err)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	mustInit(t, &setting.AuthConfig{SigningKeyID: "hs", Keys: []*setting.JWTKeyConfig{{KID: "hs", Alg: "HS256", Secret: testSecret}}})
+	if HasPublicKeys() || len(JWKS().Keys) != 0 {
+		t.Errorf("HS256 keys must not be published: %+v", JWKS())
+	}
+
+	mustInit(t, &setting.AuthConfig{SigningKeyID: "rs", Keys: []*setting.JWTKeyConfig{
+		{KID: "hs", Alg: "HS256", Secret: testSecret},
+		{KID: "rs", Alg: "RS256", PrivateKeyFile: keys.rsaPriv},
+		{KID: "ed", Alg: "EdDSA", PublicKeyFile: keys.edPub},
+	}})
+	set := JWKS()
+	if !HasPublicKeys() || len(set.Keys) != 2 {
+		t.Fatalf("JWKS = %+v, want ed and rs", set)
+	}
+	decode := func(s string) []byte {
+		t.Helper()
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	// 按kid排序
+	ed, rs := set.Keys[0], set.Keys[1]
+	if ed.Kty != "OKP" || ed.Kid != "ed" || ed.Alg != "EdDSA" || ed.Use != "sig" || ed.Crv != "Ed25519" || ed.N != "" {
+		t.Errorf("OKP key = %+v", ed)
+	}
+	if x := decode(ed.X); !ed25519.PublicKey(x).Equal(keys.ed.Public()) {
+		t.Errorf("OKP x = %x", x)
+	}
+	if rs.Kty != "RSA" || rs.Kid != "rs" || rs.Alg != "RS256" || rs.Use != "sig" || rs.X != "" {
+		t.Errorf("RSA key = %+v", rs)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(decode(rs.N)), E: int(new(big.Int).SetBytes(decode(rs.E)).Int64())}
+	if !pub.Equal(&keys.rsa.PublicKey) {
+		t.Errorf("RSA n/e do not match the public key")
+	}
+	if rs.E != "AQAB" {
+		t.Errorf("RSA e = %q, want AQAB", rs.E)
+	}
+}
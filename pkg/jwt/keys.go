@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"bell_best/setting"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 签名密钥从配置中读取，每个密钥有一个kid，签发token时写入header
+// 轮换密钥时先加入新密钥并设为signing_key_id，旧密钥保留到它签发的token全部过期后再删除
+
+const minHMACSecretLen = 32
+
+var (
+	ErrUnknownKey         = errors.New("unknown signing key")
+	ErrNoSigningKey       = errors.New("no signing key configured")
+	ErrUnsupportedAlg     = errors.New("unsupported signing algorithm")
+	ErrHMACSecretTooShort = fmt.Errorf("HS256 secret must be at least %d bytes", minHMACSecretLen)
+)
+
+// key 一个签名密钥，sign为nil表示只用于校验
+type key struct {
+	kid    string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+var (
+	signingKey *key
+	verifyKeys = map[string]*key{}
+)
+
+// loadKeys 加载配置中的全部密钥
+func loadKeys(cfg *setting.AuthConfig) error {
+	keys := make(map[string]*key, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		if kc.KID == "" {
+			return errors.New("jwt key without kid")
+		}
+		if _, ok := keys[kc.KID]; ok {
+			return fmt.Errorf("duplicate jwt key %q", kc.KID)
+		}
+		k, err := loadKey(kc)
+		if err != nil {
+			return fmt.Errorf("load jwt key %q: %w", kc.KID, err)
+		}
+		keys[k.kid] = k
+	}
+	sk, ok := keys[cfg.SigningKeyID]
+	if !ok || sk.sign == nil {
+		return fmt.Errorf("%w: %q", ErrNoSigningKey, cfg.SigningKeyID)
+	}
+	signingKey = sk
+	verifyKeys = keys
+	return nil
+}
+
+func loadKey(kc *setting.JWTKeyConfig) (*key, error) {
+	k := &key{kid: kc.KID}
+	switch kc.Alg {
+	case "HS256":
+		if len(kc.Secret) < minHMACSecretLen {
+			return nil, ErrHMACSecretTooShort
+		}
+		k.method = jwt.SigningMethodHS256
+		k.sign = []byte(kc.Secret)
+		k.verify = k.sign
+	case "RS256":
+		k.method = jwt.SigningMethodRS256
+		if kc.PrivateKeyFile != "" {
+			b, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(b)
+			if err != nil {
+				return nil, err
+			}
+			k.sign, k.verify = priv, &priv.PublicKey
+		} else {
+			b, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if k.verify, err = jwt.ParseRSAPublicKeyFromPEM(b); err != nil {
+				return nil, err
+			}
+		}
+	case "EdDSA":
+		k.method = jwt.SigningMethodEdDSA
+		if kc.PrivateKeyFile != "" {
+			b, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(b)
+			if err != nil {
+				return nil, err
+			}
+			k.sign, k.verify = priv, priv.(crypto.Signer).Public()
+		} else {
+			b, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if k.verify, err = jwt.ParseEdPublicKeyFromPEM(b); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, kc.Alg)
+	}
+	return k, nil
+}
+
+// keyFunc 根据header中的kid选择校验密钥，并且要求alg与该密钥一致，防止算法替换攻击
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := verifyKeys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, token.Method.Alg())
+	}
+	return k.verify, nil
+}
+
+// JWK 公钥的JSON Web Key表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+}
+
+// JWKSet /.well-known/jwks.json 返回的数据
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// HasPublicKeys 是否配置了非对称密钥
+func HasPublicKeys() bool {
+	return len(JWKS().Keys) > 0
+}
+
+// JWKS 返回所有非对称密钥的公钥，HS256密钥不会出现在这里
+func JWKS() *JWKSet {
+	set := &JWKSet{Keys: make([]JWK, 0, len(verifyKeys))}
+	kids := make([]string, 0, len(verifyKeys))
+	for kid := range verifyKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		k := verifyKeys[kid]
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: k.kid,
+				Alg: k.method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: k.kid,
+				Alg: k.method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
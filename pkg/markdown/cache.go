@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// Rendered 帖子正文的渲染结果
+type Rendered struct {
+	HTML    string
+	Excerpt string
+}
+
+// Cache 按帖子id缓存渲染结果，正文的哈希变了就重新渲染，超出容量时淘汰最久未使用的
+// 渲染结果只取决于正文，多个实例各自缓存也不会不一致
+type Cache struct {
+	mu           sync.Mutex
+	size         int
+	excerptRunes int
+	ll           *list.List
+	items        map[int64]*list.Element
+}
+
+type cacheEntry struct {
+	id       int64
+	hash     [sha256.Size]byte
+	rendered *Rendered
+}
+
+// NewCache 创建最多缓存size篇帖子的缓存，摘要最多excerptRunes个字符
+func NewCache(size, excerptRunes int) *Cache {
+	return &Cache{
+		size:         size,
+		excerptRunes: excerptRunes,
+		ll:           list.New(),
+		items:        make(map[int64]*list.Element),
+	}
+}
+
+// Render 返回帖子正文的渲染结果，缓存未命中时渲染并缓存
+func (c *Cache) Render(id int64, src string) *Rendered {
+	hash := sha256.Sum256([]byte(src))
+	c.mu.Lock()
+	if el, ok := c.items[id]; ok && el.Value.(*cacheEntry).hash == hash {
+		c.ll.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*cacheEntry).rendered
+	}
+	c.mu.Unlock()
+
+	// 渲染不持有锁
+	h := Render(src)
+	r := &Rendered{HTML: h, Excerpt: truncate(htmlToText(h), c.excerptRunes)}
+	entry := &cacheEntry{id: id, hash: hash, rendered: r}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[id]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return r
+	}
+	c.items[id] = c.ll.PushFront(entry)
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).id)
+	}
+	return r
+}
@@ -0,0 +1,386 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+const linkRel = "nofollow noopener noreferrer"
+
+// inlineRenderer 渲染段落内的行内元素，所有普通文本都做HTML转义
+type inlineRenderer struct {
+	b      *strings.Builder
+	inLink bool // 链接文字中不再生成链接
+}
+
+func renderInline(s string) string {
+	var b strings.Builder
+	r := &inlineRenderer{b: &b}
+	r.render(s)
+	return b.String()
+}
+
+func (r *inlineRenderer) render(s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				r.escapeByte(s[i+1])
+				i += 2
+				continue
+			}
+		case hardBreak[0]:
+			r.b.WriteString("<br>")
+			i++
+			continue
+		case '`':
+			n := runLen(s, i)
+			if end := findRun(s, i+n, '`', n); end >= 0 {
+				r.codeSpan(s[i+n : end])
+				i = end + n
+				continue
+			}
+			// 没有配对的反引号，整段原样输出
+			r.b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '*', '_', '~':
+			if end, ok := r.emphasis(s, i); ok {
+				i = end
+				continue
+			}
+			// 不能配对的整段分隔符原样输出
+			n := runLen(s, i)
+			r.b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				if end, ok := r.link(s, i+1, true); ok {
+					i = end
+					continue
+				}
+			}
+		case '[':
+			if end, ok := r.link(s, i, false); ok {
+				i = end
+				continue
+			}
+		case '<':
+			if end, ok := r.autolink(s, i); ok {
+				i = end
+				continue
+			}
+		}
+		r.escapeByte(c)
+		i++
+	}
+}
+
+func (r *inlineRenderer) escapeByte(c byte) {
+	switch c {
+	case '<':
+		r.b.WriteString("&lt;")
+	case '>':
+		r.b.WriteString("&gt;")
+	case '&':
+		r.b.WriteString("&amp;")
+	case '"':
+		r.b.WriteString("&#34;")
+	case '\'':
+		r.b.WriteString("&#39;")
+	default:
+		r.b.WriteByte(c)
+	}
+}
+
+func (r *inlineRenderer) codeSpan(code string) {
+	code = strings.NewReplacer("\n", " ", hardBreak, " ").Replace(code)
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		code = code[1 : len(code)-1]
+	}
+	r.b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+}
+
+// emphasis 解析*斜体*、**粗体**、_斜体_、__粗体__及~~删除线~~，返回结束位置
+func (r *inlineRenderer) emphasis(s string, i int) (int, bool) {
+	c := s[i]
+	n := runLen(s, i)
+	// 单词中间的_不算强调，避免snake_case被误解析
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		return 0, false
+	}
+	if n > 2 || (c == '~' && n != 2) {
+		return 0, false
+	}
+	open := i + n
+	if open >= len(s) || isSpace(s[open]) {
+		return 0, false
+	}
+	end := findCloser(s, open, c, n)
+	if end < 0 {
+		return 0, false
+	}
+	tag := "em"
+	switch {
+	case c == '~':
+		tag = "del"
+	case n == 2:
+		tag = "strong"
+	}
+	r.b.WriteString("<" + tag + ">")
+	r.render(s[open:end])
+	r.b.WriteString("</" + tag + ">")
+	return end + n, true
+}
+
+// findCloser 查找长度恰好为d的结束符，跳过转义字符和行内代码
+func findCloser(s string, from int, c byte, d int) int {
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			n := runLen(s, j)
+			if end := findRun(s, j+n, '`', n); end >= 0 {
+				j = end + n
+			} else {
+				j += n
+			}
+			continue
+		case c:
+			n := runLen(s, j)
+			if n == d && j > from && !isSpace(s[j-1]) && (c != '_' || j+n >= len(s) || !isAlnum(s[j+n])) {
+				return j
+			}
+			j += n
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+// link 解析[文字](地址 "标题")及![描述](地址 "标题")，i为[的位置
+func (r *inlineRenderer) link(s string, i int, image bool) (int, bool) {
+	if r.inLink && !image {
+		return 0, false
+	}
+	label := matchBracket(s, i)
+	if label < 0 || label+1 >= len(s) || s[label+1] != '(' {
+		return 0, false
+	}
+	dest, title, end, ok := parseLinkTail(s, label+2)
+	if !ok {
+		return 0, false
+	}
+	text := s[i+1 : label]
+	u := safeURL(dest, image)
+	switch {
+	case image && u == "":
+		r.b.WriteString(html.EscapeString(PlainText(text)))
+	case image:
+		r.b.WriteString(`<img src="` + html.EscapeString(u) + `" alt="` + html.EscapeString(PlainText(text)) + `"`)
+		if title != "" {
+			r.b.WriteString(` title="` + html.EscapeString(title) + `"`)
+		}
+		r.b.WriteString(">")
+	case u == "":
+		r.render(text)
+	default:
+		r.b.WriteString(`<a href="` + html.EscapeString(u) + `"`)
+		if title != "" {
+			r.b.WriteString(` title="` + html.EscapeString(title) + `"`)
+		}
+		r.b.WriteString(` rel="` + linkRel + `">`)
+		r.inLink = true
+		r.render(text)
+		r.inLink = false
+		r.b.WriteString("</a>")
+	}
+	return end, true
+}
+
+// matchBracket 返回与s[i]处的[配对的]的位置
+func matchBracket(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			n := runLen(s, j)
+			if end := findRun(s, j+n, '`', n); end >= 0 {
+				j = end + n - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// parseLinkTail 解析(之后的地址和可选的标题，返回)之后的位置
+func parseLinkTail(s string, i int) (dest, title string, end int, ok bool) {
+	i = skipSpaces(s, i)
+	if i < len(s) && s[i] == '<' {
+		j := strings.IndexAny(s[i+1:], "<>\n")
+		if j < 0 || s[i+1+j] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[i+1 : i+1+j]
+		i += j + 2
+	} else {
+		start, depth := i, 0
+	loop:
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					break loop
+				}
+				depth--
+			case ' ', '\n', hardBreak[0]:
+				break loop
+			}
+		}
+		if i > len(s) {
+			return "", "", 0, false
+		}
+		dest = s[start:i]
+	}
+	i = skipSpaces(s, i)
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		q := s[i]
+		j := i + 1
+		for j < len(s) && s[j] != q {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(s) {
+			return "", "", 0, false
+		}
+		title = unescape(s[i+1 : j])
+		i = skipSpaces(s, j+1)
+	}
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return unescape(dest), title, i + 1, true
+}
+
+// autolink 解析<https://example.com>形式的链接
+func (r *inlineRenderer) autolink(s string, i int) (int, bool) {
+	j := strings.IndexAny(s[i+1:], "<> \n")
+	if j < 0 || s[i+1+j] != '>' || r.inLink {
+		return 0, false
+	}
+	u := s[i+1 : i+1+j]
+	lower := strings.ToLower(u)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+		return 0, false
+	}
+	if safeURL(u, false) == "" {
+		return 0, false
+	}
+	text := u
+	if strings.HasPrefix(lower, "mailto:") {
+		text = u[len("mailto:"):]
+	}
+	r.b.WriteString(`<a href="` + html.EscapeString(u) + `" rel="` + linkRel + `">` + html.EscapeString(text) + "</a>")
+	return i + j + 2, true
+}
+
+// safeURL 只允许http、https、mailto（图片只允许http、https）及相对地址，其余返回空
+func safeURL(u string, image bool) string {
+	u = strings.TrimSpace(u)
+	if u == "" {
+		return ""
+	}
+	for _, c := range []byte(u) {
+		if c < 0x20 || c == 0x7f || c == ' ' {
+			return ""
+		}
+	}
+	if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+		switch strings.ToLower(u[:i]) {
+		case "http", "https":
+		case "mailto":
+			if image {
+				return ""
+			}
+		default:
+			return ""
+		}
+	}
+	return u
+}
+
+// unescape 去掉反斜杠转义
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// runLen 返回从i开始连续相同字符的个数
+func runLen(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// findRun 从from开始查找长度恰好为n的c字符串
+func findRun(s string, from int, c byte, n int) int {
+	for j := from; j < len(s); {
+		if s[j] != c {
+			j++
+			continue
+		}
+		m := runLen(s, j)
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	return -1
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\n' || c == hardBreak[0] }
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isPunct(c byte) bool { return c < 0x80 && c > ' ' && !isAlnum(c) && c != 0x7f }
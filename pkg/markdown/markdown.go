@@ -0,0 +1,316 @@
+// Package markdown 把帖子正文的Markdown渲染成HTML
+// 支持常用语法：标题、段落、强调、删除线、行内代码、代码块、引用、列表、分隔线、链接和图片
+// 原始HTML一律转义，链接只允许http/https/mailto及相对地址，渲染结果可以直接插入页面
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// hardBreak 段落内强制换行的占位符，输入中的\x00会先被替换掉
+const hardBreak = "\x00"
+
+// Render 把Markdown渲染成安全的HTML
+func Render(src string) string {
+	var b strings.Builder
+	renderBlocks(&b, splitLines(src), false)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\x00", "�")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	src = strings.TrimRight(src, "\n ")
+	return strings.Split(src, "\n")
+}
+
+func isBlank(line string) bool { return strings.TrimSpace(line) == "" }
+
+func indentOf(line string) int { return len(line) - len(strings.TrimLeft(line, " ")) }
+
+// renderBlocks 逐个解析块级元素，tight为true时段落不包<p>，用于紧凑列表
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		t := strings.TrimLeft(line, " ")
+		if isBlank(line) {
+			i++
+			continue
+		}
+		if fence, lang, ok := openFence(t); ok {
+			i = renderFence(b, lines, i+1, fence, lang)
+			continue
+		}
+		if level, text, ok := heading(t); ok {
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(text), level)
+			i++
+			continue
+		}
+		if isHR(t) {
+			b.WriteString("<hr>\n")
+			i++
+			continue
+		}
+		if strings.HasPrefix(t, ">") {
+			var inner []string
+			for ; i < len(lines); i++ {
+				q := strings.TrimLeft(lines[i], " ")
+				if !strings.HasPrefix(q, ">") {
+					break
+				}
+				inner = append(inner, strings.TrimPrefix(q[1:], " "))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, inner, false)
+			b.WriteString("</blockquote>\n")
+			continue
+		}
+		if _, ok := parseListItem(line); ok {
+			i = renderList(b, lines, i)
+			continue
+		}
+		// 段落，直到空行或其他块级元素开始
+		j := i + 1
+		for j < len(lines) && !isBlank(lines[j]) && !interrupts(lines[j]) {
+			j++
+		}
+		text := renderInline(joinParagraph(lines[i:j]))
+		if tight {
+			b.WriteString(text + "\n")
+		} else {
+			b.WriteString("<p>" + text + "</p>\n")
+		}
+		i = j
+	}
+}
+
+// interrupts 该行是否会结束当前段落
+func interrupts(line string) bool {
+	t := strings.TrimLeft(line, " ")
+	if _, _, ok := openFence(t); ok {
+		return true
+	}
+	if _, _, ok := heading(t); ok {
+		return true
+	}
+	if isHR(t) || strings.HasPrefix(t, ">") {
+		return true
+	}
+	// 有序列表只有从1开始才打断段落，避免正文里的“2024. ”被当成列表
+	item, ok := parseListItem(line)
+	return ok && (!item.ordered || item.start == 1)
+}
+
+// joinParagraph 合并段落的各行，行尾两个空格或反斜杠表示强制换行
+func joinParagraph(lines []string) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		if i < len(lines)-1 {
+			switch {
+			case strings.HasSuffix(line, "  "):
+				line = strings.TrimRight(line, " ") + hardBreak
+			case strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\"):
+				line = line[:len(line)-1] + hardBreak
+			}
+		} else {
+			line = strings.TrimRight(line, " ")
+		}
+		parts[i] = line
+	}
+	return strings.Join(parts, "\n")
+}
+
+// openFence 判断是否是代码块的开始，返回结束标记及语言
+func openFence(t string) (fence, lang string, ok bool) {
+	if !strings.HasPrefix(t, "```") && !strings.HasPrefix(t, "~~~") {
+		return "", "", false
+	}
+	n := len(t) - len(strings.TrimLeft(t, t[:1]))
+	info := strings.TrimSpace(t[n:])
+	if t[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	if fields := strings.Fields(info); len(fields) > 0 && validLang(fields[0]) {
+		lang = fields[0]
+	}
+	return t[:n], lang, true
+}
+
+func validLang(lang string) bool {
+	for _, c := range []byte(lang) {
+		if !isAlnum(c) && !strings.ContainsRune("-_+#.", rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderFence 输出代码块，返回代码块结束后的下一行
+func renderFence(b *strings.Builder, lines []string, i int, fence, lang string) int {
+	var code strings.Builder
+	for ; i < len(lines); i++ {
+		t := strings.TrimLeft(lines[i], " ")
+		if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]+" ") == "" {
+			i++
+			break
+		}
+		code.WriteString(lines[i] + "\n")
+	}
+	if lang != "" {
+		b.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+	} else {
+		b.WriteString("<pre><code>")
+	}
+	b.WriteString(html.EscapeString(code.String()))
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+func heading(t string) (level int, text string, ok bool) {
+	level = len(t) - len(strings.TrimLeft(t, "#"))
+	if level == 0 || level > 6 || (len(t) > level && t[level] != ' ') {
+		return 0, "", false
+	}
+	text = strings.TrimSpace(t[level:])
+	// 去掉结尾的#
+	if trimmed := strings.TrimRight(text, "#"); trimmed == "" || strings.HasSuffix(trimmed, " ") {
+		text = strings.TrimSpace(trimmed)
+	}
+	return level, text, true
+}
+
+func isHR(t string) bool {
+	if t == "" || !strings.ContainsRune("-*_", rune(t[0])) {
+		return false
+	}
+	n := 0
+	for _, c := range []byte(t) {
+		switch c {
+		case t[0]:
+			n++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+type listItem struct {
+	ordered bool
+	marker  byte // 无序列表为-*+，有序列表为.或)
+	start   int
+	indent  int // 列表项内容的缩进，后续行至少缩进这么多才属于该项
+	content string
+}
+
+func parseListItem(line string) (item listItem, ok bool) {
+	t := strings.TrimLeft(line, " ")
+	pre := len(line) - len(t)
+	if pre > 3 || t == "" {
+		return item, false
+	}
+	n := 0
+	switch {
+	case strings.ContainsRune("-*+", rune(t[0])):
+		item.marker, n = t[0], 1
+	case t[0] >= '0' && t[0] <= '9':
+		for n < len(t) && n < 9 && t[n] >= '0' && t[n] <= '9' {
+			n++
+		}
+		if n == len(t) || (t[n] != '.' && t[n] != ')') {
+			return item, false
+		}
+		item.ordered, item.marker = true, t[n]
+		item.start, _ = strconv.Atoi(t[:n])
+		n++
+	default:
+		return item, false
+	}
+	if n < len(t) && t[n] != ' ' {
+		return item, false
+	}
+	rest := t[n:]
+	content := strings.TrimLeft(rest, " ")
+	spaces := len(rest) - len(content)
+	if spaces > 4 || content == "" {
+		spaces = 1
+	}
+	item.indent = pre + n + spaces
+	item.content = content
+	return item, true
+}
+
+// renderList 输出列表，返回列表结束后的下一行
+// 列表项之间或项内有空行时为松散列表，每段都包<p>
+func renderList(b *strings.Builder, lines []string, i int) int {
+	first, _ := parseListItem(lines[i])
+	var (
+		items [][]string
+		loose bool
+	)
+	for i < len(lines) {
+		item, ok := parseListItem(lines[i])
+		if !ok || item.ordered != first.ordered || item.marker != first.marker {
+			break
+		}
+		body := []string{item.content}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				j := i
+				for j < len(lines) && isBlank(lines[j]) {
+					j++
+				}
+				if j < len(lines) && indentOf(lines[j]) >= item.indent {
+					body = append(body, make([]string, j-i)...)
+					i = j
+					loose = true
+					continue
+				}
+				if j < len(lines) {
+					if next, ok := parseListItem(lines[j]); ok && next.ordered == first.ordered && next.marker == first.marker {
+						i = j
+						loose = true
+					}
+				}
+				break
+			}
+			if indentOf(line) >= item.indent {
+				body = append(body, line[item.indent:])
+				i++
+				continue
+			}
+			if _, ok := parseListItem(line); ok || interrupts(line) {
+				break
+			}
+			// 懒惰续行，属于上一段
+			body = append(body, line)
+			i++
+		}
+		items = append(items, body)
+	}
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.start != 1 {
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", first.start)
+	} else {
+		b.WriteString("<" + tag + ">\n")
+	}
+	for _, body := range items {
+		var inner strings.Builder
+		renderBlocks(&inner, body, !loose)
+		b.WriteString("<li>" + strings.TrimSuffix(inner.String(), "\n") + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
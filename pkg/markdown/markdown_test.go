@@ -0,0 +1,267 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"heading", "# Title", "<h1>Title</h1>"},
+		{"heading h6", "###### h6", "<h6>h6</h6>"},
+		{"heading too deep", "####### h7", "<p>####### h7</p>"},
+		{"heading needs space", "#nospace", "<p>#nospace</p>"},
+		{"heading closing hashes", "## closed ##", "<h2>closed</h2>"},
+		{"heading interrupts paragraph", "text\n# heading", "<p>text</p>\n<h1>heading</h1>"},
+		{"soft break", "hello\nworld", "<p>hello\nworld</p>"},
+		{"hard break spaces", "a  \nb", "<p>a<br>\nb</p>"},
+		{"hard break backslash", "a\\\nb", "<p>a<br>\nb</p>"},
+		{"paragraphs", "para1\n\npara2", "<p>para1</p>\n<p>para2</p>"},
+		{"emphasis", "*em* **strong** _em_ __strong__ ~~del~~",
+			"<p><em>em</em> <strong>strong</strong> <em>em</em> <strong>strong</strong> <del>del</del></p>"},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>"},
+		{"unclosed emphasis", "**unclosed", "<p>**unclosed</p>"},
+		{"escaped emphasis", "\\*not em\\*", "<p>*not em*</p>"},
+		{"code span", "`code <b>`", "<p><code>code &lt;b&gt;</code></p>"},
+		{"code span with backtick", "`` a`b ``", "<p><code>a`b</code></p>"},
+		{"fenced code", "```go\nfunc main() {}\n<x>\n```", "<pre><code class=\"language-go\">func main() {}\n&lt;x&gt;\n</code></pre>"},
+		{"tilde fence", "~~~\ncode\n~~~", "<pre><code>code\n</code></pre>"},
+		{"unclosed fence", "```\nunclosed", "<pre><code>unclosed\n</code></pre>"},
+		{"blockquote", "> quote\n> more", "<blockquote>\n<p>quote\nmore</p>\n</blockquote>"},
+		{"bullet list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>"},
+		{"ordered list", "1. a\n2. b", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>"},
+		{"ordered list start", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>"},
+		{"loose list", "- a\n\n- b", "<ul>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ul>"},
+		{"nested list", "- a\n  - nested", "<ul>\n<li>a\n<ul>\n<li>nested</li>\n</ul></li>\n</ul>"},
+		{"thematic break", "---", "<hr>"},
+		{"thematic break stars", "***", "<hr>"},
+		{"link with title", "[link](https://example.com \"title\")",
+			"<p><a href=\"https://example.com\" title=\"title\" rel=\"nofollow noopener noreferrer\">link</a></p>"},
+		{"relative link", "[rel](/post/1)", "<p><a href=\"/post/1\" rel=\"nofollow noopener noreferrer\">rel</a></p>"},
+		{"image", "![alt](https://example.com/a.png)", "<p><img src=\"https://example.com/a.png\" alt=\"alt\"></p>"},
+		{"autolink", "<https://example.com>", "<p><a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\">https://example.com</a></p>"},
+		{"mailto autolink", "<mailto:a@b.c>", "<p><a href=\"mailto:a@b.c\" rel=\"nofollow noopener noreferrer\">a@b.c</a></p>"},
+		{"html characters", "a & b < c > d \"q\" 'x'", "<p>a &amp; b &lt; c &gt; d &#34;q&#34; &#39;x&#39;</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// allowedAttrs Render可能输出的标签及各标签允许的属性
+var allowedAttrs = map[string]map[string]bool{
+	"p": {}, "h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {},
+	"em": {}, "strong": {}, "del": {}, "br": {}, "hr": {}, "pre": {}, "blockquote": {},
+	"ul": {}, "li": {}, "ol": {"start": true},
+	"code": {"class": true},
+	"a":    {"href": true, "title": true, "rel": true},
+	"img":  {"src": true, "alt": true, "title": true},
+}
+
+// checkSafe 解析Render的输出，只允许白名单中的标签和属性，链接只能是http/https/mailto或相对地址，链接不能嵌套
+func checkSafe(t *testing.T, src, out string) {
+	t.Helper()
+	z := html.NewTokenizer(strings.NewReader(out))
+	depth := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "a" {
+				depth--
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			attrs, ok := allowedAttrs[tok.Data]
+			if !ok {
+				t.Errorf("Render(%q) = %q: unexpected tag <%s>", src, out, tok.Data)
+				continue
+			}
+			if tok.Data == "a" {
+				if depth++; depth > 1 {
+					t.Errorf("Render(%q) = %q: nested link", src, out)
+				}
+			}
+			for _, a := range tok.Attr {
+				if !attrs[a.Key] {
+					t.Errorf("Render(%q) = %q: unexpected attribute %s on <%s>", src, out, a.Key, tok.Data)
+				}
+				if (a.Key == "href" || a.Key == "src") && !safeScheme(a.Val, tok.Data == "img") {
+					t.Errorf("Render(%q) = %q: unsafe %s %q", src, out, a.Key, a.Val)
+				}
+			}
+		}
+	}
+}
+
+// safeScheme 按浏览器的方式判断属性值的协议，去掉空白及控制字符后再取冒号之前的部分
+func safeScheme(v string, image bool) bool {
+	v = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, v)
+	i := strings.IndexAny(v, ":/?#")
+	if i < 0 || v[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(v[:i]) {
+	case "http", "https":
+		return true
+	case "mailto":
+		return !image
+	}
+	return false
+}
+
+func TestRenderXSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // 为空时只检查输出是否安全
+	}{
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"mixed case scheme", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"leading spaces", "[x](  javascript:alert(1))", "<p>x</p>"},
+		{"control character in scheme", "[x](java\x01script:alert(1))", "<p>x</p>"},
+		{"nul before scheme", "[x](\x00javascript:alert(1))", "<p>x</p>"},
+		{"del character", "[x](https://a.com/\x7f)", "<p>x</p>"},
+		{"tab in scheme", "[x](java\tscript:alert(1))", ""},
+		{"newline in scheme", "[x](java\nscript:alert(1))", ""},
+		{"escaped colon", "[x](javascript\\:alert(1))", "<p>x</p>"},
+		{"entity colon", "[x](javascript&#58;alert(1))", ""},
+		{"angle bracket destination", "[x](<javascript:alert(1)>)", "<p>x</p>"},
+		{"vbscript link", "[x](vbscript:msgbox)", "<p>x</p>"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"data image", "![x](data:image/png;base64,AAA)", "<p>x</p>"},
+		{"javascript image", "![x](javascript:alert(1))", "<p>x</p>"},
+		{"mailto image", "![x](mailto:a@b.c)", "<p>x</p>"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>"},
+		{"mixed case autolink", "<JAVASCRIPT:alert(1)>", "<p>&lt;JAVASCRIPT:alert(1)&gt;</p>"},
+		{"quote in autolink", "<https://a.com/\"onmouseover=\"alert(1)>", ""},
+		{"quote in destination", "[x](https://a.com/\"onclick=\"x)", ""},
+		{"quote in title", "[x](https://a.com \"t\\\" onmouseover=\\\"alert(1)\")",
+			"<p><a href=\"https://a.com\" title=\"t&#34; onmouseover=&#34;alert(1)\" rel=\"nofollow noopener noreferrer\">x</a></p>"},
+		{"double quote in single quoted title", "[x](https://a.com 't\" onclick=\"x')", ""},
+		{"quote in image alt", "![a\" onerror=\"alert(1)](https://a.com/x.png)",
+			"<p><img src=\"https://a.com/x.png\" alt=\"a&#34; onerror=&#34;alert(1)\"></p>"},
+		{"angle brackets in url", "[x](https://a.com/?q=<script>)", ""},
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"img tag", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"anchor tag", "<a href=\"javascript:alert(1)\">x</a>", "<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>"},
+		{"html in heading", "# <svg onload=alert(1)>", "<h1>&lt;svg onload=alert(1)&gt;</h1>"},
+		{"html in emphasis", "*<script>*", "<p><em>&lt;script&gt;</em></p>"},
+		{"html in quote", "> <script>", "<blockquote>\n<p>&lt;script&gt;</p>\n</blockquote>"},
+		{"html in list", "- <iframe>", "<ul>\n<li>&lt;iframe&gt;</li>\n</ul>"},
+		{"html in code span", "`<script>`", "<p><code>&lt;script&gt;</code></p>"},
+		{"html in fence info", "```js\"><script>\nx\n```", "<pre><code>x\n</code></pre>"},
+		{"nested links", "[a [b](https://b.com)](https://a.com)", ""},
+		{"unsafe link inside link", "[[x](javascript:alert(1))](https://a.com)", ""},
+		{"link inside autolink text", "[<https://a.com>](https://b.com)", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.src)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+			checkSafe(t, tt.src, got)
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		u     string
+		image bool
+		want  string
+	}{
+		{"https://example.com/a?b=c#d", false, "https://example.com/a?b=c#d"},
+		{"HTTP://EXAMPLE.COM", false, "HTTP://EXAMPLE.COM"},
+		{"mailto:a@b.c", false, "mailto:a@b.c"},
+		{"mailto:a@b.c", true, ""},
+		{"/post/1", false, "/post/1"},
+		{"post/1?next=a:b", false, "post/1?next=a:b"},
+		{"#top", false, "#top"},
+		{"  https://example.com  ", false, "https://example.com"},
+		{"javascript:alert(1)", false, ""},
+		{"JavaScript:alert(1)", false, ""},
+		{"data:image/png;base64,AAA", true, ""},
+		{"file:///etc/passwd", false, ""},
+		{"java\x00script:alert(1)", false, ""},
+		{"https://a.com/\x1f", false, ""},
+		{"https://a.com/ b", false, ""},
+		{"", false, ""},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.u, tt.image); got != tt.want {
+			t.Errorf("safeURL(%q, %v) = %q, want %q", tt.u, tt.image, got, tt.want)
+		}
+	}
+}
+
+// TestRenderNoPanic 各种不完整的输入不能panic，输出也要安全
+func TestRenderNoPanic(t *testing.T) {
+	inputs := []string{
+		"", "\n\n\n", "[", "]", "[]", "[](", "[x](", "[x](<", "[x](\"", "![", "<", "<>", "`", "``", "***", "___", "~~",
+		"\\", "[x](a \"", "[x](a 'b", "- ", "1.", "> ", "```", "# ", "**a*b**", "*a**b*", "[x]( )", "\x00", "\r\n\r",
+		strings.Repeat("[", 1000), strings.Repeat("*", 1000), strings.Repeat("> ", 200) + "x", strings.Repeat("- ", 200) + "x",
+	}
+	for _, src := range inputs {
+		checkSafe(t, src, Render(src))
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		src  string
+		n    int
+		want string
+	}{
+		{"# Title\n\nsome **bold** text & more", 0, "Title some bold text & more"},
+		{"- a\n- b", 0, "a b"},
+		{"hello world", 5, "hello…"},
+		{"你好世界", 2, "你好…"},
+		{"short", 10, "short"},
+		{"<script>x</script>", 0, "<script>x</script>"},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.src, tt.n); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.src, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2, 10)
+	r1 := c.Render(1, "*a*")
+	if r1.HTML != "<p><em>a</em></p>" || r1.Excerpt != "a" {
+		t.Fatalf("Render = %+v", r1)
+	}
+	if c.Render(1, "*a*") != r1 {
+		t.Error("same content not cached")
+	}
+	if r := c.Render(1, "**a**"); r.HTML != "<p><strong>a</strong></p>" {
+		t.Errorf("changed content rendered as %q", r.HTML)
+	}
+	// 超出容量时淘汰最久未使用的
+	r2 := c.Render(2, "b")
+	c.Render(1, "**a**")
+	c.Render(3, "c")
+	if c.Render(1, "**a**") == nil || c.Render(2, "b") == r2 {
+		t.Error("least recently used entry not evicted")
+	}
+}
@@ -0,0 +1,50 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const ellipsis = "…"
+
+// PlainText 去掉Markdown标记，返回空白合并后的纯文本，用于摘要和搜索结果
+func PlainText(src string) string {
+	return htmlToText(Render(src))
+}
+
+// Excerpt 返回正文开头最多n个字符的纯文本摘要
+func Excerpt(src string, n int) string {
+	return truncate(PlainText(src), n)
+}
+
+// htmlToText 去掉Render输出的标签，块级元素之间都有换行，合并空白后不会粘连
+func htmlToText(h string) string {
+	var b strings.Builder
+	inTag := false
+	for i := 0; i < len(h); i++ {
+		switch c := h[i]; {
+		case c == '<':
+			inTag = true
+		case c == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteByte(c)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(b.String())), " ")
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for j := range s {
+		if i == n {
+			return strings.TrimRight(s[:j], " ") + ellipsis
+		}
+		i++
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+// testArgon2idParams 测试使用较小的参数，加快测试速度
+var testArgon2idParams = Argon2idParams{Memory: 64, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestArgon2idVerify(t *testing.T) {
+	h := NewArgon2id(testArgon2idParams)
+	encoded, err := h.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Match(encoded) {
+		t.Fatalf("Match(%q) = false", encoded)
+	}
+	if ok, err := h.Verify("secret", encoded); !ok || err != nil {
+		t.Errorf("Verify correct password = %v, %v", ok, err)
+	}
+	if ok, err := h.Verify("wrong", encoded); ok || err != nil {
+		t.Errorf("Verify wrong password = %v, %v", ok, err)
+	}
+	if h.NeedsRehash(encoded) {
+		t.Error("NeedsRehash with the same params = true")
+	}
+	if !NewArgon2id(DefaultArgon2idParams).NeedsRehash(encoded) {
+		t.Error("NeedsRehash with stronger params = false")
+	}
+}
+
+func TestDecodeArgon2idInvalid(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	tests := []struct {
+		name    string
+		encoded string
+		want    error
+	}{
+		{"t=0", "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key, ErrInvalidHash},
+		{"t too large", "$argon2id$v=19$m=64,t=65,p=1$" + salt + "$" + key, ErrInvalidHash},
+		{"p=0", "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key, ErrInvalidHash},
+		{"m=0", "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key, ErrInvalidHash},
+		{"m less than 8*p", "$argon2id$v=19$m=15,t=1,p=2$" + salt + "$" + key, ErrInvalidHash},
+		{"m too large", "$argon2id$v=19$m=1048577,t=1,p=1$" + salt + "$" + key, ErrInvalidHash},
+		{"empty key", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$", ErrInvalidHash},
+		{"p overflow", "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + key, ErrUnknownFormat},
+		{"negative t", "$argon2id$v=19$m=64,t=-1,p=1$" + salt + "$" + key, ErrUnknownFormat},
+		{"missing params", "$argon2id$v=19$m=64$" + salt + "$" + key, ErrUnknownFormat},
+		{"bad salt", "$argon2id$v=19$m=64,t=1,p=1$!!$" + key, ErrUnknownFormat},
+		{"too few parts", "$argon2id$v=19$" + key, ErrUnknownFormat},
+	}
+	h := NewArgon2id(testArgon2idParams)
+	for _, tt := range tests {
+		if _, _, _, err := decodeArgon2id(tt.encoded); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+		// 不合法的参数不能让校验panic或通过
+		if ok, err := h.Verify("secret", tt.encoded); ok || err == nil {
+			t.Errorf("%s: Verify = %v, %v, want error", tt.name, ok, err)
+		}
+		if !h.NeedsRehash(tt.encoded) {
+			t.Errorf("%s: NeedsRehash = false", tt.name)
+		}
+	}
+	// 边界上的参数是合法的
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=16,t=1,p=2$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=64,p=1$" + salt + "$" + key,
+	} {
+		if _, _, _, err := decodeArgon2id(encoded); err != nil {
+			t.Errorf("decodeArgon2id(%q) = %v", encoded, err)
+		}
+	}
+}
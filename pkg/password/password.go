@@ -0,0 +1,74 @@
+package password
+
+import (
+	"bell_best/setting"
+	"errors"
+	"fmt"
+)
+
+// 密码统一保存为PHC格式的字符串，如 $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+// 字符串里带有算法和参数，校验时根据前缀选择对应的算法，因此可以随时切换默认算法
+
+var (
+	ErrUnknownFormat = errors.New("unknown password hash format")
+	ErrUnknownHasher = errors.New("unknown password hasher")
+	ErrInvalidHash   = errors.New("invalid password hash parameters")
+)
+
+// Hasher 密码哈希算法
+type Hasher interface {
+	// Name 算法名称，对应配置中的 auth.password_hasher
+	Name() string
+	// Hash 生成密码的哈希字符串
+	Hash(password string) (string, error)
+	// Verify 校验密码与哈希字符串是否匹配
+	Verify(password, encoded string) (bool, error)
+	// Match 判断哈希字符串是否由该算法生成
+	Match(encoded string) bool
+	// NeedsRehash 判断哈希字符串使用的参数是否弱于当前参数
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	hashers = []Hasher{
+		NewArgon2id(DefaultArgon2idParams),
+		NewBcrypt(DefaultBcryptCost),
+	}
+	current = hashers[0]
+)
+
+// Init 根据配置设置生成新密码时使用的算法，cfg为nil或没有配置算法时使用argon2id
+func Init(cfg *setting.AuthConfig) error {
+	if cfg == nil || cfg.PasswordHasher == "" {
+		current = hashers[0]
+		return nil
+	}
+	for _, h := range hashers {
+		if h.Name() == cfg.PasswordHasher {
+			current = h
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownHasher, cfg.PasswordHasher)
+}
+
+// Hash 使用当前算法生成密码的哈希字符串
+func Hash(password string) (string, error) {
+	return current.Hash(password)
+}
+
+// Verify 校验密码，rehash为true表示哈希字符串不是用当前算法及参数生成的，校验通过后应重新哈希保存
+// 无法识别的格式返回ErrUnknownFormat
+func Verify(password, encoded string) (ok, rehash bool, err error) {
+	for _, h := range hashers {
+		if !h.Match(encoded) {
+			continue
+		}
+		ok, err = h.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, h != current || h.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownFormat
+}
@@ -0,0 +1,31 @@
+package password
+
+import (
+	"bell_best/setting"
+	"errors"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	t.Cleanup(func() { current = hashers[0] })
+	tests := []struct {
+		cfg  *setting.AuthConfig
+		want string
+		err  error
+	}{
+		{nil, "argon2id", nil},
+		{&setting.AuthConfig{}, "argon2id", nil},
+		{&setting.AuthConfig{PasswordHasher: "bcrypt"}, "bcrypt", nil},
+		{&setting.AuthConfig{PasswordHasher: "argon2id"}, "argon2id", nil},
+		{&setting.AuthConfig{PasswordHasher: "md5"}, "argon2id", ErrUnknownHasher},
+	}
+	for _, tt := range tests {
+		current = hashers[0]
+		if err := Init(tt.cfg); !errors.Is(err, tt.err) {
+			t.Errorf("Init(%+v) err = %v, want %v", tt.cfg, err, tt.err)
+		}
+		if current.Name() != tt.want {
+			t.Errorf("Init(%+v) hasher = %s, want %s", tt.cfg, current.Name(), tt.want)
+		}
+	}
+}
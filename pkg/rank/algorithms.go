@@ -0,0 +1,112 @@
+package rank
+
+import (
+	"math"
+	"time"
+)
+
+// 几种排序算法的说明:http://www.ruanyifeng.com/blog/algorithm/
+// 修改公式时要同步修改dao/redis/scripts.go中投票脚本的实现
+
+const (
+	AlgSimple  = "simple"
+	AlgHot     = "hot"
+	AlgGravity = "gravity"
+	AlgWilson  = "wilson"
+
+	DefaultScorePerVote = 432   // 86400/200 -> 200张赞成票可以给你的帖子续一天
+	DefaultHotDecay     = 45000 // 12.5小时
+	DefaultGravity      = 1.8
+	DefaultWilsonZ      = 1.96 // 95%的置信度
+)
+
+// hotEpoch reddit算法的起始时间 2005-12-08 07:46:43 UTC
+const hotEpoch = 1134028003
+
+// Simple 项目原来使用的简化算法：发帖时间 + 净票数*每票的分数
+type Simple struct {
+	ScorePerVote float64
+}
+
+func NewSimple(scorePerVote float64) *Simple {
+	return &Simple{ScorePerVote: scorePerVote}
+}
+
+func (*Simple) Name() string { return AlgSimple }
+
+func (s *Simple) Param() float64 { return s.ScorePerVote }
+
+func (s *Simple) Score(ups, downs int64, created, _ time.Time) float64 {
+	return float64(created.Unix()) + float64(ups-downs)*s.ScorePerVote
+}
+
+// Hot reddit的hot算法：净票数取对数，再加上发帖时间
+// 越新的帖子分数越高，前10票与后面90票的权重相同
+type Hot struct {
+	Decay float64 // 发帖时间每晚多少秒相当于净票数多一个数量级
+}
+
+func NewHot(decay float64) *Hot {
+	return &Hot{Decay: decay}
+}
+
+func (*Hot) Name() string { return AlgHot }
+
+func (h *Hot) Param() float64 { return h.Decay }
+
+func (h *Hot) Score(ups, downs int64, created, _ time.Time) float64 {
+	s := float64(ups - downs)
+	order := math.Log10(math.Max(math.Abs(s), 1))
+	var sign float64
+	switch {
+	case s > 0:
+		sign = 1
+	case s < 0:
+		sign = -1
+	}
+	seconds := float64(created.Unix() - hotEpoch)
+	return sign*order + seconds/h.Decay
+}
+
+// Gravity hacker news的算法：净票数 / (帖子小时数+2)^gravity
+// 分数随时间衰减，需要后台任务定期重新计算
+type Gravity struct {
+	Gravity float64
+}
+
+func NewGravity(gravity float64) *Gravity {
+	return &Gravity{Gravity: gravity}
+}
+
+func (*Gravity) Name() string { return AlgGravity }
+
+func (g *Gravity) Param() float64 { return g.Gravity }
+
+func (g *Gravity) Score(ups, downs int64, created, now time.Time) float64 {
+	hours := math.Max(now.Sub(created).Hours(), 0)
+	return float64(ups-downs) / math.Pow(hours+2, g.Gravity)
+}
+
+// Wilson 威尔逊区间的下限，只和赞成票的比例及总票数有关，与发帖时间无关
+// 适合流量小、帖子少的社区，按"最受好评"排序
+type Wilson struct {
+	Z float64
+}
+
+func NewWilson(z float64) *Wilson {
+	return &Wilson{Z: z}
+}
+
+func (*Wilson) Name() string { return AlgWilson }
+
+func (w *Wilson) Param() float64 { return w.Z }
+
+func (w *Wilson) Score(ups, downs int64, _, _ time.Time) float64 {
+	n := float64(ups + downs)
+	if n == 0 {
+		return 0
+	}
+	p := float64(ups) / n
+	z2 := w.Z * w.Z
+	return (p + z2/(2*n) - w.Z*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
@@ -0,0 +1,98 @@
+package rank
+
+import (
+	"bell_best/setting"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 帖子的分数由排序算法根据赞成票、反对票及发帖时间计算
+// 每个社区可以在配置中选择不同的算法及参数，没有单独配置的社区使用默认算法
+// 不同算法的分数量级不同，因此每篇帖子保存两个分数：
+// 社区算法计算的分数保存在community:score:<社区id>中，只用于社区内的排序
+// 默认算法计算的分数保存在post:score中，用于全站及多个社区合并后的排序
+
+var ErrUnknownRanker = errors.New("unknown ranker")
+
+// Ranker 帖子排序算法
+type Ranker interface {
+	// Name 算法名称，对应配置中的 rank.*.algorithm
+	Name() string
+	// Score 计算帖子的分数，分数越大越靠前
+	Score(ups, downs int64, created, now time.Time) float64
+	// Param 算法的参数，redis投票的lua脚本按名称和参数用同样的公式计算分数
+	Param() float64
+}
+
+var (
+	mu          sync.RWMutex
+	defaultRank Ranker = NewSimple(DefaultScorePerVote)
+	communities        = map[int64]Ranker{}
+)
+
+// New 根据配置创建排序算法，参数为0时使用该算法的默认值
+func New(cfg *setting.RankerConfig) (Ranker, error) {
+	switch cfg.Algorithm {
+	case "", AlgSimple:
+		return NewSimple(orDefault(cfg.ScorePerVote, DefaultScorePerVote)), nil
+	case AlgHot:
+		return NewHot(orDefault(cfg.Decay, DefaultHotDecay)), nil
+	case AlgGravity:
+		return NewGravity(orDefault(cfg.Gravity, DefaultGravity)), nil
+	case AlgWilson:
+		return NewWilson(orDefault(cfg.Z, DefaultWilsonZ)), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownRanker, cfg.Algorithm)
+}
+
+// Init 根据配置设置默认算法及每个社区使用的算法，cfg为nil时全部使用simple算法
+func Init(cfg *setting.RankConfig) error {
+	def := Ranker(NewSimple(DefaultScorePerVote))
+	m := make(map[int64]Ranker)
+	if cfg != nil {
+		if cfg.Default != nil {
+			r, err := New(cfg.Default)
+			if err != nil {
+				return err
+			}
+			def = r
+		}
+		for _, c := range cfg.Communities {
+			r, err := New(c)
+			if err != nil {
+				return fmt.Errorf("community %d: %w", c.CommunityID, err)
+			}
+			m[c.CommunityID] = r
+		}
+	}
+	mu.Lock()
+	defaultRank, communities = def, m
+	mu.Unlock()
+	return nil
+}
+
+// Default 返回默认的排序算法
+func Default() Ranker {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultRank
+}
+
+// For 返回社区使用的排序算法
+func For(communityID int64) Ranker {
+	mu.RLock()
+	defer mu.RUnlock()
+	if r, ok := communities[communityID]; ok {
+		return r
+	}
+	return defaultRank
+}
+
+func orDefault(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
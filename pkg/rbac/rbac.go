@@ -0,0 +1,64 @@
+package rbac
+
+// 用户的角色从低到高依次是user、moderator、admin，高等级的角色拥有低等级角色的全部权限
+// 角色保存在user表中，登录时写入JWT，修改角色后需要重新登录或刷新token才会生效
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator" // 全站版主，可以管理所有社区的帖子
+	RoleAdmin     = "admin"
+)
+
+var levels = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Permission 权限
+type Permission string
+
+const (
+	PermPostRemove      Permission = "post:remove"      // 删除任意帖子
+	PermCommunityManage Permission = "community:manage" // 创建、编辑、归档社区
+	PermModeratorManage Permission = "moderator:manage" // 任命、撤销社区版主
+	PermUserRoleManage  Permission = "user:role"        // 修改用户的角色
+	PermUserRevoke      Permission = "user:revoke"      // 强制用户下线
+)
+
+// permissions 拥有权限需要的最低角色
+var permissions = map[Permission]string{
+	PermPostRemove:      RoleModerator,
+	PermCommunityManage: RoleAdmin,
+	PermModeratorManage: RoleAdmin,
+	PermUserRoleManage:  RoleAdmin,
+	PermUserRevoke:      RoleAdmin,
+}
+
+// Valid 判断是不是已知的角色
+func Valid(role string) bool {
+	_, ok := levels[role]
+	return ok
+}
+
+// Normalize 把空的或未知的角色当作普通用户，兼容没有角色的旧token
+func Normalize(role string) string {
+	if !Valid(role) {
+		return RoleUser
+	}
+	return role
+}
+
+// AtLeast 判断role是否不低于min
+func AtLeast(role, min string) bool {
+	return levels[Normalize(role)] >= levels[min]
+}
+
+// Can 判断role是否拥有权限perm，未知的权限总是返回false
+func Can(role string, perm Permission) bool {
+	min, ok := permissions[perm]
+	if !ok {
+		return false
+	}
+	return AtLeast(role, min)
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 高亮时命中的词用<em>包起来，其余文本做HTML转义，前端可以直接作为HTML展示
+
+const (
+	highlightPre  = "<em>"
+	highlightPost = "</em>"
+	ellipsis      = "…"
+)
+
+// matchSpans 返回text中命中terms的区间，重叠或相邻的区间会合并
+func matchSpans(text string, terms []string) [][2]int {
+	set := make(map[string]struct{}, len(terms))
+	for _, t := range terms {
+		set[t] = struct{}{}
+	}
+	var spans [][2]int
+	for _, t := range Tokenize(text) {
+		if _, ok := set[t.Term]; ok {
+			spans = append(spans, [2]int{t.Start, t.End})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:0]
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s[0] <= merged[n-1][1] {
+			if s[1] > merged[n-1][1] {
+				merged[n-1][1] = s[1]
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// render 转义text[start:end]并高亮其中的区间
+func render(text string, start, end int, spans [][2]int) string {
+	var b strings.Builder
+	pos := start
+	for _, s := range spans {
+		if s[1] <= start || s[0] >= end {
+			continue
+		}
+		s0, s1 := max(s[0], start), min(s[1], end)
+		b.WriteString(html.EscapeString(text[pos:s0]))
+		b.WriteString(highlightPre)
+		b.WriteString(html.EscapeString(text[s0:s1]))
+		b.WriteString(highlightPost)
+		pos = s1
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// Highlight 高亮整段文本，用于标题
+func Highlight(text string, terms []string) string {
+	return render(text, 0, len(text), matchSpans(text, terms))
+}
+
+// Snippet 截取第一个命中位置附近最多maxRunes个字符并高亮，用于正文摘要
+// 没有命中时从头截取
+func Snippet(text string, terms []string, maxRunes int) string {
+	spans := matchSpans(text, terms)
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return render(text, 0, len(text), spans)
+	}
+	// 命中位置前面保留四分之一的长度作为上下文
+	start := 0
+	if len(spans) > 0 {
+		start = spans[0][0]
+		for back := maxRunes / 4; back > 0 && start > 0; back-- {
+			_, size := utf8.DecodeLastRuneInString(text[:start])
+			start -= size
+		}
+	}
+	end := start
+	for n := 0; n < maxRunes && end < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	s := render(text, start, end, spans)
+	if start > 0 {
+		s = ellipsis + s
+	}
+	if end < len(text) {
+		s += ellipsis
+	}
+	return s
+}
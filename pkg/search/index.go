@@ -0,0 +1,179 @@
+package search
+
+import (
+	"bell_best/models"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Index 纯Go实现的内存倒排索引，按BM25计算相关度
+// 索引只保存在当前进程中，启动时需要从数据库重建；多实例部署时各实例只能看到自己写入的帖子，应使用MySQL全文索引
+
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2 // 标题中的词权重更高
+)
+
+type document struct {
+	communityID int64
+	createTime  time.Time
+	length      float64
+	terms       []string // 文档包含的词，删除时用
+}
+
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[int64]float64 // 词 -> (帖子id -> 加权词频)
+	docs     map[int64]*document
+	totalLen float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[int64]float64),
+		docs:     make(map[int64]*document),
+	}
+}
+
+// IndexPost 添加或更新帖子的索引
+func (idx *Index) IndexPost(p *models.Post) error {
+	freq := make(map[string]float64)
+	for _, t := range Tokenize(p.Title) {
+		freq[t.Term] += titleWeight
+	}
+	for _, t := range Tokenize(p.Content) {
+		freq[t.Term]++
+	}
+	doc := &document{
+		communityID: p.CommunityID,
+		createTime:  p.CreateTime,
+		terms:       make([]string, 0, len(freq)),
+	}
+	if doc.createTime.IsZero() {
+		doc.createTime = time.Now()
+	}
+	for term, tf := range freq {
+		doc.length += tf
+		doc.terms = append(doc.terms, term)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if old, ok := idx.docs[p.ID]; ok {
+		// 编辑帖子时保留原来的发帖时间
+		doc.createTime = old.createTime
+		idx.remove(p.ID, old)
+	}
+	for term, tf := range freq {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[int64]float64)
+		}
+		idx.postings[term][p.ID] = tf
+	}
+	idx.docs[p.ID] = doc
+	idx.totalLen += doc.length
+	return nil
+}
+
+// RemovePost 删除帖子的索引
+func (idx *Index) RemovePost(pid int64) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if doc, ok := idx.docs[pid]; ok {
+		idx.remove(pid, doc)
+	}
+	return nil
+}
+
+func (idx *Index) remove(pid int64, doc *document) {
+	for _, term := range doc.terms {
+		delete(idx.postings[term], pid)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, pid)
+	idx.totalLen -= doc.length
+}
+
+// SearchPosts 搜索同时包含所有搜索词的帖子，按相关度从高到低分页返回帖子id及命中总数
+func (idx *Index) SearchPosts(p *models.ParamSearch) ([]string, int64, error) {
+	terms := QueryTerms(p.Q)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	lists := make([]map[int64]float64, 0, len(terms))
+	for _, term := range terms {
+		list, ok := idx.postings[term]
+		if !ok {
+			return nil, 0, nil
+		}
+		lists = append(lists, list)
+	}
+	// 从最短的倒排表开始求交集
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	n := float64(len(idx.docs))
+	avgLen := idx.totalLen / n
+	type hit struct {
+		id    int64
+		score float64
+		time  time.Time
+	}
+	var hits []hit
+	for pid := range lists[0] {
+		doc := idx.docs[pid]
+		if !match(doc, p) {
+			continue
+		}
+		score, ok := 0.0, true
+		for _, list := range lists {
+			tf, found := list[pid]
+			if !found {
+				ok = false
+				break
+			}
+			idf := math.Log(1 + (n-float64(len(list))+0.5)/(float64(len(list))+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*doc.length/avgLen))
+		}
+		if ok {
+			hits = append(hits, hit{id: pid, score: score, time: doc.createTime})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].time.After(hits[j].time)
+	})
+	total := int64(len(hits))
+	start := (p.Page - 1) * p.Size
+	if start >= total {
+		return nil, total, nil
+	}
+	end := min(start+p.Size, total)
+	ids := make([]string, 0, end-start)
+	for _, h := range hits[start:end] {
+		ids = append(ids, strconv.FormatInt(h.id, 10))
+	}
+	return ids, total, nil
+}
+
+// match 判断帖子是否满足社区及时间的过滤条件
+func match(doc *document, p *models.ParamSearch) bool {
+	if p.CommunityID != 0 && doc.communityID != p.CommunityID {
+		return false
+	}
+	if p.Since > 0 && doc.createTime.Unix() < p.Since {
+		return false
+	}
+	if p.Until > 0 && doc.createTime.Unix() >= p.Until {
+		return false
+	}
+	return true
+}
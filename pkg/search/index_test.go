@@ -0,0 +1,218 @@
+package search
+
+import (
+	"bell_best/models"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestIndex(t *testing.T, posts ...*models.Post) *Index {
+	t.Helper()
+	idx := NewIndex()
+	for _, p := range posts {
+		if err := idx.IndexPost(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return idx
+}
+
+func post(id, communityID int64, title, content string, hoursAfterBase int) *models.Post {
+	return &models.Post{
+		ID:          id,
+		CommunityID: communityID,
+		Title:       title,
+		Content:     content,
+		CreateTime:  base.Add(time.Duration(hoursAfterBase) * time.Hour),
+	}
+}
+
+func search(t *testing.T, idx *Index, p *models.ParamSearch) ([]string, int64) {
+	t.Helper()
+	if p.Page == 0 {
+		p.Page = 1
+	}
+	if p.Size == 0 {
+		p.Size = 10
+	}
+	ids, total, err := idx.SearchPosts(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids, total
+}
+
+func idList(ids ...int64) []string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.FormatInt(id, 10))
+	}
+	return s
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := newTestIndex(t,
+		post(1, 1, "notes", "golang appears once in a much longer body of text about other things entirely", 0),
+		post(2, 1, "golang", "golang golang post", 0),
+		post(3, 1, "notes", "golang golang post", 0),
+		post(4, 1, "rust", "nothing relevant here", 0),
+	)
+	// 标题中的词权重更高，词频相同时文档短的排在前面
+	ids, total := search(t, idx, &models.ParamSearch{Q: "golang"})
+	if want := idList(2, 3, 1); !reflect.DeepEqual(ids, want) || total != 3 {
+		t.Errorf("ids = %v, total %d, want %v, 3", ids, total, want)
+	}
+	// 多个词时帖子必须包含所有的词
+	ids, total = search(t, idx, &models.ParamSearch{Q: "golang post"})
+	if want := idList(2, 3); !reflect.DeepEqual(ids, want) || total != 2 {
+		t.Errorf("ids = %v, total %d, want %v, 2", ids, total, want)
+	}
+	// 大小写不敏感
+	if ids, _ = search(t, idx, &models.ParamSearch{Q: "RUST"}); !reflect.DeepEqual(ids, idList(4)) {
+		t.Errorf("case insensitive ids = %v", ids)
+	}
+	if ids, total = search(t, idx, &models.ParamSearch{Q: "python"}); len(ids) != 0 || total != 0 {
+		t.Errorf("missing term ids = %v, total %d", ids, total)
+	}
+	if ids, total = search(t, idx, &models.ParamSearch{Q: "  !!  "}); len(ids) != 0 || total != 0 {
+		t.Errorf("empty query ids = %v, total %d", ids, total)
+	}
+}
+
+func TestSearchTieBreakByTime(t *testing.T) {
+	idx := newTestIndex(t,
+		post(1, 1, "same", "identical text", 1),
+		post(2, 1, "same", "identical text", 3),
+		post(3, 1, "same", "identical text", 2),
+	)
+	ids, _ := search(t, idx, &models.ParamSearch{Q: "identical"})
+	if want := idList(2, 3, 1); !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	idx := newTestIndex(t,
+		post(1, 1, "topic", "", 0),
+		post(2, 2, "topic", "", 1),
+		post(3, 1, "topic", "", 2),
+		post(4, 2, "topic", "", 3),
+	)
+	unix := func(h int) int64 { return base.Add(time.Duration(h) * time.Hour).Unix() }
+	tests := []struct {
+		name string
+		p    *models.ParamSearch
+		want []string
+	}{
+		{"community", &models.ParamSearch{CommunityID: 2}, idList(4, 2)},
+		{"since is inclusive", &models.ParamSearch{Since: unix(2)}, idList(4, 3)},
+		{"until is exclusive", &models.ParamSearch{Until: unix(2)}, idList(2, 1)},
+		{"since and until", &models.ParamSearch{Since: unix(1), Until: unix(3)}, idList(3, 2)},
+		{"all filters", &models.ParamSearch{CommunityID: 1, Since: unix(1), Until: unix(3)}, idList(3)},
+		{"no match", &models.ParamSearch{CommunityID: 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.Q = "topic"
+			ids, total := search(t, idx, tt.p)
+			if len(ids) != len(tt.want) || (len(ids) > 0 && !reflect.DeepEqual(ids, tt.want)) || total != int64(len(tt.want)) {
+				t.Errorf("ids = %v, total %d, want %v", ids, total, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	var posts []*models.Post
+	for i := 1; i <= 5; i++ {
+		posts = append(posts, post(int64(i), 1, "page", "", i))
+	}
+	idx := newTestIndex(t, posts...)
+	tests := []struct {
+		page, size int64
+		want       []string
+	}{
+		{1, 2, idList(5, 4)},
+		{2, 2, idList(3, 2)},
+		{3, 2, idList(1)},
+		{4, 2, nil},
+		{100, 10, nil},
+	}
+	for _, tt := range tests {
+		ids, total := search(t, idx, &models.ParamSearch{Q: "page", Page: tt.page, Size: tt.size})
+		if total != 5 {
+			t.Errorf("page %d: total = %d, want 5", tt.page, total)
+		}
+		if len(ids) != len(tt.want) || (len(ids) > 0 && !reflect.DeepEqual(ids, tt.want)) {
+			t.Errorf("page %d size %d: ids = %v, want %v", tt.page, tt.size, ids, tt.want)
+		}
+	}
+}
+
+func TestReindexAndRemove(t *testing.T) {
+	idx := newTestIndex(t, post(1, 1, "old title", "old content", 0), post(2, 1, "other", "content", 1))
+
+	// 编辑后旧的词不再命中，发帖时间保持不变
+	edited := post(1, 1, "new title", "new content", 10)
+	if err := idx.IndexPost(edited); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := search(t, idx, &models.ParamSearch{Q: "old"}); len(ids) != 0 {
+		t.Errorf("old term ids = %v", ids)
+	}
+	if ids, _ := search(t, idx, &models.ParamSearch{Q: "new"}); !reflect.DeepEqual(ids, idList(1)) {
+		t.Errorf("new term ids = %v", ids)
+	}
+	until := base.Add(time.Hour).Unix()
+	if ids, _ := search(t, idx, &models.ParamSearch{Q: "new", Until: until}); !reflect.DeepEqual(ids, idList(1)) {
+		t.Errorf("edited post lost its create time: ids = %v", ids)
+	}
+	// 重建索引后文档总长度不能重复累加
+	if want := float64(len(Tokenize("new title"))*titleWeight + len(Tokenize("new content")) + len(Tokenize("other"))*titleWeight + len(Tokenize("content"))); idx.totalLen != want {
+		t.Errorf("totalLen = %v, want %v", idx.totalLen, want)
+	}
+
+	if err := idx.RemovePost(1); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := search(t, idx, &models.ParamSearch{Q: "content"}); !reflect.DeepEqual(ids, idList(2)) {
+		t.Errorf("after remove ids = %v", ids)
+	}
+	if _, ok := idx.postings["new"]; ok {
+		t.Error("posting list of removed post not deleted")
+	}
+	// 删除不存在的帖子不报错
+	if err := idx.RemovePost(100); err != nil {
+		t.Errorf("RemovePost(100) = %v", err)
+	}
+}
+
+func TestSearchCJK(t *testing.T) {
+	idx := newTestIndex(t,
+		post(1, 1, "全文搜索", "使用倒排索引实现", 0),
+		post(2, 1, "搜狗", "输入法", 1),
+		post(3, 1, "文本", "全文检索", 2),
+	)
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"全文搜索", idList(1)},
+		{"全文", idList(1, 3)},
+		// 单个汉字也能命中
+		{"搜", idList(2, 1)},
+		{"倒排 索引", idList(1)},
+		{"文搜", idList(1)},
+		{"搜索引擎", nil},
+	}
+	for _, tt := range tests {
+		ids, _ := search(t, idx, &models.ParamSearch{Q: tt.q})
+		if len(ids) != len(tt.want) || (len(ids) > 0 && !reflect.DeepEqual(ids, tt.want)) {
+			t.Errorf("q %q: ids = %v, want %v", tt.q, ids, tt.want)
+		}
+	}
+}
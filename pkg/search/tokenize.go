@@ -0,0 +1,90 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 分词规则：
+// 连续的字母、数字作为一个词，统一转成小写
+// 中日韩文字没有空格分隔，按二元组(bigram)切分，"全文搜索" -> "全文" "文搜" "搜索"
+// 建索引时额外保存单字，只搜一个字时也能命中
+
+// Token 分词结果，Start和End是词在原文中的字节偏移
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// isCJK 判断是不是中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWord(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isCJK(r)
+}
+
+// Tokenize 对文档分词，中日韩文字同时输出单字和二元组
+func Tokenize(text string) []Token {
+	return tokenize(text, true)
+}
+
+// QueryTerms 对搜索词分词并去重，中日韩文字只在单独一个字时输出单字
+func QueryTerms(q string) []string {
+	tokens := tokenize(q, false)
+	seen := make(map[string]struct{}, len(tokens))
+	terms := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if _, ok := seen[t.Term]; ok {
+			continue
+		}
+		seen[t.Term] = struct{}{}
+		terms = append(terms, t.Term)
+	}
+	return terms
+}
+
+func tokenize(text string, unigram bool) []Token {
+	var tokens []Token
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case isWord(r):
+			start := i
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !isWord(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, Token{Term: strings.ToLower(text[start:i]), Start: start, End: i})
+		case isCJK(r):
+			// 记录连续的中日韩文字每个字的起始位置
+			offsets := []int{i}
+			for i += size; i < len(text); i += size {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !isCJK(r) {
+					break
+				}
+				offsets = append(offsets, i)
+			}
+			offsets = append(offsets, i)
+			n := len(offsets) - 1
+			for j := 0; j < n; j++ {
+				if unigram || n == 1 {
+					tokens = append(tokens, Token{Term: text[offsets[j]:offsets[j+1]], Start: offsets[j], End: offsets[j+1]})
+				}
+				if j+1 < n {
+					tokens = append(tokens, Token{Term: text[offsets[j]:offsets[j+2]], Start: offsets[j], End: offsets[j+2]})
+				}
+			}
+		default:
+			i += size
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Token
+	}{
+		{"Hello, World", []Token{{"hello", 0, 5}, {"world", 7, 12}}},
+		{"go1.24", []Token{{"go1", 0, 3}, {"24", 4, 6}}},
+		{"全文搜索", []Token{{"全", 0, 3}, {"全文", 0, 6}, {"文", 3, 6}, {"文搜", 3, 9}, {"搜", 6, 9}, {"搜索", 6, 12}, {"索", 9, 12}}},
+		{"用Go写", []Token{{"用", 0, 3}, {"go", 3, 5}, {"写", 5, 8}}},
+		{"", nil},
+		{"!!!", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"Go go GO", []string{"go"}},
+		{"全文搜索", []string{"全文", "文搜", "搜索"}},
+		{"搜", []string{"搜"}},
+		{"搜 Go", []string{"搜", "go"}},
+		{"  ", []string{}},
+	}
+	for _, tt := range tests {
+		if got := QueryTerms(tt.q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("QueryTerms(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Learn Go today", []string{"go"}, "Learn <em>Go</em> today"},
+		{"全文搜索引擎", QueryTerms("全文搜索"), "<em>全文搜索</em>引擎"},
+		{"<b>go</b> & go", []string{"go"}, "&lt;b&gt;<em>go</em>&lt;/b&gt; &amp; <em>go</em>"},
+		{"no match", []string{"go"}, "no match"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.terms); got != tt.want {
+			t.Errorf("Highlight(%q, %v) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		text     string
+		terms    []string
+		maxRunes int
+		want     string
+	}{
+		{"short go text", []string{"go"}, 100, "short <em>go</em> text"},
+		{"aaaa bbbb cccc go dddd eeee", []string{"go"}, 8, "…c <em>go</em> ddd…"},
+		{"no match in this long text", []string{"go"}, 8, "no match…"},
+		{"一二三四五六七八九十全文", []string{"全文"}, 4, "…十<em>全文</em>"},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.text, tt.terms, tt.maxRunes); got != tt.want {
+			t.Errorf("Snippet(%q, %v, %d) = %q, want %q", tt.text, tt.terms, tt.maxRunes, got, tt.want)
+		}
+	}
+}
@@ -2,40 +2,122 @@ package router
 
 import (
 	"bell_best/controller"
+	"bell_best/dao/localfs"
 	_ "bell_best/docs"
 	"bell_best/logger"
 	"bell_best/middlewares"
+	"bell_best/pkg/jwt"
+	"bell_best/pkg/rbac"
+	"bell_best/setting"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.uber.org/zap"
 )
 
 func SetupRouter() *gin.Engine {
 	r := gin.New()
 	r.Use(logger.GinLogger(), logger.GinRecovery(true))
+	// 限流及登录防暴力破解按ip区分用户，只信任配置的反向代理转发的X-Forwarded-For，防止伪造ip绕过
+	// 没有配置时不信任任何代理，直接使用连接的对端地址
+	if err := r.SetTrustedProxies(setting.Conf.TrustedProxies); err != nil {
+		zap.L().Error("set trusted proxies failed", zap.Error(err))
+	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// 使用非对称密钥时公开公钥
+	if jwt.HasPublicKeys() {
+		r.GET("/.well-known/jwks.json", controller.JWKSHandler)
+	}
+
+	// 本地存储的图片由服务自身提供访问，public_url为完整地址时由nginx等提供
+	if cfg := setting.Conf.UploadConfig; cfg == nil || cfg.Storage == "" || cfg.Storage == "local" {
+		var local *setting.LocalBlobConfig
+		if cfg != nil {
+			local = cfg.Local
+		}
+		if dir, publicURL := localfs.Paths(local); strings.HasPrefix(publicURL, "/") {
+			r.Static(publicURL, dir)
+		}
+	}
 
 	v1 := r.Group("/api/v1")
 
+	// 按路由分组限流，规则见配置中的rate_limit.groups
+	authLimit := middlewares.RateLimitMiddleware("auth")
+	readLimit := middlewares.RateLimitMiddleware("read")
+	writeLimit := middlewares.RateLimitMiddleware("write")
+	voteLimit := middlewares.RateLimitMiddleware("vote")
+
 	// 注册业务路由
-	v1.POST("/signup", controller.SignUpHandler)
+	v1.POST("/signup", authLimit, controller.SignUpHandler)
 	// 登录
-	v1.POST("/login", controller.LoginHandler)
-	v1.GET("/posts/", controller.GetPostListHandler)
+	v1.POST("/login", authLimit, controller.LoginHandler)
+	// 刷新token
+	v1.POST("/refresh", authLimit, controller.RefreshTokenHandler)
+	// 帖子列表及详情不要求登录，登录后返回当前用户的投票
+	optionalAuth := middlewares.JWTOptionalAuthMiddleware()
+	v1.GET("/posts/", optionalAuth, readLimit, controller.GetPostListHandler)
 	// 根据帖子时间或分数获取帖子列表
-	v1.GET("/posts2/", controller.GetPostListHandler2)
+	v1.GET("/posts2/", optionalAuth, readLimit, controller.GetPostListHandler2)
+	// 热门标签
+	v1.GET("/tags/trending", readLimit, controller.TrendingTagsHandler)
+	// 全文搜索帖子
+	v1.GET("/search", optionalAuth, readLimit, controller.SearchHandler)
 
-	v1.GET("/community", controller.CommunityHandler)
-	v1.GET("/community/:id", controller.CommunityDetailHandler)
-	v1.GET("/post/:id", controller.GetPostDetailHandler)
+	v1.GET("/community", readLimit, controller.CommunityHandler)
+	v1.GET("/community/:id", readLimit, controller.CommunityDetailHandler)
+	v1.GET("/community/:id/moderators", readLimit, controller.CommunityModeratorsHandler)
+	v1.GET("/post/:id", optionalAuth, readLimit, controller.GetPostDetailHandler)
+	v1.GET("/post/:id/comments", readLimit, controller.GetCommentListHandler)
+	// 帖子的历史版本
+	v1.GET("/post/:id/revisions", readLimit, controller.PostRevisionsHandler)
+	v1.GET("/post/:id/revisions/:rev", readLimit, controller.PostRevisionHandler)
+	// 作者主页
+	v1.GET("/users/:id", optionalAuth, readLimit, controller.GetUserPageHandler)
 
 	v1.Use(middlewares.JWTAuthMiddleware()) // 认证JWT中间件
 
 	{
-		v1.POST("/post", controller.CreatePostHandler)
+		// 退出登录
+		v1.POST("/logout", controller.LogoutHandler)
+		// 修改个人资料
+		v1.PUT("/me", writeLimit, controller.UpdateProfileHandler)
+		// 订阅社区，订阅的社区的帖子通过/posts2?subscribed=true查询
+		v1.POST("/community/:id/subscribe", writeLimit, controller.SubscribeCommunityHandler)
+		v1.DELETE("/community/:id/subscribe", writeLimit, controller.SubscribeCommunityHandler)
+		// 关注用户及关注的作者的帖子
+		v1.POST("/users/:id/follow", writeLimit, controller.FollowUserHandler)
+		v1.DELETE("/users/:id/follow", writeLimit, controller.FollowUserHandler)
+		v1.GET("/me/timeline", readLimit, controller.TimelineHandler)
+		v1.POST("/post", writeLimit, controller.CreatePostHandler)
+		v1.PUT("/post/:id", writeLimit, controller.UpdatePostHandler)
+		v1.DELETE("/post/:id", writeLimit, controller.DeletePostHandler)
+		// 草稿及定时发布
+		v1.GET("/me/drafts", readLimit, controller.DraftListHandler)
+		v1.POST("/post/:id/publish", writeLimit, controller.PublishPostHandler)
+		// 版主把帖子恢复为历史版本
+		v1.POST("/post/:id/revisions/:rev/rollback", writeLimit, controller.RollbackPostHandler)
+		// 社区管理
+		manageCommunity := middlewares.RequirePermission(rbac.PermCommunityManage)
+		v1.POST("/community", manageCommunity, writeLimit, controller.CreateCommunityHandler)
+		v1.PUT("/community/:id", manageCommunity, writeLimit, controller.UpdateCommunityHandler)
+		v1.POST("/community/:id/archive", manageCommunity, writeLimit, controller.ArchiveCommunityHandler)
+		v1.DELETE("/community/:id/archive", manageCommunity, writeLimit, controller.ArchiveCommunityHandler)
+		manageModerator := middlewares.RequirePermission(rbac.PermModeratorManage)
+		v1.POST("/community/:id/moderators", manageModerator, writeLimit, controller.AddModeratorHandler)
+		v1.DELETE("/community/:id/moderators/:uid", manageModerator, writeLimit, controller.RemoveModeratorHandler)
+		// 修改用户角色
+		v1.PUT("/users/:id/role", middlewares.RequirePermission(rbac.PermUserRoleManage), writeLimit, controller.SetUserRoleHandler)
+		// 强制用户下线
+		v1.POST("/users/:id/revoke", middlewares.RequirePermission(rbac.PermUserRevoke), writeLimit, controller.RevokeUserHandler)
+		// 上传图片，返回的地址在帖子正文中引用
+		v1.POST("/uploads", writeLimit, controller.UploadHandler)
+		// 评论
+		v1.POST("/post/:id/comments", writeLimit, controller.CreateCommentHandler)
 		// 投票
-		v1.POST("/vote", controller.PostVoteController)
+		v1.POST("/vote", voteLimit, controller.PostVoteController)
 	}
 
 	r.GET("/ping", middlewares.JWTAuthMiddleware(), func(c *gin.Context) {
@@ -0,0 +1,486 @@
+package router
+
+import (
+	"bell_best/controller"
+	"bell_best/dao/localfs"
+	"bell_best/dao/memory"
+	"bell_best/logic"
+	"bell_best/models"
+	"bell_best/pkg/jwt"
+	"bell_best/pkg/search"
+	"bell_best/pkg/snowflake"
+	"bell_best/setting"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var initOnce sync.Once
+
+// newTestServer 使用内存存储启动完整的HTTP接口，每个测试使用独立的数据
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	initOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		err := jwt.Init(&setting.AuthConfig{
+			SigningKeyID: "hs",
+			Keys:         []*setting.JWTKeyConfig{{KID: "hs", Alg: "HS256", Secret: "0123456789abcdef0123456789abcdef"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = snowflake.Init("2020-07-01", 1); err != nil {
+			t.Fatal(err)
+		}
+		if err = controller.InitTrans("zh"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	blobs, err := localfs.New(&setting.LocalBlobConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	votes := memory.NewVoteStore()
+	logic.Init(&logic.Stores{
+		Post:        memory.NewPostStore(),
+		User:        memory.NewUserStore(),
+		Community:   memory.NewCommunityStore(&models.CommunityDetail{ID: 1, Name: "Go", Introduction: "Golang"}),
+		Comment:     memory.NewCommentStore(),
+		Vote:        votes,
+		VoteArchive: memory.NewVoteArchiveStore(),
+		Token:       memory.NewTokenStore(),
+		Lock:        memory.NewLockStore(),
+		RateLimit:   memory.NewRateLimitStore(),
+		LoginGuard:  memory.NewLoginGuardStore(),
+		Search:      search.NewIndex(),
+		Upload:      memory.NewUploadStore(),
+		Blob:        blobs,
+		Follow:      memory.NewFollowStore(),
+		Timeline:    votes,
+	})
+	srv := httptest.NewServer(SetupRouter())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type response struct {
+	Code controller.ResCode `json:"code"`
+	Data json.RawMessage    `json:"data"`
+}
+
+// call 发送请求并解析统一的响应格式，data不为空时解析到out
+func call(t *testing.T, srv *httptest.Server, method, path, token string, body, out interface{}) controller.ResCode {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: status %d", method, path, resp.StatusCode)
+	}
+	var r response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if out != nil && len(r.Data) > 0 {
+		if err = json.Unmarshal(r.Data, out); err != nil {
+			t.Fatalf("%s %s: decode data: %v", method, path, err)
+		}
+	}
+	return r.Code
+}
+
+// signUpAndLogin 注册并登录，返回access token
+func signUpAndLogin(t *testing.T, srv *httptest.Server, username string) string {
+	t.Helper()
+	p := map[string]string{"username": username, "password": "secret", "re_password": "secret"}
+	if code := call(t, srv, "POST", "/api/v1/signup", "", p, nil); code != controller.CodeSuccess {
+		t.Fatalf("signup %s: code %d", username, code)
+	}
+	var data struct {
+		Token string `json:"token"`
+	}
+	p = map[string]string{"username": username, "password": "secret"}
+	if code := call(t, srv, "POST", "/api/v1/login", "", p, &data); code != controller.CodeSuccess || data.Token == "" {
+		t.Fatalf("login %s: code %d, token %q", username, code, data.Token)
+	}
+	return data.Token
+}
+
+// createPost 发帖并返回帖子id
+func createPost(t *testing.T, srv *httptest.Server, token, title string) int64 {
+	t.Helper()
+	var post models.Post
+	p := map[string]interface{}{"title": title, "content": "content of " + title, "community_id": 1}
+	if code := call(t, srv, "POST", "/api/v1/post", token, p, &post); code != controller.CodeSuccess {
+		t.Fatalf("create post %s: code %d", title, code)
+	}
+	return post.ID
+}
+
+func TestSignUpLogin(t *testing.T) {
+	srv := newTestServer(t)
+	signUpAndLogin(t, srv, "alice")
+
+	tests := []struct {
+		name string
+		path string
+		body map[string]string
+		want controller.ResCode
+	}{
+		{"duplicate signup", "/api/v1/signup", map[string]string{"username": "alice", "password": "x", "re_password": "x"}, controller.CodeUserExist},
+		{"missing username", "/api/v1/signup", map[string]string{"password": "x", "re_password": "x"}, controller.CodeInvalidParam},
+		{"missing password", "/api/v1/login", map[string]string{"username": "alice"}, controller.CodeInvalidParam},
+		{"wrong password", "/api/v1/login", map[string]string{"username": "alice", "password": "wrong"}, controller.CodeInvalidPassword},
+		{"unknown user", "/api/v1/login", map[string]string{"username": "nobody", "password": "secret"}, controller.CodeUserNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := call(t, srv, "POST", tt.path, "", tt.body, nil); code != tt.want {
+				t.Errorf("code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePostRequiresLogin(t *testing.T) {
+	srv := newTestServer(t)
+	p := map[string]interface{}{"title": "t", "content": "c", "community_id": 1}
+	if code := call(t, srv, "POST", "/api/v1/post", "", p, nil); code != controller.CodeNeedLogin {
+		t.Errorf("code = %d, want %d", code, controller.CodeNeedLogin)
+	}
+	if code := call(t, srv, "POST", "/api/v1/post", "invalid", p, nil); code != controller.CodeInvalidToken {
+		t.Errorf("code = %d, want %d", code, controller.CodeInvalidToken)
+	}
+}
+
+func TestPostCreateAndList(t *testing.T) {
+	srv := newTestServer(t)
+	token := signUpAndLogin(t, srv, "alice")
+	first := createPost(t, srv, token, "first")
+	second := createPost(t, srv, token, "second")
+
+	var detail models.ApiPostDetail
+	if code := call(t, srv, "GET", "/api/v1/post/"+strconv.FormatInt(first, 10), "", nil, &detail); code != controller.CodeSuccess {
+		t.Fatalf("post detail: code %d", code)
+	}
+	if detail.Title != "first" || detail.AuthorName != "alice" || detail.CommunityDetail == nil || detail.CommunityDetail.Name != "Go" {
+		t.Errorf("post detail = %+v", detail)
+	}
+
+	// 按时间排序，新帖子在前
+	var list []*models.ApiPostDetail
+	if code := call(t, srv, "GET", "/api/v1/posts2/?order=time", "", nil, &list); code != controller.CodeSuccess {
+		t.Fatalf("post list: code %d", code)
+	}
+	if len(list) != 2 || list[0].Post.ID != second || list[1].Post.ID != first {
+		t.Fatalf("post list = %v, want [%d %d]", postIDs(list), second, first)
+	}
+
+	// 游标分页
+	var page models.ApiPostList
+	if code := call(t, srv, "GET", "/api/v1/posts2/?cursor=&size=1", "", nil, &page); code != controller.CodeSuccess {
+		t.Fatalf("cursor page: code %d", code)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].Post.ID != second || page.NextCursor == "" {
+		t.Fatalf("first cursor page = %v, next %q", postIDs(page.Posts), page.NextCursor)
+	}
+	next := page.NextCursor
+	page = models.ApiPostList{}
+	if code := call(t, srv, "GET", "/api/v1/posts2/?size=1&cursor="+next, "", nil, &page); code != controller.CodeSuccess {
+		t.Fatalf("cursor page: code %d", code)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].Post.ID != first {
+		t.Fatalf("second cursor page = %v", postIDs(page.Posts))
+	}
+}
+
+func TestVote(t *testing.T) {
+	srv := newTestServer(t)
+	alice := signUpAndLogin(t, srv, "alice")
+	bob := signUpAndLogin(t, srv, "bob")
+	first := createPost(t, srv, alice, "first")
+	second := createPost(t, srv, alice, "second")
+
+	vote := func(token string, pid int64, direction string) controller.ResCode {
+		p := map[string]string{"post_id": strconv.FormatInt(pid, 10), "direction": direction}
+		return call(t, srv, "POST", "/api/v1/vote", token, p, nil)
+	}
+	if code := vote("", first, "1"); code != controller.CodeNeedLogin {
+		t.Errorf("vote without login: code %d", code)
+	}
+	if code := vote(bob, first, "2"); code != controller.CodeInvalidParam {
+		t.Errorf("invalid direction: code %d", code)
+	}
+	if code := vote(bob, first, "1"); code != controller.CodeSuccess {
+		t.Fatalf("vote: code %d", code)
+	}
+	if code := vote(bob, first, "1"); code != controller.CodeVoteRepeated {
+		t.Errorf("repeated vote: code %d", code)
+	}
+
+	// 按分数排序，有赞成票的帖子排在前面，并返回当前用户的投票
+	var list []*models.ApiPostDetail
+	if code := call(t, srv, "GET", "/api/v1/posts2/?order=score", bob, nil, &list); code != controller.CodeSuccess {
+		t.Fatalf("post list: code %d", code)
+	}
+	if len(list) != 2 || list[0].Post.ID != first || list[1].Post.ID != second {
+		t.Fatalf("post list = %v, want [%d %d]", postIDs(list), first, second)
+	}
+	if list[0].UpVotes != 1 || list[0].Score != 1 || list[0].MyVote == nil || *list[0].MyVote != 1 {
+		t.Errorf("voted post = up %d, score %d, my_vote %v", list[0].UpVotes, list[0].Score, list[0].MyVote)
+	}
+
+	// 改投反对票
+	if code := vote(bob, first, "-1"); code != controller.CodeSuccess {
+		t.Fatalf("change vote: code %d", code)
+	}
+	var detail models.ApiPostDetail
+	call(t, srv, "GET", "/api/v1/post/"+strconv.FormatInt(first, 10), bob, nil, &detail)
+	if detail.UpVotes != 0 || detail.DownVotes != 1 || detail.Score != -1 {
+		t.Errorf("after down vote = up %d, down %d, score %d", detail.UpVotes, detail.DownVotes, detail.Score)
+	}
+}
+
+func TestPostListInvalidSize(t *testing.T) {
+	srv := newTestServer(t)
+	token := signUpAndLogin(t, srv, "alice")
+	createPost(t, srv, token, "first")
+
+	tests := []struct {
+		path string
+		want controller.ResCode
+	}{
+		{"/api/v1/posts2/?cursor=&size=-1", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?cursor=&size=0", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?cursor=&size=101", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?cursor=&size=99999999999", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?size=-1", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?page=0", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?tag=go&cursor=&size=-1", controller.CodeInvalidParam},
+		{"/api/v1/me/timeline?size=-1", controller.CodeInvalidParam},
+		{"/api/v1/posts2/?cursor=&size=100", controller.CodeSuccess},
+		{"/api/v1/posts2/?cursor=", controller.CodeSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if code := call(t, srv, "GET", tt.path, token, nil, nil); code != tt.want {
+				t.Errorf("code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func postIDs(posts []*models.ApiPostDetail) []int64 {
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.Post.ID)
+	}
+	return ids
+}
+
+func TestAdminRevokeUser(t *testing.T) {
+	srv := newTestServer(t)
+	if err := logic.SeedAdmin(&setting.SeedAdminConfig{Username: "admin", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	login := func(username string) (token, refreshToken, userID string) {
+		var data struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+			UserID       string `json:"user_id"`
+		}
+		p := map[string]string{"username": username, "password": "secret"}
+		if code := call(t, srv, "POST", "/api/v1/login", "", p, &data); code != controller.CodeSuccess {
+			t.Fatalf("login %s: code %d", username, code)
+		}
+		return data.Token, data.RefreshToken, data.UserID
+	}
+	signUpAndLogin(t, srv, "alice")
+	oldToken, refreshToken, uid := login("alice")
+	// 刷新后旧的access token仍在有效期内，但已不在任何refresh会话中
+	var data struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	p := map[string]string{"refresh_token": refreshToken}
+	if code := call(t, srv, "POST", "/api/v1/refresh", "", p, &data); code != controller.CodeSuccess {
+		t.Fatalf("refresh: code %d", code)
+	}
+	for _, token := range []string{oldToken, data.Token} {
+		if code := call(t, srv, "GET", "/api/v1/me/drafts", token, nil, nil); code != controller.CodeSuccess {
+			t.Fatalf("token before revoke: code %d", code)
+		}
+	}
+
+	userToken := signUpAndLogin(t, srv, "bob")
+	if code := call(t, srv, "POST", "/api/v1/users/"+uid+"/revoke", userToken, nil, nil); code != controller.CodeNoPermission {
+		t.Errorf("revoke by user: code %d, want %d", code, controller.CodeNoPermission)
+	}
+	adminToken, _, _ := login("admin")
+	if code := call(t, srv, "POST", "/api/v1/users/999/revoke", adminToken, nil, nil); code != controller.CodeUserNotExist {
+		t.Errorf("revoke unknown user: code %d, want %d", code, controller.CodeUserNotExist)
+	}
+	if code := call(t, srv, "POST", "/api/v1/users/"+uid+"/revoke", adminToken, nil, nil); code != controller.CodeSuccess {
+		t.Fatalf("revoke: code %d", code)
+	}
+	for i, token := range []string{oldToken, data.Token} {
+		if code := call(t, srv, "GET", "/api/v1/me/drafts", token, nil, nil); code != controller.CodeInvalidToken {
+			t.Errorf("token %d after revoke: code %d, want %d", i, code, controller.CodeInvalidToken)
+		}
+	}
+	p = map[string]string{"refresh_token": data.RefreshToken}
+	if code := call(t, srv, "POST", "/api/v1/refresh", "", p, nil); code == controller.CodeSuccess {
+		t.Error("refresh token still valid after revoke")
+	}
+	// 重新登录不受影响
+	token, _, _ := login("alice")
+	if code := call(t, srv, "GET", "/api/v1/me/drafts", token, nil, nil); code != controller.CodeSuccess {
+		t.Errorf("login after revoke: code %d", code)
+	}
+}
+
+func TestTimelineFanOutModeSwitch(t *testing.T) {
+	srv := newTestServer(t)
+	old := setting.Conf.FollowConfig
+	setting.Conf.FollowConfig = &setting.FollowConfig{FanOutLimit: 1}
+	t.Cleanup(func() { setting.Conf.FollowConfig = old })
+
+	authorToken := signUpAndLogin(t, srv, "author")
+	var author struct {
+		UserID string `json:"user_id"`
+	}
+	p := map[string]string{"username": "author", "password": "secret"}
+	if code := call(t, srv, "POST", "/api/v1/login", "", p, &author); code != controller.CodeSuccess {
+		t.Fatalf("login: code %d", code)
+	}
+	follow := "/api/v1/users/" + author.UserID + "/follow"
+	bob := signUpAndLogin(t, srv, "bob")
+	carol := signUpAndLogin(t, srv, "carol")
+	timeline := func(token string) []int64 {
+		t.Helper()
+		var data models.ApiPostList
+		if code := call(t, srv, "GET", "/api/v1/me/timeline?size=10", token, nil, &data); code != controller.CodeSuccess {
+			t.Fatalf("timeline: code %d", code)
+		}
+		return postIDs(data.Posts)
+	}
+
+	// 只有一个粉丝时发帖推送到粉丝的时间线
+	if code := call(t, srv, "POST", follow, bob, nil, nil); code != controller.CodeSuccess {
+		t.Fatalf("bob follow: code %d", code)
+	}
+	p1 := createPost(t, srv, authorToken, "pushed")
+	// 粉丝超过上限后改为读取时合并
+	if code := call(t, srv, "POST", follow, carol, nil, nil); code != controller.CodeSuccess {
+		t.Fatalf("carol follow: code %d", code)
+	}
+	p2 := createPost(t, srv, authorToken, "pulled")
+	if got := timeline(bob); !reflect.DeepEqual(got, []int64{p2, p1}) {
+		t.Errorf("bob timeline in pull mode = %v, want %v", got, []int64{p2, p1})
+	}
+	// 粉丝降回上限后改为推送，读取时合并期间发的帖子要补到粉丝的时间线
+	if code := call(t, srv, "DELETE", follow, carol, nil, nil); code != controller.CodeSuccess {
+		t.Fatalf("carol unfollow: code %d", code)
+	}
+	if got := timeline(bob); !reflect.DeepEqual(got, []int64{p2, p1}) {
+		t.Errorf("bob timeline after switching back to push = %v, want %v", got, []int64{p2, p1})
+	}
+	if got := timeline(carol); len(got) != 0 {
+		t.Errorf("carol timeline after unfollow = %v, want empty", got)
+	}
+}
+
+func TestClientIPIgnoresUntrustedForwardedFor(t *testing.T) {
+	newTestServer(t)
+	old := setting.Conf.TrustedProxies
+	t.Cleanup(func() { setting.Conf.TrustedProxies = old })
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"no trusted proxies", nil, "192.0.2.1"},
+		{"trusted proxy", []string{"192.0.2.1"}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		setting.Conf.TrustedProxies = tt.proxies
+		r := SetupRouter()
+		r.GET("/test/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+		req := httptest.NewRequest("GET", "/test/ip", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetUserRoleUnknownUser(t *testing.T) {
+	srv := newTestServer(t)
+	if err := logic.SeedAdmin(&setting.SeedAdminConfig{Username: "admin", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		Token string `json:"token"`
+	}
+	p := map[string]string{"username": "admin", "password": "secret"}
+	if code := call(t, srv, "POST", "/api/v1/login", "", p, &data); code != controller.CodeSuccess {
+		t.Fatalf("login: code %d", code)
+	}
+	body := map[string]string{"role": "moderator"}
+	if code := call(t, srv, "PUT", "/api/v1/users/999/role", data.Token, body, nil); code != controller.CodeUserNotExist {
+		t.Errorf("set role of unknown user: code %d, want %d", code, controller.CodeUserNotExist)
+	}
+}
+
+func TestCreateCommentContentLength(t *testing.T) {
+	srv := newTestServer(t)
+	token := signUpAndLogin(t, srv, "comment_len")
+	pid := createPost(t, srv, token, "comment length")
+	path := fmt.Sprintf("/api/v1/post/%d/comments", pid)
+	tests := []struct {
+		name    string
+		content string
+		want    controller.ResCode
+	}{
+		{"empty", "", controller.CodeInvalidParam},
+		// 长度按字符而不是字节计算
+		{"2048 runes", strings.Repeat("评", 2048), controller.CodeSuccess},
+		{"2049 runes", strings.Repeat("评", 2049), controller.CodeInvalidParam},
+		{"2049 bytes", strings.Repeat("a", 2049), controller.CodeInvalidParam},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := call(t, srv, "POST", path, token, map[string]string{"content": tt.content}, nil); code != tt.want {
+				t.Errorf("code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"time"
 )
 
 // 全局变量，用来保存所有配置信息
@@ -16,10 +17,159 @@ type AppConfig struct {
 	StartTime string `mapstructure:"start_time"`
 	MachineID int64  `mapstructure:"machine_id"`
 	Port      int    `mapstructure:"port"`
+	// 信任的反向代理，只有来自这些地址的X-Forwarded-For才用于获取客户端ip，限流及登录防暴力破解都按该ip统计
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 
-	*LogConfig   `mapstructure:"log"`
-	*MySQLConfig `mapstructure:"mysql"`
-	*RedisConfig `mapstructure:"redis"`
+	*AuthConfig       `mapstructure:"auth"`
+	*RankConfig       `mapstructure:"rank"`
+	*VoteConfig       `mapstructure:"vote"`
+	*RateLimitConfig  `mapstructure:"rate_limit"`
+	*LoginGuardConfig `mapstructure:"login_guard"`
+	*SeedAdminConfig  `mapstructure:"seed_admin"`
+	*SearchConfig     `mapstructure:"search"`
+	*TagConfig        `mapstructure:"tag"`
+	*PublishConfig    `mapstructure:"publish"`
+	*FollowConfig     `mapstructure:"follow"`
+	*MarkdownConfig   `mapstructure:"markdown"`
+	*UploadConfig     `mapstructure:"upload"`
+	*LogConfig        `mapstructure:"log"`
+	*MySQLConfig      `mapstructure:"mysql"`
+	*RedisConfig      `mapstructure:"redis"`
+}
+
+type AuthConfig struct {
+	AccessTokenExpire  time.Duration   `mapstructure:"access_token_expire"`
+	RefreshTokenExpire time.Duration   `mapstructure:"refresh_token_expire"`
+	PasswordHasher     string          `mapstructure:"password_hasher"` // argon2id 或 bcrypt
+	SigningKeyID       string          `mapstructure:"signing_key_id"`  // 签发token使用的密钥kid
+	Keys               []*JWTKeyConfig `mapstructure:"keys"`            // 所有可用于校验token的密钥
+}
+
+// JWTKeyConfig JWT签名密钥
+// HS256使用secret；RS256和EdDSA使用PEM文件，只配置public_key_file的密钥只用于校验
+type JWTKeyConfig struct {
+	KID            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"` // HS256 / RS256 / EdDSA
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// RankConfig 帖子分数的排序算法
+type RankConfig struct {
+	RecomputeInterval time.Duration   `mapstructure:"recompute_interval"` // 后台重新计算分数的间隔，0表示不重新计算
+	Default           *RankerConfig   `mapstructure:"default"`
+	Communities       []*RankerConfig `mapstructure:"communities"` // 单独配置算法的社区
+}
+
+// RankerConfig 排序算法及参数，参数为0时使用算法的默认值
+type RankerConfig struct {
+	CommunityID  int64   `mapstructure:"community_id"`
+	Algorithm    string  `mapstructure:"algorithm"`      // simple / hot / gravity / wilson
+	ScorePerVote float64 `mapstructure:"score_per_vote"` // simple: 每一票值多少分
+	Decay        float64 `mapstructure:"decay"`          // hot: 发帖时间晚多少秒相当于净票数多一个数量级
+	Gravity      float64 `mapstructure:"gravity"`        // gravity: 分数随时间衰减的速度
+	Z            float64 `mapstructure:"z"`              // wilson: 置信度对应的z值
+}
+
+// VoteConfig 投票
+type VoteConfig struct {
+	ArchiveInterval time.Duration `mapstructure:"archive_interval"` // 归档投票期已结束的帖子票数的间隔，0表示不归档
+}
+
+// RateLimitConfig 限流，按路由分组配置令牌桶
+// 登录的用户按用户id限流，未登录的按ip限流
+type RateLimitConfig struct {
+	Enabled bool                      `mapstructure:"enabled"`
+	Groups  map[string]*RateLimitRule `mapstructure:"groups"` // 分组名 -> 限流规则
+}
+
+// RateLimitRule 每period最多limit个请求，允许最多burst个请求的突发，burst为0时等于limit
+type RateLimitRule struct {
+	Limit  int64         `mapstructure:"limit"`
+	Period time.Duration `mapstructure:"period"`
+	Burst  int64         `mapstructure:"burst"`
+}
+
+// LoginGuardConfig 登录防暴力破解，按用户名和ip分别统计失败次数
+type LoginGuardConfig struct {
+	Enabled  bool            `mapstructure:"enabled"`
+	Username *LoginGuardRule `mapstructure:"username"`
+	IP       *LoginGuardRule `mapstructure:"ip"`
+}
+
+// LoginGuardRule 窗口期内失败超过free_attempts次后，每次失败禁止登录base_delay*2^n，最长max_delay
+// 失败达到lockout_threshold次时禁止登录lockout_duration
+type LoginGuardRule struct {
+	Window           time.Duration `mapstructure:"window"` // 最后一次失败多久之后清零
+	FreeAttempts     int64         `mapstructure:"free_attempts"`
+	BaseDelay        time.Duration `mapstructure:"base_delay"`
+	MaxDelay         time.Duration `mapstructure:"max_delay"`
+	LockoutThreshold int64         `mapstructure:"lockout_threshold"`
+	LockoutDuration  time.Duration `mapstructure:"lockout_duration"`
+}
+
+// SeedAdminConfig 启动时创建的管理员账号，用户名已存在时只把角色改为管理员，不修改密码
+type SeedAdminConfig struct {
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// SearchConfig 帖子全文搜索
+type SearchConfig struct {
+	Engine string `mapstructure:"engine"` // mysql(默认，FULLTEXT索引) / inverted(进程内的倒排索引，只适合单实例部署)
+}
+
+// TagConfig 帖子标签
+type TagConfig struct {
+	MaxPerPost     int           `mapstructure:"max_per_post"`    // 每个帖子最多的标签数，0表示使用默认值5
+	TrendingWindow time.Duration `mapstructure:"trending_window"` // 热门标签统计最近多长时间的使用次数，按小时统计，最长7天
+}
+
+// PublishConfig 定时发布
+type PublishConfig struct {
+	ScheduleInterval time.Duration `mapstructure:"schedule_interval"` // 检查并发布到期的定时帖子的间隔，0表示不发布
+}
+
+// FollowConfig 关注及时间线
+type FollowConfig struct {
+	TimelineSize int64 `mapstructure:"timeline_size"` // 每个用户的时间线最多保留的帖子数，0表示使用默认值800
+	FanOutLimit  int64 `mapstructure:"fan_out_limit"` // 粉丝数不超过该值的作者发帖时推送到粉丝的时间线，超过时在粉丝读取时合并，0表示使用默认值1000
+}
+
+// MarkdownConfig 帖子正文的Markdown渲染
+type MarkdownConfig struct {
+	CacheSize     int `mapstructure:"cache_size"`     // 缓存多少篇帖子的渲染结果，0表示使用默认值1024
+	ExcerptLength int `mapstructure:"excerpt_length"` // 列表摘要的最大字符数，0表示使用默认值140
+}
+
+// UploadConfig 图片上传
+type UploadConfig struct {
+	MaxSize         int64            `mapstructure:"max_size"`         // 单个文件的最大字节数，0表示使用默认值5MB
+	MaxPixels       int              `mapstructure:"max_pixels"`       // 图片宽×高的上限，0表示使用默认值4000万
+	ThumbnailSize   int              `mapstructure:"thumbnail_size"`   // 缩略图最长边的像素数，0表示使用默认值320
+	OrphanTTL       time.Duration    `mapstructure:"orphan_ttl"`       // 没有帖子引用的图片保留多久，0表示使用默认值24h
+	CleanupInterval time.Duration    `mapstructure:"cleanup_interval"` // 清理没有帖子引用的图片的间隔，0表示不清理
+	Storage         string           `mapstructure:"storage"`          // local 或 s3
+	Local           *LocalBlobConfig `mapstructure:"local"`
+	S3              *S3BlobConfig    `mapstructure:"s3"`
+}
+
+// LocalBlobConfig 保存在本地目录，public_url以/开头时由服务自身提供访问
+type LocalBlobConfig struct {
+	Dir       string `mapstructure:"dir"`
+	PublicURL string `mapstructure:"public_url"`
+}
+
+// S3BlobConfig 保存在S3兼容的对象存储中，如MinIO
+type S3BlobConfig struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"` // 使用endpoint/bucket/key形式的地址，MinIO需要开启
+	PublicURL string `mapstructure:"public_url"` // 访问文件的地址前缀，为空时使用对象存储的地址
 }
 
 type LogConfig struct {